@@ -1,6 +1,9 @@
 package auth
 
 import (
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/hex"
     "fmt"
     "log"
     "net/http"
@@ -8,14 +11,18 @@ import (
     "strings"
     "time"
 
+    "github.com/jalong4/stock-service-go/config"
     "github.com/jalong4/stock-service-go/models"
     "github.com/gin-gonic/gin"
     "github.com/golang-jwt/jwt/v4"
+    "go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type AccessClaims struct {
-    ID    string `json:"_id"`
-    Email string `json:"email"`
+    ID         string `json:"_id"`
+    Email      string `json:"email"`
+    Role       string `json:"role"`
+    IssuedAtMs int64  `json:"iatMs,omitempty"` // Issue time in Unix milliseconds, finer than the standard iat
     jwt.StandardClaims
 }
 
@@ -54,27 +61,191 @@ func AuthMiddleware() gin.HandlerFunc {
         }
 
         tokenString := authHeader[len(Bearer_schema):]
-        token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+        claims := &AccessClaims{}
+        token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
             if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
                 return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
             }
 
             return []byte(os.Getenv("ACCESS_TOKEN_SECRET")), nil
         })
+        if err != nil || !token.Valid {
+            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+            return
+        }
+
+        // Reject tokens that were revoked by a logout before they expired
+        revoked, err := isRevoked(claims)
+        if err != nil {
+            log.Printf("Failed to check token revocation: %v", err)
+            c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate token"})
+            return
+        }
+        if revoked {
+            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+            return
+        }
+
+        // Pass the processing to the next middleware or handler
+        c.Set("userID", claims.ID)
+        c.Set("claims", claims)
+    }
+}
+
+// RequireRole returns middleware that only lets through tokens carrying the given role.
+// Admins are allowed through every role check.
+func RequireRole(role string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        claims, err := GetAccessClaims(c)
+        if err != nil {
+            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+            return
+        }
 
-        if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-            // Pass the processing to the next middleware or handler
-            c.Set("userID", claims["user_id"])
-        } else {
-            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+        if !HasRole(claims, role) {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Requires %s role", role)})
             return
         }
     }
 }
 
+// HasRole reports whether the claims satisfy the given role
+func HasRole(claims *AccessClaims, role string) bool {
+    // Tokens issued before roles existed belong to ordinary users
+    claimsRole := claims.Role
+    if claimsRole == "" {
+        claimsRole = models.RoleUser
+    }
+    return claimsRole == models.RoleAdmin || claimsRole == role
+}
+
+// IsAdmin reports whether the authenticated user has the admin role
+func IsAdmin(c *gin.Context) bool {
+    claims, err := GetAccessClaims(c)
+    return err == nil && HasRole(claims, models.RoleAdmin)
+}
+
+// GetAccessClaims returns the claims of the access token validated by AuthMiddleware
+func GetAccessClaims(c *gin.Context) (*AccessClaims, error) {
+    value, exists := c.Get("claims")
+    if !exists {
+        return nil, fmt.Errorf("no token claims in request context")
+    }
+
+    claims, ok := value.(*AccessClaims)
+    if !ok {
+        return nil, fmt.Errorf("invalid token claims in request context")
+    }
+    return claims, nil
+}
+
+// ParseRefreshToken validates a refresh token's signature and expiry and returns its claims
+func ParseRefreshToken(tokenString string) (*RefreshClaims, error) {
+    refreshSecret, err := getRefreshSecret()
+    if err != nil {
+        return nil, err
+    }
+
+    claims := &RefreshClaims{}
+    token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+        if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+            return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+        }
+        return refreshSecret, nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    if !token.Valid || claims.Id == "" {
+        return nil, fmt.Errorf("invalid refresh token")
+    }
+    return claims, nil
+}
+
+// GetUserID returns the ID of the authenticated user set by AuthMiddleware
+func GetUserID(c *gin.Context) (primitive.ObjectID, error) {
+    value, exists := c.Get("userID")
+    if !exists {
+        return primitive.NilObjectID, fmt.Errorf("no user ID in request context")
+    }
+
+    id, ok := value.(string)
+    if !ok {
+        return primitive.NilObjectID, fmt.Errorf("invalid user ID in token")
+    }
+
+    return parseUserID(id)
+}
+
+// NewPasswordResetToken returns a random password reset token and the hash it is stored under
+func NewPasswordResetToken() (string, string, error) {
+    token := make([]byte, 32)
+    if _, err := rand.Read(token); err != nil {
+        return "", "", err
+    }
+    encoded := hex.EncodeToString(token)
+    return encoded, HashPasswordResetToken(encoded), nil
+}
+
+// HashPasswordResetToken returns the hash a password reset token is stored under
+func HashPasswordResetToken(token string) string {
+    sum := sha256.Sum256([]byte(token))
+    return hex.EncodeToString(sum[:])
+}
 
 // Private functions
 
+// parseUserID converts the user ID claim to an ObjectID
+func parseUserID(id string) (primitive.ObjectID, error) {
+    // Tokens issued before the claim was stored as a hex string carry ObjectID("...")
+    id = strings.TrimSuffix(strings.TrimPrefix(id, `ObjectID("`), `")`)
+
+    return primitive.ObjectIDFromHex(id)
+}
+
+// isRevoked checks the token against the revoked token IDs and the user's "tokens issued before" cutoff
+func isRevoked(claims *AccessClaims) (bool, error) {
+    if claims.Id != "" {
+        revoked, err := config.IsTokenRevoked(claims.Id)
+        if err != nil || revoked {
+            return revoked, err
+        }
+    }
+
+    userID, err := parseUserID(claims.ID)
+    if err != nil {
+        return true, nil
+    }
+
+    notBefore, err := config.GetTokensNotBefore(userID)
+    if err != nil {
+        return false, err
+    }
+    return issuedByCutoff(claims, notBefore), nil
+}
+
+// issuedByCutoff reports whether the token was issued at or before the user's token cutoff.
+// Tokens issued before the millisecond claim existed are compared in whole seconds.
+func issuedByCutoff(claims *AccessClaims, notBefore *time.Time) bool {
+    if notBefore == nil {
+        return false
+    }
+    if claims.IssuedAtMs != 0 {
+        return claims.IssuedAtMs <= notBefore.UnixMilli()
+    }
+    return claims.IssuedAt <= notBefore.Unix()
+}
+
+// issuedAtMs returns the millisecond issue time of a new token, kept strictly after the user's token cutoff
+// so that tokens issued in the same millisecond as a "log out everywhere" stay valid
+func issuedAtMs(now time.Time, notBefore *time.Time) int64 {
+    if notBefore != nil && now.UnixMilli() <= notBefore.UnixMilli() {
+        return notBefore.UnixMilli() + 1
+    }
+    return now.UnixMilli()
+}
+
 func getAccessSecret() ([]byte, error) {
     accessSecret := os.Getenv("ACCESS_TOKEN_SECRET")
     if accessSecret == "" {
@@ -92,13 +263,22 @@ func getRefreshSecret() ([]byte, error) {
 }
 
 func createTokensWithAtClaim(user *models.User) (string, string, *AccessClaims, error) {
-    var err error
+    issuedAt := time.Now()
+    notBefore, err := config.GetTokensNotBefore(user.ID)
+    if err != nil {
+        return "", "", nil, err
+    }
+
     // Creating Access Token
     atClaims := AccessClaims{
-        ID:    user.ID.String(),
-        Email: user.Email,
+        ID:         user.ID.Hex(),
+        Email:      user.Email,
+        Role:       user.GetRole(),
+        IssuedAtMs: issuedAtMs(issuedAt, notBefore),
         StandardClaims: jwt.StandardClaims{
-            ExpiresAt: time.Now().Add(time.Hour * 24 * 30).Unix(), // Token expires after 30 days
+            Id:        primitive.NewObjectID().Hex(),
+            IssuedAt:  issuedAt.Unix(),
+            ExpiresAt: issuedAt.Add(time.Hour * 24 * 30).Unix(), // Token expires after 30 days
         },
     }
     at := jwt.NewWithClaims(jwt.SigningMethodHS256, atClaims)
@@ -115,10 +295,15 @@ func createTokensWithAtClaim(user *models.User) (string, string, *AccessClaims,
     }
 
     // Creating Refresh Token
-    rtClaims := jwt.MapClaims{}
-    rtClaims["user_id"] = user.ID
-    rtClaims["email"] = user.Email
-    rtClaims["exp"] = time.Now().Add(time.Hour * 24 * 7).Unix() // Token expires after 7 days
+    rtClaims := RefreshClaims{
+        ID:    user.ID.Hex(),
+        Email: user.Email,
+        StandardClaims: jwt.StandardClaims{
+            Id:        primitive.NewObjectID().Hex(),
+            IssuedAt:  issuedAt.Unix(),
+            ExpiresAt: issuedAt.Add(time.Hour * 24 * 7).Unix(), // Token expires after 7 days
+        },
+    }
     rt := jwt.NewWithClaims(jwt.SigningMethodHS256, rtClaims)
 
     refreshToken, err := getRefreshSecret()
@@ -133,5 +318,17 @@ func createTokensWithAtClaim(user *models.User) (string, string, *AccessClaims,
         return "", "", nil, err
     }
 
+    // Record the refresh token so it can only be exchanged once
+    err = config.InsertRefreshToken(models.RefreshToken{
+        TokenID:   rtClaims.Id,
+        UserID:    user.ID,
+        IssuedAt:  issuedAt,
+        ExpiresAt: time.Unix(rtClaims.ExpiresAt, 0),
+    })
+    if err != nil {
+        log.Println("Refresh Token could not be recorded:", err.Error())
+        return "", "", nil, err
+    }
+
     return accessTokenSigned, refreshTokenSigned, &atClaims, nil
 }
@@ -0,0 +1,86 @@
+package auth
+
+import (
+    "testing"
+    "time"
+
+    "github.com/golang-jwt/jwt/v4"
+)
+
+func TestIssuedByCutoff(t *testing.T) {
+    cutoff := time.Date(2024, 5, 1, 12, 0, 0, 400*int(time.Millisecond), time.UTC)
+
+    tests := []struct {
+        name   string
+        claims AccessClaims
+        cutoff *time.Time
+        want   bool
+    }{
+        {"no cutoff", AccessClaims{IssuedAtMs: cutoff.UnixMilli() - 1000}, nil, false},
+        {"earlier millisecond", AccessClaims{IssuedAtMs: cutoff.UnixMilli() - 1}, &cutoff, true},
+        {"same millisecond", AccessClaims{IssuedAtMs: cutoff.UnixMilli()}, &cutoff, true},
+        {"later millisecond of the same second", AccessClaims{IssuedAtMs: cutoff.UnixMilli() + 1}, &cutoff, false},
+        {"earlier in the same second", AccessClaims{IssuedAtMs: cutoff.UnixMilli() - 300}, &cutoff, true},
+        {"seconds only, same second", AccessClaims{StandardClaims: jwt.StandardClaims{IssuedAt: cutoff.Unix()}}, &cutoff, true},
+        {"seconds only, next second", AccessClaims{StandardClaims: jwt.StandardClaims{IssuedAt: cutoff.Unix() + 1}}, &cutoff, false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := issuedByCutoff(&tt.claims, tt.cutoff); got != tt.want {
+                t.Errorf("issuedByCutoff() = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestIssuedAtMs(t *testing.T) {
+    cutoff := time.Date(2024, 5, 1, 12, 0, 0, 400*int(time.Millisecond), time.UTC)
+
+    tests := []struct {
+        name   string
+        now    time.Time
+        cutoff *time.Time
+        want   int64
+    }{
+        {"no cutoff", cutoff, nil, cutoff.UnixMilli()},
+        {"after the cutoff", cutoff.Add(5 * time.Millisecond), &cutoff, cutoff.UnixMilli() + 5},
+        {"same millisecond as the cutoff", cutoff.Add(500 * time.Microsecond), &cutoff, cutoff.UnixMilli() + 1},
+        {"clock behind the cutoff", cutoff.Add(-time.Second), &cutoff, cutoff.UnixMilli() + 1},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got := issuedAtMs(tt.now, tt.cutoff)
+            if got != tt.want {
+                t.Errorf("issuedAtMs() = %d, want %d", got, tt.want)
+            }
+            // A token issued after a cutoff must never be revoked by it
+            if issuedByCutoff(&AccessClaims{IssuedAtMs: got}, tt.cutoff) {
+                t.Errorf("token issued at %d is revoked by its own cutoff", got)
+            }
+        })
+    }
+}
+
+func TestParseUserID(t *testing.T) {
+    tests := []struct {
+        id      string
+        want    string
+        wantErr bool
+    }{
+        {"65f1c2a9e4b0a1b2c3d4e5f6", "65f1c2a9e4b0a1b2c3d4e5f6", false},
+        {`ObjectID("65f1c2a9e4b0a1b2c3d4e5f6")`, "65f1c2a9e4b0a1b2c3d4e5f6", false},
+        {"not-an-id", "", true},
+    }
+
+    for _, tt := range tests {
+        got, err := parseUserID(tt.id)
+        if (err != nil) != tt.wantErr {
+            t.Fatalf("parseUserID(%q) error = %v, wantErr %v", tt.id, err, tt.wantErr)
+        }
+        if err == nil && got.Hex() != tt.want {
+            t.Errorf("parseUserID(%q) = %s, want %s", tt.id, got.Hex(), tt.want)
+        }
+    }
+}
@@ -0,0 +1,367 @@
+package config
+// Path: config/accounts.go
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "strings"
+    "time"
+
+    "github.com/jalong4/stock-service-go/models"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Accounts
+
+// accountNameCollation compares account names without regard to case
+var accountNameCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// accountCollections are the collections whose records belong to an account, by its ID, and show its name
+var accountCollections = []string{"holdings", "transactions", "cashFlows", "realizedLots", "snapshots", "corporateActionAdjustments"}
+
+// GetAccountsCollection returns the accounts collection from the MongoDB
+func GetAccountsCollection() *mongo.Collection {
+    collection := MongoDB.Database(os.Getenv("MONGO_DB")).Collection("accounts")
+    return collection
+}
+
+// EnsureAccountIndexes makes account names unique per user, ignoring case
+func EnsureAccountIndexes() error {
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    _, err := GetAccountsCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "name", Value: 1}},
+        Options: options.Index().SetUnique(true).SetCollation(accountNameCollation),
+    })
+    return err
+}
+
+// AddAccount inserts a new account
+func AddAccount(account models.Account) (string, error) {
+    if account.ID != primitive.NilObjectID {
+        return "", fmt.Errorf("ID should not be provided for a new account")
+    }
+    if account.UserID == primitive.NilObjectID {
+        return "", fmt.Errorf("User ID must be provided for a new account")
+    }
+
+    collection := GetAccountsCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    result, err := collection.InsertOne(ctx, account)
+    if err != nil {
+        return "", err
+    }
+    return result.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// GetAccounts retrieves the user's accounts ordered by name
+func GetAccounts(userID primitive.ObjectID) ([]models.Account, error) {
+    var accounts []models.Account
+    collection := GetAccountsCollection()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}}).SetCollation(accountNameCollation)
+    cursor, err := collection.Find(ctx, bson.M{"userId": userID}, opts)
+    if err != nil {
+        log.Printf("Failed to retrieve accounts: %v", err)
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    for cursor.Next(ctx) {
+        var account models.Account
+        if err = cursor.Decode(&account); err != nil {
+            log.Printf("Failed to decode account: %v", err)
+            continue
+        }
+        accounts = append(accounts, account)
+    }
+
+    if err = cursor.Err(); err != nil {
+        log.Printf("Cursor error: %v", err)
+        return nil, err
+    }
+
+    return accounts, nil
+}
+
+// GetEveryAccount retrieves the accounts of all users, for background jobs
+func GetEveryAccount() ([]models.Account, error) {
+    var accounts []models.Account
+    collection := GetAccountsCollection()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+    defer cancel()
+
+    cursor, err := collection.Find(ctx, bson.M{})
+    if err != nil {
+        log.Printf("Failed to retrieve accounts: %v", err)
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    if err = cursor.All(ctx, &accounts); err != nil {
+        log.Printf("Failed to decode accounts: %v", err)
+        return nil, err
+    }
+    return accounts, nil
+}
+
+// GetAccountByID retrieves an account by its ID if it is owned by the given user
+func GetAccountByID(userID primitive.ObjectID, id string) (*models.Account, error) {
+    oid, err := primitive.ObjectIDFromHex(id)
+    if err != nil {
+        return nil, err
+    }
+
+    collection := GetAccountsCollection()
+    var account models.Account
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    err = collection.FindOne(ctx, bson.M{"_id": oid, "userId": userID}).Decode(&account)
+    if err != nil {
+        return nil, err
+    }
+    return &account, nil
+}
+
+// GetAccountByName retrieves one of the user's accounts by its exact name, ignoring case
+func GetAccountByName(userID primitive.ObjectID, name string) (*models.Account, error) {
+    collection := GetAccountsCollection()
+    var account models.Account
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    opts := options.FindOne().SetCollation(accountNameCollation)
+    err := collection.FindOne(ctx, bson.M{"userId": userID, "name": strings.TrimSpace(name)}, opts).Decode(&account)
+    if err != nil {
+        return nil, err
+    }
+    return &account, nil
+}
+
+// UpdateAccount saves changes to one of the user's accounts. Records are joined to their account by its ID,
+// and a new name is copied to them for display. A rename runs in a transaction where the server supports
+// them, and as sequential updates on a standalone server.
+func UpdateAccount(userID primitive.ObjectID, previousName string, account models.Account) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    if account.Name == previousName {
+        return updateAccountFields(ctx, userID, account)
+    }
+
+    rename := func(ctx context.Context) error {
+        if err := updateAccountFields(ctx, userID, account); err != nil {
+            return err
+        }
+        for _, collectionName := range accountCollections {
+            _, err := MongoDB.Database(os.Getenv("MONGO_DB")).Collection(collectionName).UpdateMany(ctx,
+                bson.M{"userId": userID, "accountId": account.ID},
+                bson.M{"$set": bson.M{"account": account.Name}})
+            if err != nil {
+                return err
+            }
+        }
+        return nil
+    }
+
+    session, err := MongoDB.StartSession()
+    if err != nil {
+        return err
+    }
+    defer session.EndSession(ctx)
+
+    _, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+        return nil, rename(sc)
+    })
+    if transactionsUnsupported(err) {
+        log.Printf("Transactions are not supported by this server, renaming account %s without one", account.ID.Hex())
+        return rename(ctx)
+    }
+    return err
+}
+
+// updateAccountFields saves the account's own fields
+func updateAccountFields(ctx context.Context, userID primitive.ObjectID, account models.Account) error {
+    update := bson.M{"$set": bson.M{
+        "name":            account.Name,
+        "institution":     account.Institution,
+        "type":            account.Type,
+        "currency":        account.Currency,
+        "costBasisMethod": account.CostBasisMethod,
+    }}
+    result, err := GetAccountsCollection().UpdateOne(ctx, bson.M{"_id": account.ID, "userId": userID}, update)
+    if err != nil {
+        return err
+    }
+    if result.MatchedCount == 0 {
+        return mongo.ErrNoDocuments
+    }
+    return nil
+}
+
+// transactionsUnsupported reports whether err is a standalone server refusing a transaction
+func transactionsUnsupported(err error) bool {
+    var serverErr mongo.ServerError
+    if !errors.As(err, &serverErr) {
+        return false
+    }
+    // IllegalOperation: "Transaction numbers are only allowed on a replica set member or mongos"
+    return serverErr.HasErrorCode(20) || serverErr.HasErrorMessage("Transaction numbers are only allowed")
+}
+
+// DeleteAccountByID deletes one of the user's accounts
+func DeleteAccountByID(userID primitive.ObjectID, id primitive.ObjectID) (*mongo.DeleteResult, error) {
+    collection := GetAccountsCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    return collection.DeleteOne(ctx, bson.M{"_id": id, "userId": userID})
+}
+
+// IsAccountInUse reports whether any holding, transaction or cash flow belongs to the account
+func IsAccountInUse(userID primitive.ObjectID, accountID primitive.ObjectID) (bool, error) {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    for _, collectionName := range []string{"holdings", "transactions", "cashFlows"} {
+        count, err := MongoDB.Database(os.Getenv("MONGO_DB")).Collection(collectionName).CountDocuments(ctx,
+            bson.M{"userId": userID, "accountId": accountID}, options.Count().SetLimit(1))
+        if err != nil || count > 0 {
+            return count > 0, err
+        }
+    }
+    return false, nil
+}
+
+// Migration
+
+// MigrateAccounts creates an account for every account name used by holdings, transactions, cash flows or the
+// old account settings, and links those records and the ones derived from them to it. Records already linked are left alone, so it is safe
+// to run at every startup.
+func MigrateAccounts() error {
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+    defer cancel()
+
+    database := MongoDB.Database(os.Getenv("MONGO_DB"))
+
+    // Cost basis methods saved before accounts existed
+    costBasisMethods := map[primitive.ObjectID]map[string]string{}
+    cursor, err := database.Collection("accountSettings").Find(ctx, bson.M{})
+    if err != nil {
+        return err
+    }
+    for cursor.Next(ctx) {
+        var settings struct {
+            UserID          primitive.ObjectID `bson:"userId"`
+            Account         string             `bson:"account"`
+            CostBasisMethod string             `bson:"costBasisMethod"`
+        }
+        if err = cursor.Decode(&settings); err != nil {
+            continue
+        }
+        if costBasisMethods[settings.UserID] == nil {
+            costBasisMethods[settings.UserID] = map[string]string{}
+        }
+        costBasisMethods[settings.UserID][settings.Account] = settings.CostBasisMethod
+    }
+    cursor.Close(ctx)
+
+    // Only the records a user entered create accounts, the others are derived from them
+    ownsRecords := map[string]bool{"holdings": true, "transactions": true, "cashFlows": true}
+
+    created, linked := 0, int64(0)
+    for _, collectionName := range accountCollections {
+        collection := database.Collection(collectionName)
+
+        // The user and account name of every record not yet linked to an account
+        pipeline := mongo.Pipeline{
+            {{Key: "$match", Value: bson.M{"accountId": bson.M{"$exists": false}, "userId": bson.M{"$exists": true}}}},
+            {{Key: "$group", Value: bson.M{"_id": bson.M{"userId": "$userId", "account": "$account"}}}},
+        }
+        cursor, err := collection.Aggregate(ctx, pipeline)
+        if err != nil {
+            return err
+        }
+
+        var names []struct {
+            ID struct {
+                UserID  primitive.ObjectID `bson:"userId"`
+                Account string             `bson:"account"`
+            } `bson:"_id"`
+        }
+        if err = cursor.All(ctx, &names); err != nil {
+            return err
+        }
+
+        for _, name := range names {
+            userID, accountName := name.ID.UserID, name.ID.Account
+            if userID == primitive.NilObjectID || strings.TrimSpace(accountName) == "" {
+                continue
+            }
+
+            account, err := GetAccountByName(userID, accountName)
+            if err == mongo.ErrNoDocuments && !ownsRecords[collectionName] {
+                continue // Derived records of an account that no longer exists
+            }
+            if err == mongo.ErrNoDocuments {
+                account = &models.Account{
+                    UserID:          userID,
+                    Name:            accountName,
+                    Type:            guessAccountType(accountName),
+                    Currency:        models.DefaultCurrency,
+                    CostBasisMethod: costBasisMethods[userID][accountName],
+                    CreatedAt:       time.Now(),
+                }
+                id, err := AddAccount(*account)
+                if err != nil {
+                    return err
+                }
+                account.ID, _ = primitive.ObjectIDFromHex(id)
+                created++
+            } else if err != nil {
+                return err
+            }
+
+            result, err := collection.UpdateMany(ctx,
+                bson.M{"userId": userID, "account": accountName, "accountId": bson.M{"$exists": false}},
+                bson.M{"$set": bson.M{"accountId": account.ID, "account": account.Name}})
+            if err != nil {
+                return err
+            }
+            linked += result.ModifiedCount
+        }
+    }
+
+    if created > 0 || linked > 0 {
+        log.Printf("Migrated account names: created %d accounts and linked %d records", created, linked)
+    }
+    return nil
+}
+
+// guessAccountType infers the type of an account from its name when migrating free text account names
+func guessAccountType(name string) string {
+    lower := strings.ToLower(name)
+    switch {
+    case strings.Contains(lower, "roth"):
+        return models.AccountTypeRothIRA
+    case strings.Contains(lower, "ira"):
+        return models.AccountTypeIRA
+    case strings.Contains(lower, "401k") || strings.Contains(lower, "401(k)"):
+        return models.AccountType401k
+    }
+    return models.AccountTypeTaxable
+}
@@ -0,0 +1,236 @@
+package config
+// Path: config/alerts.go
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "os"
+    "time"
+
+    "github.com/jalong4/stock-service-go/models"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Alerts
+
+// GetAlertsCollection returns the alerts collection from the MongoDB
+func GetAlertsCollection() *mongo.Collection {
+    collection := MongoDB.Database(os.Getenv("MONGO_DB")).Collection("alerts")
+    return collection
+}
+
+// GetAlertTriggersCollection returns the alert trigger history collection from the MongoDB
+func GetAlertTriggersCollection() *mongo.Collection {
+    collection := MongoDB.Database(os.Getenv("MONGO_DB")).Collection("alertTriggers")
+    return collection
+}
+
+// AddAlert inserts a new alert
+func AddAlert(alert models.Alert) (string, error) {
+    if alert.ID != primitive.NilObjectID {
+        return "", fmt.Errorf("ID should not be provided for a new alert")
+    }
+    if alert.UserID == primitive.NilObjectID {
+        return "", fmt.Errorf("User ID must be provided for a new alert")
+    }
+
+    collection := GetAlertsCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    result, err := collection.InsertOne(ctx, alert)
+    if err != nil {
+        return "", err
+    }
+    return result.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// GetAlerts retrieves the user's alerts, oldest first
+func GetAlerts(userID primitive.ObjectID) ([]models.Alert, error) {
+    return findAlerts(bson.M{"userId": userID})
+}
+
+// GetActiveAlerts retrieves every user's active alerts
+func GetActiveAlerts() ([]models.Alert, error) {
+    return findAlerts(bson.M{"active": true})
+}
+
+// GetAlertByID retrieves one of the user's alerts by its ID
+func GetAlertByID(userID primitive.ObjectID, id string) (*models.Alert, error) {
+    oid, err := primitive.ObjectIDFromHex(id)
+    if err != nil {
+        return nil, err
+    }
+
+    collection := GetAlertsCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    var alert models.Alert
+    if err := collection.FindOne(ctx, bson.M{"_id": oid, "userId": userID}).Decode(&alert); err != nil {
+        return nil, err
+    }
+    return &alert, nil
+}
+
+// UpdateAlert saves changes to one of the user's alerts
+func UpdateAlert(userID primitive.ObjectID, alert models.Alert) error {
+    collection := GetAlertsCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    result, err := collection.ReplaceOne(ctx, bson.M{"_id": alert.ID, "userId": userID}, alert)
+    if err != nil {
+        return err
+    }
+    if result.MatchedCount == 0 {
+        return mongo.ErrNoDocuments
+    }
+    return nil
+}
+
+// SetAlertState records the outcome of evaluating an alert
+func SetAlertState(id primitive.ObjectID, triggered bool, value float64, evaluatedAt time.Time, triggeredAt *time.Time) error {
+    collection := GetAlertsCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    set := bson.M{"triggered": triggered, "lastValue": value, "lastEvaluatedAt": evaluatedAt}
+    if triggeredAt != nil {
+        set["lastTriggeredAt"] = *triggeredAt
+    }
+    _, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+    return err
+}
+
+// ClaimAlertTrigger marks an armed alert as triggered. It returns false if the alert was already triggered,
+// e.g. by another evaluation running at the same time, so each crossing is only reported once.
+func ClaimAlertTrigger(id primitive.ObjectID, value float64, triggeredAt time.Time) (bool, error) {
+    collection := GetAlertsCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    set := bson.M{"triggered": true, "lastValue": value, "lastEvaluatedAt": triggeredAt, "lastTriggeredAt": triggeredAt}
+    result, err := collection.UpdateOne(ctx, bson.M{"_id": id, "triggered": bson.M{"$ne": true}}, bson.M{"$set": set})
+    if err != nil {
+        return false, err
+    }
+    return result.ModifiedCount == 1, nil
+}
+
+// DeleteAlertByID deletes one of the user's alerts and its trigger history
+func DeleteAlertByID(userID primitive.ObjectID, id primitive.ObjectID) (*mongo.DeleteResult, error) {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    result, err := GetAlertsCollection().DeleteOne(ctx, bson.M{"_id": id, "userId": userID})
+    if err != nil || result.DeletedCount == 0 {
+        return result, err
+    }
+
+    _, err = GetAlertTriggersCollection().DeleteMany(ctx, bson.M{"alertId": id, "userId": userID})
+    return result, err
+}
+
+// AddAlertTrigger records an alert firing
+func AddAlertTrigger(trigger models.AlertTrigger) error {
+    collection := GetAlertTriggersCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    _, err := collection.InsertOne(ctx, trigger)
+    return err
+}
+
+// GetAlertTriggers retrieves the user's alert trigger history, newest first, optionally for one alert
+func GetAlertTriggers(userID primitive.ObjectID, alertID primitive.ObjectID, limit int64) ([]models.AlertTrigger, error) {
+    var triggers []models.AlertTrigger
+    collection := GetAlertTriggersCollection()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    filter := bson.M{"userId": userID}
+    if alertID != primitive.NilObjectID {
+        filter["alertId"] = alertID
+    }
+
+    opts := options.Find().SetSort(bson.D{{Key: "triggeredAt", Value: -1}}).SetLimit(limit)
+    cursor, err := collection.Find(ctx, filter, opts)
+    if err != nil {
+        log.Printf("Failed to retrieve alert triggers: %v", err)
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    for cursor.Next(ctx) {
+        var trigger models.AlertTrigger
+        if err = cursor.Decode(&trigger); err != nil {
+            log.Printf("Failed to decode alert trigger: %v", err)
+            continue
+        }
+        triggers = append(triggers, trigger)
+    }
+
+    if err = cursor.Err(); err != nil {
+        log.Printf("Cursor error: %v", err)
+        return nil, err
+    }
+
+    return triggers, nil
+}
+
+// findAlerts returns the alerts matching filter, oldest first
+func findAlerts(filter bson.M) ([]models.Alert, error) {
+    var alerts []models.Alert
+    collection := GetAlertsCollection()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+    cursor, err := collection.Find(ctx, filter, opts)
+    if err != nil {
+        log.Printf("Failed to retrieve alerts: %v", err)
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    for cursor.Next(ctx) {
+        var alert models.Alert
+        if err = cursor.Decode(&alert); err != nil {
+            log.Printf("Failed to decode alert: %v", err)
+            continue
+        }
+        alerts = append(alerts, alert)
+    }
+
+    if err = cursor.Err(); err != nil {
+        log.Printf("Cursor error: %v", err)
+        return nil, err
+    }
+
+    return alerts, nil
+}
+
+// EnsureAlertIndexes creates the indexes used to find active alerts and a user's trigger history
+func EnsureAlertIndexes() error {
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    _, err := GetAlertsCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys: bson.D{{Key: "active", Value: 1}, {Key: "userId", Value: 1}},
+    })
+    if err != nil {
+        return err
+    }
+
+    _, err = GetAlertTriggersCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys: bson.D{{Key: "userId", Value: 1}, {Key: "triggeredAt", Value: -1}},
+    })
+    return err
+}
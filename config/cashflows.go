@@ -0,0 +1,96 @@
+package config
+// Path: config/cashflows.go
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "os"
+    "time"
+
+    "github.com/jalong4/stock-service-go/models"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Cash Flows
+
+// GetCashFlowsCollection returns the cash flows collection from the MongoDB
+func GetCashFlowsCollection() *mongo.Collection {
+    collection := MongoDB.Database(os.Getenv("MONGO_DB")).Collection("cashFlows")
+    return collection
+}
+
+// AddCashFlow inserts a new deposit or withdrawal
+func AddCashFlow(cashFlow models.CashFlow) (string, error) {
+    if cashFlow.ID != primitive.NilObjectID {
+        return "", fmt.Errorf("ID should not be provided for a new cash flow")
+    }
+    if cashFlow.UserID == primitive.NilObjectID {
+        return "", fmt.Errorf("User ID must be provided for a new cash flow")
+    }
+
+    collection := GetCashFlowsCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    result, err := collection.InsertOne(ctx, cashFlow)
+    if err != nil {
+        return "", err
+    }
+    return result.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// GetCashFlows retrieves the user's cash flows ordered by date, optionally for one account
+func GetCashFlows(userID primitive.ObjectID, accountID primitive.ObjectID) ([]models.CashFlow, error) {
+    var cashFlows []models.CashFlow
+    collection := GetCashFlowsCollection()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    filter := bson.M{"userId": userID}
+    if accountID != primitive.NilObjectID {
+        filter["accountId"] = accountID
+    }
+
+    opts := options.Find().SetSort(bson.D{{Key: "date", Value: 1}, {Key: "createdAt", Value: 1}})
+    cursor, err := collection.Find(ctx, filter, opts)
+    if err != nil {
+        log.Printf("Failed to retrieve cash flows: %v", err)
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    for cursor.Next(ctx) {
+        var cashFlow models.CashFlow
+        if err = cursor.Decode(&cashFlow); err != nil {
+            log.Printf("Failed to decode cash flow: %v", err)
+            continue
+        }
+        cashFlows = append(cashFlows, cashFlow)
+    }
+
+    if err = cursor.Err(); err != nil {
+        log.Printf("Cursor error: %v", err)
+        return nil, err
+    }
+
+    return cashFlows, nil
+}
+
+// DeleteCashFlowByID deletes a cash flow by its ID if it is owned by the given user
+func DeleteCashFlowByID(userID primitive.ObjectID, id string) (*mongo.DeleteResult, error) {
+    oid, err := primitive.ObjectIDFromHex(id)
+    if err != nil {
+        return nil, err
+    }
+
+    collection := GetCashFlowsCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    return collection.DeleteOne(ctx, bson.M{"_id": oid, "userId": userID})
+}
@@ -0,0 +1,182 @@
+package config
+// Path: config/corporateactions.go
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "os"
+    "time"
+
+    "github.com/jalong4/stock-service-go/models"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Corporate Actions
+
+// GetCorporateActionsCollection returns the corporate actions collection from the MongoDB
+func GetCorporateActionsCollection() *mongo.Collection {
+    collection := MongoDB.Database(os.Getenv("MONGO_DB")).Collection("corporateActions")
+    return collection
+}
+
+// GetCorporateActionAdjustmentsCollection returns the collection auditing what each corporate action changed
+func GetCorporateActionAdjustmentsCollection() *mongo.Collection {
+    collection := MongoDB.Database(os.Getenv("MONGO_DB")).Collection("corporateActionAdjustments")
+    return collection
+}
+
+// AddCorporateAction inserts a new corporate action
+func AddCorporateAction(action models.CorporateAction) (string, error) {
+    if action.ID != primitive.NilObjectID {
+        return "", fmt.Errorf("ID should not be provided for a new corporate action")
+    }
+
+    collection := GetCorporateActionsCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    result, err := collection.InsertOne(ctx, action)
+    if err != nil {
+        return "", err
+    }
+    return result.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// SetCorporateActionApplied records that every position affected by the corporate action has been adjusted
+func SetCorporateActionApplied(id primitive.ObjectID, appliedAt time.Time) error {
+    collection := GetCorporateActionsCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    _, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"appliedAt": appliedAt}})
+    return err
+}
+
+// GetCorporateActions retrieves the corporate actions newest first, optionally for one ticker
+func GetCorporateActions(ticker string) ([]models.CorporateAction, error) {
+    var actions []models.CorporateAction
+    collection := GetCorporateActionsCollection()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    filter := bson.M{}
+    if ticker != "" {
+        filter["$or"] = bson.A{bson.M{"ticker": ticker}, bson.M{"newTicker": ticker}}
+    }
+
+    opts := options.Find().SetSort(bson.D{{Key: "date", Value: -1}, {Key: "createdAt", Value: -1}})
+    cursor, err := collection.Find(ctx, filter, opts)
+    if err != nil {
+        log.Printf("Failed to retrieve corporate actions: %v", err)
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    for cursor.Next(ctx) {
+        var action models.CorporateAction
+        if err = cursor.Decode(&action); err != nil {
+            log.Printf("Failed to decode corporate action: %v", err)
+            continue
+        }
+        actions = append(actions, action)
+    }
+
+    if err = cursor.Err(); err != nil {
+        log.Printf("Cursor error: %v", err)
+        return nil, err
+    }
+
+    return actions, nil
+}
+
+// GetCorporateActionByID retrieves a corporate action by its ID
+func GetCorporateActionByID(id string) (*models.CorporateAction, error) {
+    oid, err := primitive.ObjectIDFromHex(id)
+    if err != nil {
+        return nil, err
+    }
+
+    collection := GetCorporateActionsCollection()
+    var action models.CorporateAction
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    err = collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&action)
+    if err != nil {
+        return nil, err
+    }
+    return &action, nil
+}
+
+// AddCorporateActionAdjustment records what a corporate action changed in one position
+func AddCorporateActionAdjustment(adjustment models.CorporateActionAdjustment) error {
+    collection := GetCorporateActionAdjustmentsCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    _, err := collection.InsertOne(ctx, adjustment)
+    return err
+}
+
+// GetCorporateActionAdjustments retrieves the positions a corporate action changed, only the given user's
+// unless userID is nil
+func GetCorporateActionAdjustments(actionID primitive.ObjectID, userID primitive.ObjectID) ([]models.CorporateActionAdjustment, error) {
+    var adjustments []models.CorporateActionAdjustment
+    collection := GetCorporateActionAdjustmentsCollection()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    filter := bson.M{"corporateActionId": actionID}
+    if userID != primitive.NilObjectID {
+        filter["userId"] = userID
+    }
+
+    cursor, err := collection.Find(ctx, filter)
+    if err != nil {
+        log.Printf("Failed to retrieve corporate action adjustments: %v", err)
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    for cursor.Next(ctx) {
+        var adjustment models.CorporateActionAdjustment
+        if err = cursor.Decode(&adjustment); err != nil {
+            log.Printf("Failed to decode corporate action adjustment: %v", err)
+            continue
+        }
+        adjustments = append(adjustments, adjustment)
+    }
+
+    if err = cursor.Err(); err != nil {
+        log.Printf("Cursor error: %v", err)
+        return nil, err
+    }
+
+    return adjustments, nil
+}
+
+// EnsureCorporateActionIndexes creates the indexes corporate actions rely on. The same action can only be
+// recorded once per ticker and effective date, so retried requests cannot adjust positions twice.
+func EnsureCorporateActionIndexes() error {
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    _, err := GetCorporateActionsCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys:    bson.D{{Key: "ticker", Value: 1}, {Key: "type", Value: 1}, {Key: "date", Value: 1}},
+        Options: options.Index().SetUnique(true),
+    })
+    if err != nil {
+        return err
+    }
+
+    _, err = GetCorporateActionAdjustmentsCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys: bson.D{{Key: "corporateActionId", Value: 1}, {Key: "userId", Value: 1}},
+    })
+    return err
+}
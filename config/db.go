@@ -6,6 +6,7 @@ import (
     "fmt"
     "log"
     "os"
+    "strings"
     "time"
 
 	"github.com/jalong4/stock-service-go/models"
@@ -187,6 +188,81 @@ func UpdateUserByID(id string, updatedUser models.User) (*mongo.UpdateResult, er
     return result, nil
 }
 
+// SetUserBenchmark sets the ticker the user's performance is compared against
+func SetUserBenchmark(id primitive.ObjectID, ticker string) error {
+    collection := GetUsersCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"benchmark": ticker}})
+    if err != nil {
+        return err
+    }
+    if result.MatchedCount == 0 {
+        return mongo.ErrNoDocuments
+    }
+    return nil
+}
+
+// SetUserBaseCurrency sets the currency the user's summaries are reported in
+func SetUserBaseCurrency(id primitive.ObjectID, currency string) error {
+    collection := GetUsersCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"baseCurrency": currency}})
+    if err != nil {
+        return err
+    }
+    if result.MatchedCount == 0 {
+        return mongo.ErrNoDocuments
+    }
+    return nil
+}
+
+// PromoteAdmins gives the admin role to the existing users with any of the email addresses, ignoring case,
+// and returns how many were promoted
+func PromoteAdmins(emails []string) (int64, error) {
+    collection := GetUsersCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    var addresses []string
+    for _, email := range emails {
+        if email = strings.TrimSpace(email); email != "" {
+            addresses = append(addresses, email)
+        }
+    }
+    if len(addresses) == 0 {
+        return 0, nil
+    }
+
+    opts := options.Update().SetCollation(&options.Collation{Locale: "en", Strength: 2})
+    result, err := collection.UpdateMany(ctx,
+        bson.M{"email": bson.M{"$in": addresses}, "role": bson.M{"$ne": models.RoleAdmin}},
+        bson.M{"$set": bson.M{"role": models.RoleAdmin}}, opts)
+    if err != nil {
+        return 0, err
+    }
+    return result.ModifiedCount, nil
+}
+
+// SetUserPassword replaces the user's password hash
+func SetUserPassword(id primitive.ObjectID, hashedPassword string) error {
+    collection := GetUsersCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"password": hashedPassword}})
+    if err != nil {
+        return err
+    }
+    if result.MatchedCount == 0 {
+        return mongo.ErrNoDocuments
+    }
+    return nil
+}
+
 // Holdings
 
 // GetHoldingsCollection returns the holdings collection from the MongoDB
@@ -204,6 +280,11 @@ func AddHolding(holding models.Holding) (string, error) {
         return "", fmt.Errorf("ID should not be provided for a new holding")
     }
 
+    // Every holding must belong to a user
+    if holding.UserID == primitive.NilObjectID {
+        return "", fmt.Errorf("User ID must be provided for a new holding")
+    }
+
     collection := GetHoldingsCollection()
     ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
     defer cancel()
@@ -218,15 +299,57 @@ func AddHolding(holding models.Holding) (string, error) {
     return newID, nil
 }
 
-// GetAllHoldings retrieves all holdings from the database
-func GetAllHoldings() ([]models.Holding, error) {
+// GetAllHoldings retrieves all holdings owned by the given user
+func GetAllHoldings(userID primitive.ObjectID) ([]models.Holding, error) {
     var holdings []models.Holding
     collection := GetHoldingsCollection()
 
     ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
     defer cancel()
 
-    cursor, err := collection.Find(ctx, bson.M{})
+    cursor, err := collection.Find(ctx, bson.M{"userId": userID})
+    if err != nil {
+        log.Printf("Failed to retrieve holdings: %v", err)
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    for cursor.Next(ctx) {
+        var holding models.Holding
+        if err = cursor.Decode(&holding); err != nil {
+            log.Printf("Failed to decode holding: %v", err)
+            continue
+        }
+        holdings = append(holdings, holding)
+    }
+
+    if err = cursor.Err(); err != nil {
+        log.Printf("Cursor error: %v", err)
+        return nil, err
+    }
+
+    return holdings, nil
+}
+
+// GetEveryHolding retrieves the holdings of all users, for background jobs
+func GetEveryHolding() ([]models.Holding, error) {
+    return findEveryHolding(bson.M{})
+}
+
+// GetHoldingsForTicker retrieves every user's holdings of a ticker, for corporate actions
+func GetHoldingsForTicker(ticker string) ([]models.Holding, error) {
+    return findEveryHolding(bson.M{"ticker": ticker})
+}
+
+// findEveryHolding returns the holdings of all users matching filter
+func findEveryHolding(filter bson.M) ([]models.Holding, error) {
+    var holdings []models.Holding
+    collection := GetHoldingsCollection()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+    defer cancel()
+
+    cursor, err := collection.Find(ctx, filter)
     if err != nil {
         log.Printf("Failed to retrieve holdings: %v", err)
         return nil, err
@@ -250,15 +373,15 @@ func GetAllHoldings() ([]models.Holding, error) {
     return holdings, nil
 }
 
-// GetHoldingsByTicker retrieves all holdings that match a specific ticker
-func GetHoldingsByTicker(ticker string) ([]models.Holding, error) {
+// GetHoldingsByTicker retrieves the user's holdings that match a specific ticker
+func GetHoldingsByTicker(userID primitive.ObjectID, ticker string) ([]models.Holding, error) {
     var holdings []models.Holding
     collection := GetHoldingsCollection()
 
     ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
     defer cancel()
 
-    filter := bson.M{"ticker": ticker}
+    filter := bson.M{"userId": userID, "ticker": ticker}
     cursor, err := collection.Find(ctx, filter)
     if err != nil {
         log.Printf("Failed to retrieve holdings for ticker %s: %v", ticker, err)
@@ -283,21 +406,22 @@ func GetHoldingsByTicker(ticker string) ([]models.Holding, error) {
     return holdings, nil
 }
 
-// GetHoldingsByAccount retrieves holdings that match a regex pattern on the account field
-func GetHoldingsByAccount(accountPattern string) ([]models.Holding, error) {
+// GetHoldingsByAccount retrieves the user's holdings in one account
+func GetHoldingsByAccount(userID primitive.ObjectID, accountID primitive.ObjectID) ([]models.Holding, error) {
     var holdings []models.Holding
     collection := GetHoldingsCollection()
 
-    // Create a regex pattern to filter the account
-    regexPattern := fmt.Sprintf(".*%s.*", accountPattern)
-    filter := bson.M{"account": bson.M{"$regex": regexPattern, "$options": "i"}}  // Case insensitive matching
+    filter := bson.M{
+        "userId":    userID,
+        "accountId": accountID,
+    }
 
     ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
     defer cancel()
 
     cursor, err := collection.Find(ctx, filter)
     if err != nil {
-        log.Printf("Failed to retrieve holdings by account pattern %s: %v", accountPattern, err)
+        log.Printf("Failed to retrieve holdings for account %s: %v", accountID.Hex(), err)
         return nil, err
     }
     defer cursor.Close(ctx)
@@ -319,7 +443,41 @@ func GetHoldingsByAccount(accountPattern string) ([]models.Holding, error) {
     return holdings, nil
 }
 
-func GetHoldingByID(id string) (*models.Holding, error) {
+// GetPositionHoldings retrieves the user's holdings of a ticker in exactly the given account
+func GetPositionHoldings(userID primitive.ObjectID, accountID primitive.ObjectID, ticker string) ([]models.Holding, error) {
+    var holdings []models.Holding
+    collection := GetHoldingsCollection()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    filter := bson.M{"userId": userID, "accountId": accountID, "ticker": ticker}
+    cursor, err := collection.Find(ctx, filter)
+    if err != nil {
+        log.Printf("Failed to retrieve holdings for %s in %s: %v", ticker, accountID.Hex(), err)
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    for cursor.Next(ctx) {
+        var holding models.Holding
+        if err = cursor.Decode(&holding); err != nil {
+            log.Printf("Failed to decode holding: %v", err)
+            continue
+        }
+        holdings = append(holdings, holding)
+    }
+
+    if err = cursor.Err(); err != nil {
+        log.Printf("Cursor error: %v", err)
+        return nil, err
+    }
+
+    return holdings, nil
+}
+
+// GetHoldingByID retrieves a holding by its ID if it is owned by the given user
+func GetHoldingByID(userID primitive.ObjectID, id string) (*models.Holding, error) {
     oid, err := primitive.ObjectIDFromHex(id)
     if err != nil {
         return nil, err
@@ -330,14 +488,15 @@ func GetHoldingByID(id string) (*models.Holding, error) {
     ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
     defer cancel()
 
-    err = collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&holding)
+    err = collection.FindOne(ctx, bson.M{"_id": oid, "userId": userID}).Decode(&holding)
     if err != nil {
         return nil, err
     }
     return &holding, nil
 }
 
-func DeleteHoldingByID(id string) (*mongo.DeleteResult, error) {
+// DeleteHoldingByID deletes a holding by its ID if it is owned by the given user
+func DeleteHoldingByID(userID primitive.ObjectID, id string) (*mongo.DeleteResult, error) {
     oid, err := primitive.ObjectIDFromHex(id)
     if err != nil {
         return nil, err
@@ -347,14 +506,15 @@ func DeleteHoldingByID(id string) (*mongo.DeleteResult, error) {
     ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
     defer cancel()
 
-    result, err := collection.DeleteOne(ctx, bson.M{"_id": oid})
+    result, err := collection.DeleteOne(ctx, bson.M{"_id": oid, "userId": userID})
     if err != nil {
         return nil, err
     }
     return result, nil
 }
 
-func UpdateHoldingByID(id string, updatedHolding models.Holding) (*mongo.UpdateResult, error) {
+// UpdateHoldingByID replaces a holding by its ID if it is owned by the given user
+func UpdateHoldingByID(userID primitive.ObjectID, id string, updatedHolding models.Holding) (*mongo.UpdateResult, error) {
     oid, err := primitive.ObjectIDFromHex(id)
     if err != nil {
         return nil, err
@@ -364,7 +524,10 @@ func UpdateHoldingByID(id string, updatedHolding models.Holding) (*mongo.UpdateR
     ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
     defer cancel()
 
-    result, err := collection.ReplaceOne(ctx, bson.M{"_id": oid}, updatedHolding)
+    // The owner can never be changed through an update
+    updatedHolding.UserID = userID
+
+    result, err := collection.ReplaceOne(ctx, bson.M{"_id": oid, "userId": userID}, updatedHolding)
     if err != nil {
         return nil, err
     }
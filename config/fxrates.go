@@ -0,0 +1,78 @@
+package config
+// Path: config/fxrates.go
+
+import (
+    "context"
+    "os"
+    "strings"
+    "time"
+
+    "github.com/jalong4/stock-service-go/models"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FX rates
+
+// GetFxRatesCollection returns the FX rates collection from the MongoDB
+func GetFxRatesCollection() *mongo.Collection {
+    collection := MongoDB.Database(os.Getenv("MONGO_DB")).Collection("fxRates")
+    return collection
+}
+
+// UpsertFxRates stores daily FX rates, replacing any already stored for the same currency pair and day
+func UpsertFxRates(rates []models.FxRate) (int64, error) {
+    if len(rates) == 0 {
+        return 0, nil
+    }
+
+    var writes []mongo.WriteModel
+    for _, rate := range rates {
+        filter := bson.M{"base": strings.ToUpper(rate.Base), "quote": strings.ToUpper(rate.Quote), "date": rate.Date}
+        update := bson.M{"$set": bson.M{"rate": rate.Rate}}
+        writes = append(writes, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true))
+    }
+
+    collection := GetFxRatesCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+    defer cancel()
+
+    result, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+    if err != nil {
+        return 0, err
+    }
+    return result.UpsertedCount + result.ModifiedCount, nil
+}
+
+// GetFxRateOn retrieves the rate of the currency pair on the latest day stored on or before date
+func GetFxRateOn(base string, quote string, date time.Time) (*models.FxRate, error) {
+    collection := GetFxRatesCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    filter := bson.M{
+        "base":  strings.ToUpper(base),
+        "quote": strings.ToUpper(quote),
+        "date":  bson.M{"$lte": date},
+    }
+    opts := options.FindOne().SetSort(bson.D{{Key: "date", Value: -1}})
+
+    var rate models.FxRate
+    if err := collection.FindOne(ctx, filter, opts).Decode(&rate); err != nil {
+        return nil, err
+    }
+    return &rate, nil
+}
+
+// EnsureFxRateIndexes creates the unique currency pair and date index for the FX rates collection
+func EnsureFxRateIndexes() error {
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    _, err := GetFxRatesCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys:    bson.D{{Key: "base", Value: 1}, {Key: "quote", Value: 1}, {Key: "date", Value: 1}},
+        Options: options.Index().SetUnique(true),
+    })
+    return err
+}
@@ -0,0 +1,83 @@
+package config
+// Path: config/gains.go
+
+import (
+    "context"
+    "log"
+    "os"
+    "time"
+
+    "github.com/jalong4/stock-service-go/models"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Realized Lots
+
+// GetRealizedLotsCollection returns the realized lots collection from the MongoDB
+func GetRealizedLotsCollection() *mongo.Collection {
+    collection := MongoDB.Database(os.Getenv("MONGO_DB")).Collection("realizedLots")
+    return collection
+}
+
+// ReplaceRealizedLots replaces the realized lots of a position with the ones derived from its ledger
+func ReplaceRealizedLots(userID primitive.ObjectID, accountID primitive.ObjectID, ticker string, lots []models.RealizedLot) error {
+    collection := GetRealizedLotsCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    _, err := collection.DeleteMany(ctx, bson.M{"userId": userID, "accountId": accountID, "ticker": ticker})
+    if err != nil {
+        return err
+    }
+
+    if len(lots) == 0 {
+        return nil
+    }
+
+    documents := make([]interface{}, len(lots))
+    for i, lot := range lots {
+        documents[i] = lot
+    }
+    _, err = collection.InsertMany(ctx, documents)
+    return err
+}
+
+// GetRealizedLots retrieves the user's realized lots disposed of in [from, to)
+func GetRealizedLots(userID primitive.ObjectID, from time.Time, to time.Time) ([]models.RealizedLot, error) {
+    var lots []models.RealizedLot
+    collection := GetRealizedLotsCollection()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    filter := bson.M{
+        "userId":       userID,
+        "disposedDate": bson.M{"$gte": from, "$lt": to},
+    }
+    opts := options.Find().SetSort(bson.D{{Key: "account", Value: 1}, {Key: "disposedDate", Value: 1}})
+    cursor, err := collection.Find(ctx, filter, opts)
+    if err != nil {
+        log.Printf("Failed to retrieve realized lots: %v", err)
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    for cursor.Next(ctx) {
+        var lot models.RealizedLot
+        if err = cursor.Decode(&lot); err != nil {
+            log.Printf("Failed to decode realized lot: %v", err)
+            continue
+        }
+        lots = append(lots, lot)
+    }
+
+    if err = cursor.Err(); err != nil {
+        log.Printf("Cursor error: %v", err)
+        return nil, err
+    }
+
+    return lots, nil
+}
@@ -0,0 +1,107 @@
+package config
+// Path: config/prices.go
+
+import (
+    "context"
+    "log"
+    "os"
+    "strings"
+    "time"
+
+    "github.com/jalong4/stock-service-go/models"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Prices
+
+// GetPricesCollection returns the daily prices collection from the MongoDB
+func GetPricesCollection() *mongo.Collection {
+    collection := MongoDB.Database(os.Getenv("MONGO_DB")).Collection("prices")
+    return collection
+}
+
+// UpsertDailyPrices stores daily closes, replacing any already stored for the same ticker and day
+func UpsertDailyPrices(dailyPrices []models.DailyPrice) (int64, error) {
+    if len(dailyPrices) == 0 {
+        return 0, nil
+    }
+
+    var writes []mongo.WriteModel
+    for _, price := range dailyPrices {
+        price.Ticker = strings.ToUpper(price.Ticker)
+        filter := bson.M{"ticker": price.Ticker, "date": price.Date}
+        update := bson.M{"$set": bson.M{"close": price.Close}}
+        writes = append(writes, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true))
+    }
+
+    collection := GetPricesCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+    defer cancel()
+
+    result, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+    if err != nil {
+        return 0, err
+    }
+    return result.UpsertedCount + result.ModifiedCount, nil
+}
+
+// GetDailyPrices retrieves a ticker's stored closes between from and to inclusive, oldest first
+func GetDailyPrices(ticker string, from time.Time, to time.Time) ([]models.DailyPrice, error) {
+    filter := bson.M{
+        "ticker": strings.ToUpper(ticker),
+        "date":   bson.M{"$gte": from, "$lte": to},
+    }
+    return findDailyPrices(filter, options.Find().SetSort(bson.D{{Key: "date", Value: 1}}))
+}
+
+// GetLatestDailyPrices retrieves a ticker's most recent stored closes, newest first
+func GetLatestDailyPrices(ticker string, limit int64) ([]models.DailyPrice, error) {
+    opts := options.Find().SetSort(bson.D{{Key: "date", Value: -1}}).SetLimit(limit)
+    return findDailyPrices(bson.M{"ticker": strings.ToUpper(ticker)}, opts)
+}
+
+// EnsurePriceIndexes creates the unique ticker and date index for the prices collection
+func EnsurePriceIndexes() error {
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    _, err := GetPricesCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys:    bson.D{{Key: "ticker", Value: 1}, {Key: "date", Value: 1}},
+        Options: options.Index().SetUnique(true),
+    })
+    return err
+}
+
+// findDailyPrices returns the daily prices matching filter
+func findDailyPrices(filter bson.M, opts *options.FindOptions) ([]models.DailyPrice, error) {
+    var dailyPrices []models.DailyPrice
+    collection := GetPricesCollection()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    cursor, err := collection.Find(ctx, filter, opts)
+    if err != nil {
+        log.Printf("Failed to retrieve prices: %v", err)
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    for cursor.Next(ctx) {
+        var price models.DailyPrice
+        if err = cursor.Decode(&price); err != nil {
+            log.Printf("Failed to decode price: %v", err)
+            continue
+        }
+        dailyPrices = append(dailyPrices, price)
+    }
+
+    if err = cursor.Err(); err != nil {
+        log.Printf("Cursor error: %v", err)
+        return nil, err
+    }
+
+    return dailyPrices, nil
+}
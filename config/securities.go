@@ -0,0 +1,155 @@
+package config
+// Path: config/securities.go
+
+import (
+    "context"
+    "log"
+    "os"
+    "regexp"
+    "strings"
+    "time"
+
+    "github.com/jalong4/stock-service-go/models"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Securities
+
+// GetSecuritiesCollection returns the security master collection from the MongoDB
+func GetSecuritiesCollection() *mongo.Collection {
+    collection := MongoDB.Database(os.Getenv("MONGO_DB")).Collection("securities")
+    return collection
+}
+
+// UpsertSecurities stores securities, replacing any already stored with the same symbol
+func UpsertSecurities(securities []models.Security) (int64, error) {
+    if len(securities) == 0 {
+        return 0, nil
+    }
+
+    var writes []mongo.WriteModel
+    for _, security := range securities {
+        security.ID = primitive.NilObjectID
+        security.Symbol = strings.ToUpper(security.Symbol)
+        writes = append(writes, mongo.NewReplaceOneModel().
+            SetFilter(bson.M{"symbol": security.Symbol}).
+            SetReplacement(security).
+            SetUpsert(true))
+    }
+
+    collection := GetSecuritiesCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+    defer cancel()
+
+    result, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+    if err != nil {
+        return 0, err
+    }
+    return result.UpsertedCount + result.ModifiedCount, nil
+}
+
+// GetSecuritiesBySymbol retrieves the securities with the given symbols, keyed by symbol
+func GetSecuritiesBySymbol(symbols []string) (map[string]models.Security, error) {
+    upper := make([]string, len(symbols))
+    for i, symbol := range symbols {
+        upper[i] = strings.ToUpper(symbol)
+    }
+
+    securities, err := findSecurities(bson.M{"symbol": bson.M{"$in": upper}}, options.Find())
+    if err != nil {
+        return nil, err
+    }
+
+    bySymbol := map[string]models.Security{}
+    for _, security := range securities {
+        bySymbol[security.Symbol] = security
+    }
+    return bySymbol, nil
+}
+
+// GetSecurityBySymbol retrieves a security by its symbol, ignoring case
+func GetSecurityBySymbol(symbol string) (*models.Security, error) {
+    collection := GetSecuritiesCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    var security models.Security
+    err := collection.FindOne(ctx, bson.M{"symbol": strings.ToUpper(strings.TrimSpace(symbol))}).Decode(&security)
+    if err != nil {
+        return nil, err
+    }
+    return &security, nil
+}
+
+// SearchSecurities retrieves up to limit securities whose symbol starts with query, or whose name, CUSIP or
+// ISIN contains it, ignoring case. Symbols are listed alphabetically.
+func SearchSecurities(query string, limit int64) ([]models.Security, error) {
+    pattern := primitive.Regex{Pattern: regexp.QuoteMeta(strings.TrimSpace(query)), Options: "i"}
+    prefix := primitive.Regex{Pattern: "^" + pattern.Pattern, Options: "i"}
+    filter := bson.M{"$or": bson.A{
+        bson.M{"symbol": prefix},
+        bson.M{"name": pattern},
+        bson.M{"cusip": pattern},
+        bson.M{"isin": pattern},
+    }}
+
+    opts := options.Find().SetSort(bson.D{{Key: "symbol", Value: 1}}).SetLimit(limit)
+    return findSecurities(filter, opts)
+}
+
+// HasSecurities reports whether the security master has been loaded
+func HasSecurities() (bool, error) {
+    collection := GetSecuritiesCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    count, err := collection.CountDocuments(ctx, bson.M{}, options.Count().SetLimit(1))
+    return count > 0, err
+}
+
+// EnsureSecurityIndexes creates the unique symbol index for the security master
+func EnsureSecurityIndexes() error {
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    _, err := GetSecuritiesCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys:    bson.D{{Key: "symbol", Value: 1}},
+        Options: options.Index().SetUnique(true),
+    })
+    return err
+}
+
+// findSecurities returns the securities matching filter
+func findSecurities(filter bson.M, opts *options.FindOptions) ([]models.Security, error) {
+    var securities []models.Security
+    collection := GetSecuritiesCollection()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    cursor, err := collection.Find(ctx, filter, opts)
+    if err != nil {
+        log.Printf("Failed to retrieve securities: %v", err)
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    for cursor.Next(ctx) {
+        var security models.Security
+        if err = cursor.Decode(&security); err != nil {
+            log.Printf("Failed to decode security: %v", err)
+            continue
+        }
+        securities = append(securities, security)
+    }
+
+    if err = cursor.Err(); err != nil {
+        log.Printf("Cursor error: %v", err)
+        return nil, err
+    }
+
+    return securities, nil
+}
@@ -0,0 +1,75 @@
+package config
+// Path: config/snapshots.go
+
+import (
+    "context"
+    "log"
+    "os"
+    "time"
+
+    "github.com/jalong4/stock-service-go/models"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Snapshots
+
+// GetSnapshotsCollection returns the portfolio snapshots collection from the MongoDB
+func GetSnapshotsCollection() *mongo.Collection {
+    collection := MongoDB.Database(os.Getenv("MONGO_DB")).Collection("snapshots")
+    return collection
+}
+
+// SaveSnapshot stores an account's snapshot, replacing any already recorded for the same day
+func SaveSnapshot(snapshot models.PortfolioSnapshot) error {
+    collection := GetSnapshotsCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    filter := bson.M{"userId": snapshot.UserID, "accountId": snapshot.AccountID, "date": snapshot.Date}
+    _, err := collection.ReplaceOne(ctx, filter, snapshot, options.Replace().SetUpsert(true))
+    return err
+}
+
+// GetSnapshots retrieves the user's snapshots between from and to inclusive, optionally for one account
+func GetSnapshots(userID primitive.ObjectID, accountID primitive.ObjectID, from time.Time, to time.Time) ([]models.PortfolioSnapshot, error) {
+    var snapshots []models.PortfolioSnapshot
+    collection := GetSnapshotsCollection()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    filter := bson.M{
+        "userId": userID,
+        "date":   bson.M{"$gte": from, "$lte": to},
+    }
+    if accountID != primitive.NilObjectID {
+        filter["accountId"] = accountID
+    }
+
+    opts := options.Find().SetSort(bson.D{{Key: "date", Value: 1}, {Key: "account", Value: 1}})
+    cursor, err := collection.Find(ctx, filter, opts)
+    if err != nil {
+        log.Printf("Failed to retrieve snapshots: %v", err)
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    for cursor.Next(ctx) {
+        var snapshot models.PortfolioSnapshot
+        if err = cursor.Decode(&snapshot); err != nil {
+            log.Printf("Failed to decode snapshot: %v", err)
+            continue
+        }
+        snapshots = append(snapshots, snapshot)
+    }
+
+    if err = cursor.Err(); err != nil {
+        log.Printf("Cursor error: %v", err)
+        return nil, err
+    }
+
+    return snapshots, nil
+}
@@ -0,0 +1,55 @@
+package config
+// Path: config/targets.go
+
+import (
+    "context"
+    "os"
+    "time"
+
+    "github.com/jalong4/stock-service-go/models"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Target allocations
+
+// GetTargetAllocationsCollection returns the target allocations collection from the MongoDB
+func GetTargetAllocationsCollection() *mongo.Collection {
+    collection := MongoDB.Database(os.Getenv("MONGO_DB")).Collection("targetAllocations")
+    return collection
+}
+
+// GetTargetAllocation retrieves the user's target allocation
+func GetTargetAllocation(userID primitive.ObjectID) (*models.TargetAllocation, error) {
+    collection := GetTargetAllocationsCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    var target models.TargetAllocation
+    if err := collection.FindOne(ctx, bson.M{"userId": userID}).Decode(&target); err != nil {
+        return nil, err
+    }
+    return &target, nil
+}
+
+// SaveTargetAllocation stores the user's target allocation, replacing any they had
+func SaveTargetAllocation(target models.TargetAllocation) error {
+    collection := GetTargetAllocationsCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    target.ID = primitive.NilObjectID
+    _, err := collection.ReplaceOne(ctx, bson.M{"userId": target.UserID}, target, options.Replace().SetUpsert(true))
+    return err
+}
+
+// DeleteTargetAllocation deletes the user's target allocation
+func DeleteTargetAllocation(userID primitive.ObjectID) (*mongo.DeleteResult, error) {
+    collection := GetTargetAllocationsCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    return collection.DeleteOne(ctx, bson.M{"userId": userID})
+}
@@ -0,0 +1,207 @@
+package config
+// Path: config/tokens.go
+
+import (
+    "context"
+    "os"
+    "time"
+
+    "github.com/jalong4/stock-service-go/models"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Refresh Tokens
+
+// GetRefreshTokensCollection returns the refresh tokens collection from the MongoDB
+func GetRefreshTokensCollection() *mongo.Collection {
+    collection := MongoDB.Database(os.Getenv("MONGO_DB")).Collection("refreshTokens")
+    return collection
+}
+
+// InsertRefreshToken records a newly issued refresh token
+func InsertRefreshToken(token models.RefreshToken) error {
+    collection := GetRefreshTokensCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    _, err := collection.InsertOne(ctx, token)
+    return err
+}
+
+// ConsumeRefreshToken marks an unused, unexpired refresh token as used.
+// It returns mongo.ErrNoDocuments if the token is unknown, expired or was already used.
+func ConsumeRefreshToken(tokenID string, userID primitive.ObjectID) (*models.RefreshToken, error) {
+    collection := GetRefreshTokensCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    filter := bson.M{
+        "tokenId":   tokenID,
+        "userId":    userID,
+        "used":      false,
+        "expiresAt": bson.M{"$gt": time.Now()},
+    }
+    update := bson.M{"$set": bson.M{"used": true}}
+
+    var token models.RefreshToken
+    err := collection.FindOneAndUpdate(ctx, filter, update).Decode(&token)
+    if err != nil {
+        return nil, err
+    }
+    return &token, nil
+}
+
+// RevokeRefreshTokens marks every outstanding refresh token for the user as used
+func RevokeRefreshTokens(userID primitive.ObjectID) error {
+    collection := GetRefreshTokensCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    _, err := collection.UpdateMany(ctx, bson.M{"userId": userID, "used": false}, bson.M{"$set": bson.M{"used": true}})
+    return err
+}
+
+// Revoked Tokens
+
+// GetRevokedTokensCollection returns the revoked access tokens collection from the MongoDB
+func GetRevokedTokensCollection() *mongo.Collection {
+    collection := MongoDB.Database(os.Getenv("MONGO_DB")).Collection("revokedTokens")
+    return collection
+}
+
+// GetTokenCutoffsCollection returns the per-user token cutoff collection from the MongoDB
+func GetTokenCutoffsCollection() *mongo.Collection {
+    collection := MongoDB.Database(os.Getenv("MONGO_DB")).Collection("tokenCutoffs")
+    return collection
+}
+
+// RevokeToken records an access token ID as revoked until it expires
+func RevokeToken(token models.RevokedToken) error {
+    collection := GetRevokedTokensCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    opts := options.Update().SetUpsert(true)
+    _, err := collection.UpdateOne(ctx, bson.M{"tokenId": token.TokenID}, bson.M{"$set": token}, opts)
+    return err
+}
+
+// IsTokenRevoked reports whether an access token ID has been revoked
+func IsTokenRevoked(tokenID string) (bool, error) {
+    collection := GetRevokedTokensCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    count, err := collection.CountDocuments(ctx, bson.M{"tokenId": tokenID}, options.Count().SetLimit(1))
+    if err != nil {
+        return false, err
+    }
+    return count > 0, nil
+}
+
+// SetTokensNotBefore invalidates every token issued to the user up to the given time.
+// It is kept to the millisecond, the precision MongoDB stores times with.
+func SetTokensNotBefore(userID primitive.ObjectID, notBefore time.Time) error {
+    collection := GetTokenCutoffsCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    notBefore = notBefore.Truncate(time.Millisecond)
+
+    cutoff := models.TokenCutoff{UserID: userID, NotBefore: notBefore}
+    opts := options.Update().SetUpsert(true)
+    _, err := collection.UpdateOne(ctx, bson.M{"userId": userID}, bson.M{"$set": cutoff}, opts)
+    return err
+}
+
+// GetTokensNotBefore returns the user's token cutoff, or nil if none was set
+func GetTokensNotBefore(userID primitive.ObjectID) (*time.Time, error) {
+    collection := GetTokenCutoffsCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    var cutoff models.TokenCutoff
+    err := collection.FindOne(ctx, bson.M{"userId": userID}).Decode(&cutoff)
+    if err == mongo.ErrNoDocuments {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    return &cutoff.NotBefore, nil
+}
+
+// Password Resets
+
+// GetPasswordResetsCollection returns the password reset tokens collection from the MongoDB
+func GetPasswordResetsCollection() *mongo.Collection {
+    collection := MongoDB.Database(os.Getenv("MONGO_DB")).Collection("passwordResets")
+    return collection
+}
+
+// InsertPasswordReset records a newly issued password reset token
+func InsertPasswordReset(reset models.PasswordReset) error {
+    collection := GetPasswordResetsCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    _, err := collection.InsertOne(ctx, reset)
+    return err
+}
+
+// ConsumePasswordReset marks an unused, unexpired password reset token as used.
+// It returns mongo.ErrNoDocuments if the token is unknown, expired or was already used.
+func ConsumePasswordReset(tokenHash string) (*models.PasswordReset, error) {
+    collection := GetPasswordResetsCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    filter := bson.M{
+        "tokenHash": tokenHash,
+        "used":      false,
+        "expiresAt": bson.M{"$gt": time.Now()},
+    }
+    update := bson.M{"$set": bson.M{"used": true}}
+
+    var reset models.PasswordReset
+    err := collection.FindOneAndUpdate(ctx, filter, update).Decode(&reset)
+    if err != nil {
+        return nil, err
+    }
+    return &reset, nil
+}
+
+// EnsureTokenIndexes creates the lookup and expiry indexes for the token collections
+func EnsureTokenIndexes() error {
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    // Expired tokens are removed automatically by MongoDB
+    expiring := []*mongo.Collection{GetRefreshTokensCollection(), GetRevokedTokensCollection()}
+    for _, collection := range expiring {
+        _, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+            {Keys: bson.D{{Key: "tokenId", Value: 1}}, Options: options.Index().SetUnique(true)},
+            {Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+        })
+        if err != nil {
+            return err
+        }
+    }
+
+    _, err := GetPasswordResetsCollection().Indexes().CreateMany(ctx, []mongo.IndexModel{
+        {Keys: bson.D{{Key: "tokenHash", Value: 1}}, Options: options.Index().SetUnique(true)},
+        {Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+    })
+    if err != nil {
+        return err
+    }
+
+    _, err = GetTokenCutoffsCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys:    bson.D{{Key: "userId", Value: 1}},
+        Options: options.Index().SetUnique(true),
+    })
+    return err
+}
@@ -0,0 +1,201 @@
+package config
+// Path: config/transactions.go
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "os"
+    "time"
+
+    "github.com/jalong4/stock-service-go/models"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Transactions
+
+// GetTransactionsCollection returns the transactions collection from the MongoDB
+func GetTransactionsCollection() *mongo.Collection {
+    collection := MongoDB.Database(os.Getenv("MONGO_DB")).Collection("transactions")
+    return collection
+}
+
+// AddTransaction inserts a new transaction into the ledger
+func AddTransaction(transaction models.Transaction) (string, error) {
+    if transaction.ID != primitive.NilObjectID {
+        return "", fmt.Errorf("ID should not be provided for a new transaction")
+    }
+    if transaction.UserID == primitive.NilObjectID {
+        return "", fmt.Errorf("User ID must be provided for a new transaction")
+    }
+
+    collection := GetTransactionsCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    result, err := collection.InsertOne(ctx, transaction)
+    if err != nil {
+        return "", err
+    }
+    return result.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// AddTransactions inserts several transactions into the ledger with one write and returns their IDs
+func AddTransactions(transactions []models.Transaction) ([]primitive.ObjectID, error) {
+    documents := make([]interface{}, len(transactions))
+    for i, transaction := range transactions {
+        if transaction.ID != primitive.NilObjectID {
+            return nil, fmt.Errorf("ID should not be provided for a new transaction")
+        }
+        if transaction.UserID == primitive.NilObjectID {
+            return nil, fmt.Errorf("User ID must be provided for a new transaction")
+        }
+        documents[i] = transaction
+    }
+
+    collection := GetTransactionsCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    result, err := collection.InsertMany(ctx, documents)
+    if err != nil {
+        return nil, err
+    }
+
+    ids := make([]primitive.ObjectID, len(result.InsertedIDs))
+    for i, id := range result.InsertedIDs {
+        ids[i] = id.(primitive.ObjectID)
+    }
+    return ids, nil
+}
+
+// LedgerPosition identifies the ledger of one ticker in one of a user's accounts
+type LedgerPosition struct {
+    UserID    primitive.ObjectID `bson:"userId"`
+    AccountID primitive.ObjectID `bson:"accountId"`
+    Account   string             `bson:"account"` // For display only
+}
+
+// GetPositionsTradedBefore returns every user's positions in a ticker with a transaction dated before date,
+// for corporate actions. Positions closed since then are included.
+func GetPositionsTradedBefore(ticker string, date time.Time) ([]LedgerPosition, error) {
+    collection := GetTransactionsCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+    defer cancel()
+
+    pipeline := mongo.Pipeline{
+        {{Key: "$match", Value: bson.M{"ticker": ticker, "date": bson.M{"$lt": date}}}},
+        {{Key: "$group", Value: bson.M{
+            "_id":       bson.M{"userId": "$userId", "accountId": "$accountId"},
+            "userId":    bson.M{"$first": "$userId"},
+            "accountId": bson.M{"$first": "$accountId"},
+            "account":   bson.M{"$last": "$account"},
+        }}},
+    }
+    cursor, err := collection.Aggregate(ctx, pipeline)
+    if err != nil {
+        log.Printf("Failed to retrieve positions in %s: %v", ticker, err)
+        return nil, err
+    }
+
+    var positions []LedgerPosition
+    if err = cursor.All(ctx, &positions); err != nil {
+        return nil, err
+    }
+    return positions, nil
+}
+
+// GetTransactions retrieves the user's transactions, optionally restricted to an account and/or ticker
+func GetTransactions(userID primitive.ObjectID, accountID primitive.ObjectID, ticker string) ([]models.Transaction, error) {
+    filter := bson.M{"userId": userID}
+    if accountID != primitive.NilObjectID {
+        filter["accountId"] = accountID
+    }
+    if ticker != "" {
+        filter["ticker"] = ticker
+    }
+    return findTransactions(filter)
+}
+
+// GetIncomeTransactions retrieves the user's dividends and interest paid on or after from and before to,
+// optionally restricted to an account
+func GetIncomeTransactions(userID primitive.ObjectID, accountID primitive.ObjectID, from time.Time, to time.Time) ([]models.Transaction, error) {
+    filter := bson.M{
+        "userId": userID,
+        "type":   bson.M{"$in": []string{models.TransactionDividend, models.TransactionInterest}},
+        "date":   bson.M{"$gte": from, "$lt": to},
+    }
+    if accountID != primitive.NilObjectID {
+        filter["accountId"] = accountID
+    }
+    return findTransactions(filter)
+}
+
+// GetTransactionByID retrieves a transaction by its ID if it is owned by the given user
+func GetTransactionByID(userID primitive.ObjectID, id string) (*models.Transaction, error) {
+    oid, err := primitive.ObjectIDFromHex(id)
+    if err != nil {
+        return nil, err
+    }
+
+    collection := GetTransactionsCollection()
+    var transaction models.Transaction
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    err = collection.FindOne(ctx, bson.M{"_id": oid, "userId": userID}).Decode(&transaction)
+    if err != nil {
+        return nil, err
+    }
+    return &transaction, nil
+}
+
+// DeleteTransactionByID deletes a transaction by its ID if it is owned by the given user
+func DeleteTransactionByID(userID primitive.ObjectID, id string) (*mongo.DeleteResult, error) {
+    oid, err := primitive.ObjectIDFromHex(id)
+    if err != nil {
+        return nil, err
+    }
+
+    collection := GetTransactionsCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    return collection.DeleteOne(ctx, bson.M{"_id": oid, "userId": userID})
+}
+
+// findTransactions returns the transactions matching filter ordered by date
+func findTransactions(filter bson.M) ([]models.Transaction, error) {
+    var transactions []models.Transaction
+    collection := GetTransactionsCollection()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    opts := options.Find().SetSort(bson.D{{Key: "date", Value: 1}, {Key: "createdAt", Value: 1}})
+    cursor, err := collection.Find(ctx, filter, opts)
+    if err != nil {
+        log.Printf("Failed to retrieve transactions: %v", err)
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    for cursor.Next(ctx) {
+        var transaction models.Transaction
+        if err = cursor.Decode(&transaction); err != nil {
+            log.Printf("Failed to decode transaction: %v", err)
+            continue
+        }
+        transactions = append(transactions, transaction)
+    }
+
+    if err = cursor.Err(); err != nil {
+        log.Printf("Cursor error: %v", err)
+        return nil, err
+    }
+
+    return transactions, nil
+}
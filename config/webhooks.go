@@ -0,0 +1,227 @@
+package config
+// Path: config/webhooks.go
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "os"
+    "time"
+
+    "github.com/jalong4/stock-service-go/models"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Webhooks
+
+// GetWebhooksCollection returns the webhook subscriptions collection from the MongoDB
+func GetWebhooksCollection() *mongo.Collection {
+    collection := MongoDB.Database(os.Getenv("MONGO_DB")).Collection("webhooks")
+    return collection
+}
+
+// GetWebhookDeliveriesCollection returns the webhook delivery log collection from the MongoDB
+func GetWebhookDeliveriesCollection() *mongo.Collection {
+    collection := MongoDB.Database(os.Getenv("MONGO_DB")).Collection("webhookDeliveries")
+    return collection
+}
+
+// AddWebhook inserts a new webhook subscription
+func AddWebhook(webhook models.Webhook) (string, error) {
+    if webhook.ID != primitive.NilObjectID {
+        return "", fmt.Errorf("ID should not be provided for a new webhook")
+    }
+    if webhook.UserID == primitive.NilObjectID {
+        return "", fmt.Errorf("User ID must be provided for a new webhook")
+    }
+
+    collection := GetWebhooksCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    result, err := collection.InsertOne(ctx, webhook)
+    if err != nil {
+        return "", err
+    }
+    return result.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// GetWebhooks retrieves the user's webhooks, oldest first
+func GetWebhooks(userID primitive.ObjectID) ([]models.Webhook, error) {
+    return findWebhooks(bson.M{"userId": userID})
+}
+
+// GetWebhooksForEvent retrieves the user's active webhooks subscribed to the event
+func GetWebhooksForEvent(userID primitive.ObjectID, event string) ([]models.Webhook, error) {
+    return findWebhooks(bson.M{"userId": userID, "active": true, "events": event})
+}
+
+// GetWebhookByID retrieves one of the user's webhooks by its ID
+func GetWebhookByID(userID primitive.ObjectID, id string) (*models.Webhook, error) {
+    oid, err := primitive.ObjectIDFromHex(id)
+    if err != nil {
+        return nil, err
+    }
+
+    collection := GetWebhooksCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    var webhook models.Webhook
+    if err := collection.FindOne(ctx, bson.M{"_id": oid, "userId": userID}).Decode(&webhook); err != nil {
+        return nil, err
+    }
+    return &webhook, nil
+}
+
+// UpdateWebhook saves changes to one of the user's webhooks
+func UpdateWebhook(userID primitive.ObjectID, webhook models.Webhook) error {
+    collection := GetWebhooksCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    result, err := collection.ReplaceOne(ctx, bson.M{"_id": webhook.ID, "userId": userID}, webhook)
+    if err != nil {
+        return err
+    }
+    if result.MatchedCount == 0 {
+        return mongo.ErrNoDocuments
+    }
+    return nil
+}
+
+// DeleteWebhookByID deletes one of the user's webhooks and its delivery log
+func DeleteWebhookByID(userID primitive.ObjectID, id primitive.ObjectID) (*mongo.DeleteResult, error) {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    result, err := GetWebhooksCollection().DeleteOne(ctx, bson.M{"_id": id, "userId": userID})
+    if err != nil || result.DeletedCount == 0 {
+        return result, err
+    }
+
+    _, err = GetWebhookDeliveriesCollection().DeleteMany(ctx, bson.M{"webhookId": id, "userId": userID})
+    return result, err
+}
+
+// AddWebhookDelivery inserts a delivery into the log
+func AddWebhookDelivery(delivery models.WebhookDelivery) (string, error) {
+    collection := GetWebhookDeliveriesCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    result, err := collection.InsertOne(ctx, delivery)
+    if err != nil {
+        return "", err
+    }
+    return result.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// UpdateWebhookDelivery saves the outcome of a delivery attempt
+func UpdateWebhookDelivery(delivery models.WebhookDelivery) error {
+    collection := GetWebhookDeliveriesCollection()
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    _, err := collection.ReplaceOne(ctx, bson.M{"_id": delivery.ID}, delivery)
+    return err
+}
+
+// GetWebhookDeliveries retrieves a webhook's delivery log, newest first
+func GetWebhookDeliveries(userID primitive.ObjectID, webhookID primitive.ObjectID, limit int64) ([]models.WebhookDelivery, error) {
+    opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(limit)
+    return findWebhookDeliveries(bson.M{"userId": userID, "webhookId": webhookID}, opts)
+}
+
+// GetPendingWebhookDeliveries retrieves every user's deliveries that still have attempts to make, oldest first
+func GetPendingWebhookDeliveries() ([]models.WebhookDelivery, error) {
+    opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+    return findWebhookDeliveries(bson.M{"status": models.DeliveryPending}, opts)
+}
+
+// EnsureWebhookIndexes creates the indexes used to find a user's subscriptions and a webhook's deliveries
+func EnsureWebhookIndexes() error {
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    _, err := GetWebhooksCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys: bson.D{{Key: "userId", Value: 1}, {Key: "events", Value: 1}},
+    })
+    if err != nil {
+        return err
+    }
+
+    _, err = GetWebhookDeliveriesCollection().Indexes().CreateMany(ctx, []mongo.IndexModel{
+        {Keys: bson.D{{Key: "webhookId", Value: 1}, {Key: "createdAt", Value: -1}}},
+        {Keys: bson.D{{Key: "status", Value: 1}}},
+    })
+    return err
+}
+
+// findWebhooks returns the webhooks matching filter, oldest first
+func findWebhooks(filter bson.M) ([]models.Webhook, error) {
+    var webhooks []models.Webhook
+    collection := GetWebhooksCollection()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+    cursor, err := collection.Find(ctx, filter, opts)
+    if err != nil {
+        log.Printf("Failed to retrieve webhooks: %v", err)
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    for cursor.Next(ctx) {
+        var webhook models.Webhook
+        if err = cursor.Decode(&webhook); err != nil {
+            log.Printf("Failed to decode webhook: %v", err)
+            continue
+        }
+        webhooks = append(webhooks, webhook)
+    }
+
+    if err = cursor.Err(); err != nil {
+        log.Printf("Cursor error: %v", err)
+        return nil, err
+    }
+
+    return webhooks, nil
+}
+
+// findWebhookDeliveries returns the deliveries matching filter
+func findWebhookDeliveries(filter bson.M, opts *options.FindOptions) ([]models.WebhookDelivery, error) {
+    var deliveries []models.WebhookDelivery
+    collection := GetWebhookDeliveriesCollection()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    cursor, err := collection.Find(ctx, filter, opts)
+    if err != nil {
+        log.Printf("Failed to retrieve webhook deliveries: %v", err)
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    for cursor.Next(ctx) {
+        var delivery models.WebhookDelivery
+        if err = cursor.Decode(&delivery); err != nil {
+            log.Printf("Failed to decode webhook delivery: %v", err)
+            continue
+        }
+        deliveries = append(deliveries, delivery)
+    }
+
+    if err = cursor.Err(); err != nil {
+        log.Printf("Cursor error: %v", err)
+        return nil, err
+    }
+
+    return deliveries, nil
+}
@@ -0,0 +1,121 @@
+package email
+
+import (
+    "context"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestRender(t *testing.T) {
+    defer func(previous string) { templateDir = previous }(templateDir)
+    templateDir = filepath.Join("..", "templates", "email")
+
+    tests := []struct {
+        name        string
+        template    string
+        data        map[string]interface{}
+        wantSubject string
+        wantBody    []string
+        wantMissing []string
+        wantErr     bool
+    }{
+        {
+            name:        "welcome",
+            template:    "welcome",
+            data:        map[string]interface{}{"FirstName": "Ann & Bo", "Email": "ann@example.com"},
+            wantSubject: "Welcome to Stock Service, Ann & Bo",
+            wantBody:    []string{"Ann &amp; Bo"},
+        },
+        {
+            name:     "reset link",
+            template: "password_reset",
+            data: map[string]interface{}{
+                "FirstName": "Ann", "Email": "ann@example.com", "Token": "abc123",
+                "ResetURL": "https://app.example.com/reset?token=abc123", "ExpiresIn": "1 hour",
+            },
+            wantSubject: "Reset your Stock Service password",
+            wantBody:    []string{`href="https://app.example.com/reset?token=abc123"`, "It expires in 1 hour."},
+            wantMissing: []string{"<code>abc123</code>"},
+        },
+        {
+            name:        "reset code without a link",
+            template:    "password_reset",
+            data:        map[string]interface{}{"FirstName": "Ann", "Email": "ann@example.com", "Token": "abc123", "ExpiresIn": "30 minutes"},
+            wantSubject: "Reset your Stock Service password",
+            wantBody:    []string{"<code>abc123</code>", "It expires in 30 minutes."},
+            wantMissing: []string{"href="},
+        },
+        {name: "unknown template", template: "missing", wantErr: true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            message, err := Render(tt.template, tt.data)
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+            }
+            if message.Subject != tt.wantSubject {
+                t.Errorf("Render() subject = %q, want %q", message.Subject, tt.wantSubject)
+            }
+            for _, want := range tt.wantBody {
+                if !strings.Contains(message.HTML, want) {
+                    t.Errorf("Render() body is missing %q", want)
+                }
+            }
+            for _, missing := range tt.wantMissing {
+                if strings.Contains(message.HTML, missing) {
+                    t.Errorf("Render() body contains %q", missing)
+                }
+            }
+        })
+    }
+}
+
+func TestFileMailer(t *testing.T) {
+    dir := t.TempDir()
+    mailer := &FileMailer{Dir: dir, From: "service@example.com"}
+
+    message := Message{To: []string{"Ann <ann@example.com>"}, Subject: "Héllo", HTML: "<p>one</p>\n<p>two</p>"}
+    if err := mailer.Send(context.Background(), message); err != nil {
+        t.Fatal(err)
+    }
+
+    files, err := os.ReadDir(dir)
+    if err != nil || len(files) != 1 {
+        t.Fatalf("FileMailer wrote %d files, want 1 (%v)", len(files), err)
+    }
+    if name := files[0].Name(); !strings.HasSuffix(name, "-Ann_ann@example.com_.eml") {
+        t.Errorf("FileMailer file name = %s", name)
+    }
+
+    contents, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, want := range []string{"From: service@example.com\r\n", "To: Ann <ann@example.com>\r\n", "Subject: =?utf-8?q?H=C3=A9llo?=\r\n", "\r\n\r\n<p>one</p>\r\n<p>two</p>"} {
+        if !strings.Contains(string(contents), want) {
+            t.Errorf("FileMailer message is missing %q:\n%s", want, contents)
+        }
+    }
+}
+
+func TestNewMailer(t *testing.T) {
+    tests := []struct {
+        name    string
+        wantErr bool
+    }{
+        {"none", false},
+        {"log", false},
+        {"file", false},
+        {"carrier-pigeon", true},
+    }
+
+    t.Setenv("MAIL_DIR", t.TempDir())
+    for _, tt := range tests {
+        if _, err := NewMailer(tt.name); (err != nil) != tt.wantErr {
+            t.Errorf("NewMailer(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+        }
+    }
+}
@@ -0,0 +1,48 @@
+package email
+// Path: email/file.go
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "path/filepath"
+    "regexp"
+    "time"
+)
+
+// unsafeFileChars matches characters left out of email file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9@._-]+`)
+
+// FileMailer writes each email to a .eml file in Dir instead of sending it, for development and tests
+type FileMailer struct {
+    Dir  string
+    From string
+}
+
+// NewFileMailerFromEnv configures a FileMailer writing to MAIL_DIR, defaulting to ./mail
+func NewFileMailerFromEnv() (Mailer, error) {
+    dir := os.Getenv("MAIL_DIR")
+    if dir == "" {
+        dir = "./mail"
+    }
+    if err := os.MkdirAll(dir, 0755); err != nil {
+        return nil, err
+    }
+
+    from := os.Getenv("MAIL_FROM")
+    if from == "" {
+        from = "stock-service@localhost"
+    }
+    return &FileMailer{Dir: dir, From: from}, nil
+}
+
+// Send writes the message, headers included, to a file named after the time and first recipient
+func (m *FileMailer) Send(ctx context.Context, message Message) error {
+    recipient := "unknown"
+    if len(message.To) > 0 {
+        recipient = unsafeFileChars.ReplaceAllString(message.To[0], "_")
+    }
+
+    name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), recipient)
+    return os.WriteFile(filepath.Join(m.Dir, name), formatMessage(m.From, message), 0644)
+}
@@ -0,0 +1,106 @@
+package email
+// Path: email/mailer.go
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "os"
+    "strings"
+    "time"
+)
+
+// sendTimeout bounds sending a single notification
+const sendTimeout = 30 * time.Second
+
+// Message is an email ready to send
+type Message struct {
+    To      []string
+    Subject string
+    HTML    string // The body, an HTML document
+}
+
+// Mailer sends email
+type Mailer interface {
+    Send(ctx context.Context, message Message) error
+}
+
+// mailerFactories builds the mailers selectable with the MAILER variable
+var mailerFactories = map[string]func() (Mailer, error){
+    "smtp": NewSMTPMailerFromEnv,
+    "file": NewFileMailerFromEnv,
+    "log":  func() (Mailer, error) { return LogMailer{}, nil },
+    "none": func() (Mailer, error) { return NoopMailer{}, nil },
+}
+
+var mailer Mailer = NoopMailer{}
+
+// Setup selects the mailer named by MAILER, defaulting to sending nothing
+func Setup() {
+    name := strings.ToLower(os.Getenv("MAILER"))
+    if name == "" {
+        name = "none"
+    }
+
+    selected, err := NewMailer(name)
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    mailer = selected
+    log.Printf("Using %s mailer", name)
+}
+
+// NewMailer builds the named mailer
+func NewMailer(name string) (Mailer, error) {
+    factory, ok := mailerFactories[name]
+    if !ok {
+        return nil, fmt.Errorf("unknown mailer: %q", name)
+    }
+    return factory()
+}
+
+// GetMailer returns the mailer chosen by Setup
+func GetMailer() Mailer {
+    return mailer
+}
+
+// SendTemplate renders the named email template with data and sends it to the address
+func SendTemplate(ctx context.Context, to string, name string, data interface{}) error {
+    message, err := Render(name, data)
+    if err != nil {
+        return err
+    }
+    message.To = []string{to}
+    return mailer.Send(ctx, message)
+}
+
+// Notify sends the named email template in the background, logging any failure.
+// Notifications never hold up or fail the request that caused them.
+func Notify(to string, name string, data interface{}) {
+    go func() {
+        ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+        defer cancel()
+        if err := SendTemplate(ctx, to, name, data); err != nil {
+            log.Printf("Failed to send %s email to %s: %v", name, to, err)
+        }
+    }()
+}
+
+// LogMailer logs that emails would have been sent instead of sending them, for development.
+// Only the recipients and subject are logged, since bodies can hold secrets such as password reset links.
+type LogMailer struct{}
+
+// Send logs the message's recipients and subject
+func (LogMailer) Send(ctx context.Context, message Message) error {
+    log.Printf("Email to %s: %s (body not logged)", strings.Join(message.To, ", "), message.Subject)
+    return nil
+}
+
+// NoopMailer drops every email, for deployments that don't send any
+type NoopMailer struct{}
+
+// Send does nothing
+func (NoopMailer) Send(ctx context.Context, message Message) error {
+    return nil
+}
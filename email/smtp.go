@@ -0,0 +1,77 @@
+package email
+// Path: email/smtp.go
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "mime"
+    "net"
+    "net/smtp"
+    "os"
+    "strings"
+    "time"
+)
+
+// SMTPMailer sends email through an SMTP server, using STARTTLS when the server offers it
+type SMTPMailer struct {
+    Host     string
+    Port     string
+    Username string // Leave empty for servers that don't need authentication
+    Password string
+    From     string
+}
+
+// NewSMTPMailerFromEnv configures an SMTPMailer from SMTP_HOST, SMTP_PORT (default 587), SMTP_USERNAME,
+// SMTP_PASSWORD and MAIL_FROM
+func NewSMTPMailerFromEnv() (Mailer, error) {
+    mailer := &SMTPMailer{
+        Host:     os.Getenv("SMTP_HOST"),
+        Port:     os.Getenv("SMTP_PORT"),
+        Username: os.Getenv("SMTP_USERNAME"),
+        Password: os.Getenv("SMTP_PASSWORD"),
+        From:     os.Getenv("MAIL_FROM"),
+    }
+    if mailer.Port == "" {
+        mailer.Port = "587"
+    }
+    if mailer.Host == "" || mailer.From == "" {
+        return nil, fmt.Errorf("SMTP_HOST and MAIL_FROM must be set for the smtp mailer")
+    }
+    return mailer, nil
+}
+
+// Send delivers the message to the SMTP server
+func (m *SMTPMailer) Send(ctx context.Context, message Message) error {
+    var auth smtp.Auth
+    if m.Username != "" {
+        auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+    }
+
+    // net/smtp has no context support, so the deadline is applied by abandoning the send
+    done := make(chan error, 1)
+    go func() {
+        done <- smtp.SendMail(net.JoinHostPort(m.Host, m.Port), auth, m.From, message.To, formatMessage(m.From, message))
+    }()
+
+    select {
+    case err := <-done:
+        return err
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
+
+// formatMessage returns the message with its headers as sent over SMTP
+func formatMessage(from string, message Message) []byte {
+    var b bytes.Buffer
+    fmt.Fprintf(&b, "From: %s\r\n", from)
+    fmt.Fprintf(&b, "To: %s\r\n", strings.Join(message.To, ", "))
+    fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+    fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+    b.WriteString("MIME-Version: 1.0\r\n")
+    b.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+    b.WriteString("\r\n")
+    b.WriteString(strings.ReplaceAll(message.HTML, "\n", "\r\n"))
+    return b.Bytes()
+}
@@ -0,0 +1,36 @@
+package email
+// Path: email/templates.go
+
+import (
+    "bytes"
+    "fmt"
+    "html"
+    "html/template"
+    "path/filepath"
+    "strings"
+)
+
+// templateDir holds one template per email, each defining a "subject" and a "body"
+var templateDir = "./templates/email"
+
+// Render executes the email template templates/email/<name>.tmpl with data
+func Render(name string, data interface{}) (Message, error) {
+    tmpl, err := template.ParseFiles(filepath.Join(templateDir, name+".tmpl"))
+    if err != nil {
+        return Message{}, fmt.Errorf("email template %s: %w", name, err)
+    }
+
+    var subject, body bytes.Buffer
+    if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+        return Message{}, fmt.Errorf("email template %s: %w", name, err)
+    }
+    if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+        return Message{}, fmt.Errorf("email template %s: %w", name, err)
+    }
+
+    // The subject is a header rather than HTML, so it is unescaped and kept to one line
+    return Message{
+        Subject: strings.Join(strings.Fields(html.UnescapeString(subject.String())), " "),
+        HTML:    body.String(),
+    }, nil
+}
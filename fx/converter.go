@@ -0,0 +1,55 @@
+package fx
+// Path: fx/converter.go
+
+import (
+    "context"
+    "errors"
+    "log"
+    "strings"
+    "time"
+)
+
+// Converter converts amounts into a base currency with rates fetched up front.
+// The zero Converter leaves every amount as it is.
+type Converter struct {
+    Base  string
+    Rates map[string]float64 // Units of Base per unit of each currency that has a rate
+}
+
+// NewConverter fetches the rate of each currency into base on date. Currencies without a rate are left out.
+func NewConverter(ctx context.Context, provider RateProvider, base string, currencies []string, date time.Time) Converter {
+    converter := Converter{Base: strings.ToUpper(base), Rates: map[string]float64{}}
+    for _, currency := range currencies {
+        currency = strings.ToUpper(currency)
+        if _, done := converter.Rates[currency]; done || currency == "" {
+            continue
+        }
+
+        rate, err := ConversionRate(ctx, provider, currency, converter.Base, date)
+        if err != nil {
+            if !errors.Is(err, ErrNoRate) {
+                log.Printf("Failed to get FX rate for %s/%s: %v", currency, converter.Base, err)
+            }
+            continue
+        }
+        converter.Rates[currency] = rate
+    }
+    return converter
+}
+
+// Rate returns the units of the base currency per unit of currency, and false if it is not known.
+// Amounts with no currency are taken to be in the base currency already.
+func (c Converter) Rate(currency string) (float64, bool) {
+    currency = strings.ToUpper(currency)
+    if c.Base == "" || currency == "" || currency == c.Base {
+        return 1, true
+    }
+    rate, ok := c.Rates[currency]
+    return rate, ok
+}
+
+// Convert returns the amount in the base currency, and false if the currency has no rate
+func (c Converter) Convert(amount float64, currency string) (float64, bool) {
+    rate, ok := c.Rate(currency)
+    return amount * rate, ok
+}
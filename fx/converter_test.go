@@ -0,0 +1,47 @@
+package fx
+
+import (
+    "context"
+    "math"
+    "testing"
+    "time"
+)
+
+func TestConverter(t *testing.T) {
+    provider := writeRates(t, "2024-01-02,EUR,USD,1.10\n2024-01-02,CAD,USD,0.75\n")
+    converter := NewConverter(context.Background(), provider, "usd", []string{"EUR", "cad", "EUR", "", "CHF"}, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC))
+
+    tests := []struct {
+        amount   float64
+        currency string
+        want     float64
+        wantOK   bool
+    }{
+        {100, "USD", 100, true},
+        {100, "", 100, true},
+        {100, "eur", 110, true},
+        {100, "CAD", 75, true},
+        {100, "CHF", 0, false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.currency, func(t *testing.T) {
+            got, ok := converter.Convert(tt.amount, tt.currency)
+            if ok != tt.wantOK {
+                t.Fatalf("Convert(%v, %q) ok = %v, want %v", tt.amount, tt.currency, ok, tt.wantOK)
+            }
+            if ok && math.Abs(got-tt.want) > 1e-9 {
+                t.Errorf("Convert(%v, %q) = %v, want %v", tt.amount, tt.currency, got, tt.want)
+            }
+        })
+    }
+
+    if len(converter.Rates) != 2 {
+        t.Errorf("converter fetched %d rates, want one per distinct currency with a rate", len(converter.Rates))
+    }
+
+    // The zero converter leaves every amount as it is
+    if got, ok := (Converter{}).Convert(42, "EUR"); !ok || got != 42 {
+        t.Errorf("zero Converter.Convert() = %v, %v, want 42, true", got, ok)
+    }
+}
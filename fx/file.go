@@ -0,0 +1,111 @@
+package fx
+// Path: fx/file.go
+
+import (
+    "context"
+    "encoding/csv"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/jalong4/stock-service-go/models"
+)
+
+// FileProvider serves FX rates from a CSV file for offline use and fixtures.
+// Each row is "date,base,quote,rate", giving the value of one unit of base in quote,
+// with dates formatted as YYYY-MM-DD. A header row is optional.
+type FileProvider struct {
+    Path string
+}
+
+// NewFileProviderFromEnv returns a FileProvider reading FX_DATA_PATH, defaulting to ./data/fx.csv
+func NewFileProviderFromEnv() (RateProvider, error) {
+    path := os.Getenv("FX_DATA_PATH")
+    if path == "" {
+        path = "./data/fx.csv"
+    }
+    return &FileProvider{Path: path}, nil
+}
+
+// RateOn returns the pair's rate on the latest day in the file on or before date
+func (p *FileProvider) RateOn(ctx context.Context, base string, quote string, date time.Time) (float64, error) {
+    file, err := os.Open(p.Path)
+    if errors.Is(err, os.ErrNotExist) {
+        return 0, ErrNoRate
+    }
+    if err != nil {
+        return 0, err
+    }
+    defer file.Close()
+
+    rates, err := ParseRatesCSV(file)
+    if err != nil {
+        return 0, fmt.Errorf("%s: %w", p.Path, err)
+    }
+
+    // Rates are oldest first, so the last match is the latest
+    found := false
+    rate := 0.0
+    for _, fxRate := range rates {
+        if fxRate.Date.After(date) {
+            break
+        }
+        if fxRate.Base == strings.ToUpper(base) && fxRate.Quote == strings.ToUpper(quote) {
+            rate = fxRate.Rate
+            found = true
+        }
+    }
+    if !found {
+        return 0, ErrNoRate
+    }
+    return rate, nil
+}
+
+// ParseRatesCSV reads "date,base,quote,rate" rows, skipping a header row if present, and returns them oldest first
+func ParseRatesCSV(r io.Reader) ([]models.FxRate, error) {
+    reader := csv.NewReader(r)
+    reader.FieldsPerRecord = -1
+    reader.TrimLeadingSpace = true
+
+    var rates []models.FxRate
+    for line := 1; ; line++ {
+        record, err := reader.Read()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return nil, err
+        }
+        if len(record) < 4 {
+            return nil, fmt.Errorf("line %d: expected date,base,quote,rate", line)
+        }
+
+        date, err := time.Parse("2006-01-02", strings.TrimSpace(record[0]))
+        if err != nil {
+            if line == 1 {
+                continue // Header row
+            }
+            return nil, fmt.Errorf("line %d: invalid date %q", line, record[0])
+        }
+
+        base := strings.ToUpper(strings.TrimSpace(record[1]))
+        quote := strings.ToUpper(strings.TrimSpace(record[2]))
+        if len(base) != 3 || len(quote) != 3 {
+            return nil, fmt.Errorf("line %d: currencies must be three letter codes", line)
+        }
+
+        rate, err := strconv.ParseFloat(strings.TrimSpace(record[3]), 64)
+        if err != nil || rate <= 0 {
+            return nil, fmt.Errorf("line %d: invalid rate %q", line, record[3])
+        }
+        rates = append(rates, models.FxRate{Base: base, Quote: quote, Date: date, Rate: rate})
+    }
+
+    sort.SliceStable(rates, func(i, j int) bool { return rates[i].Date.Before(rates[j].Date) })
+    return rates, nil
+}
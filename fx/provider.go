@@ -0,0 +1,112 @@
+package fx
+// Path: fx/provider.go
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "strings"
+    "time"
+)
+
+// ErrNoRate is returned when a provider has no rate for a currency pair
+var ErrNoRate = errors.New("no FX rate available")
+
+// pivotCurrency is used to cross currencies that have no rate between them
+const pivotCurrency = "USD"
+
+// RateProvider is a source of FX rates
+type RateProvider interface {
+    // RateOn returns the value of one unit of base in quote on the latest day on or before date
+    RateOn(ctx context.Context, base string, quote string, date time.Time) (float64, error)
+}
+
+// providerFactories builds the providers selectable with the FX_PROVIDER variable
+var providerFactories = map[string]func() (RateProvider, error){
+    "file":  NewFileProviderFromEnv,
+    "mongo": NewMongoProvider,
+    "none":  func() (RateProvider, error) { return NoopProvider{}, nil },
+}
+
+var provider RateProvider = NoopProvider{}
+
+// Setup selects the FX rate provider named by FX_PROVIDER, defaulting to the file provider
+func Setup() {
+    name := strings.ToLower(os.Getenv("FX_PROVIDER"))
+    if name == "" {
+        name = "file"
+    }
+
+    selected, err := NewProvider(name)
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    provider = selected
+    log.Printf("Using %s FX rate provider", name)
+}
+
+// NewProvider builds the named FX rate provider
+func NewProvider(name string) (RateProvider, error) {
+    factory, ok := providerFactories[name]
+    if !ok {
+        return nil, fmt.Errorf("unknown FX provider: %q", name)
+    }
+    return factory()
+}
+
+// GetProvider returns the FX rate provider chosen by Setup
+func GetProvider() RateProvider {
+    return provider
+}
+
+// ConversionRate returns the value of one unit of from in to on date. Pairs the provider has no rate for
+// are worked out from the inverse rate, or crossed through USD.
+func ConversionRate(ctx context.Context, provider RateProvider, from string, to string, date time.Time) (float64, error) {
+    from, to = strings.ToUpper(from), strings.ToUpper(to)
+    if from == to {
+        return 1, nil
+    }
+
+    rate, err := directOrInverseRate(ctx, provider, from, to, date)
+    if !errors.Is(err, ErrNoRate) || from == pivotCurrency || to == pivotCurrency {
+        return rate, err
+    }
+
+    toPivot, err := directOrInverseRate(ctx, provider, from, pivotCurrency, date)
+    if err != nil {
+        return 0, err
+    }
+    fromPivot, err := directOrInverseRate(ctx, provider, pivotCurrency, to, date)
+    if err != nil {
+        return 0, err
+    }
+    return toPivot * fromPivot, nil
+}
+
+// directOrInverseRate returns the rate of the pair, or the inverse of the rate of the opposite pair
+func directOrInverseRate(ctx context.Context, provider RateProvider, from string, to string, date time.Time) (float64, error) {
+    rate, err := provider.RateOn(ctx, from, to, date)
+    if !errors.Is(err, ErrNoRate) {
+        return rate, err
+    }
+
+    inverse, err := provider.RateOn(ctx, to, from, date)
+    if err != nil {
+        return 0, err
+    }
+    if inverse == 0 {
+        return 0, ErrNoRate
+    }
+    return 1 / inverse, nil
+}
+
+// NoopProvider has no rates, so only amounts already in the base currency can be reported
+type NoopProvider struct{}
+
+// RateOn always returns ErrNoRate
+func (NoopProvider) RateOn(ctx context.Context, base string, quote string, date time.Time) (float64, error) {
+    return 0, ErrNoRate
+}
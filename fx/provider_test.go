@@ -0,0 +1,100 @@
+package fx
+
+import (
+    "context"
+    "errors"
+    "math"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+// writeRates writes a rates file for a FileProvider and returns the provider
+func writeRates(t *testing.T, rows string) *FileProvider {
+    t.Helper()
+    path := filepath.Join(t.TempDir(), "fx.csv")
+    if err := os.WriteFile(path, []byte(rows), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    return &FileProvider{Path: path}
+}
+
+func TestConversionRate(t *testing.T) {
+    provider := writeRates(t, `date,base,quote,rate
+2024-01-02,EUR,USD,1.10
+2024-01-02,USD,JPY,140
+2024-01-02,GBP,USD,1.25
+2024-02-01,EUR,USD,1.08
+`)
+    january, february := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC)
+
+    tests := []struct {
+        name    string
+        from    string
+        to      string
+        date    time.Time
+        want    float64
+        wantErr error
+    }{
+        {"same currency", "usd", "USD", january, 1, nil},
+        {"direct", "EUR", "USD", january, 1.10, nil},
+        {"latest on or before the date", "EUR", "USD", february, 1.08, nil},
+        {"inverse", "USD", "EUR", january, 1 / 1.10, nil},
+        {"crossed through USD", "EUR", "JPY", january, 1.10 * 140, nil},
+        {"crossed through USD both ways", "GBP", "EUR", january, 1.25 / 1.10, nil},
+        {"before the first rate", "EUR", "USD", time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), 0, ErrNoRate},
+        {"unknown currency", "CHF", "USD", january, 0, ErrNoRate},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := ConversionRate(context.Background(), provider, tt.from, tt.to, tt.date)
+            if !errors.Is(err, tt.wantErr) {
+                t.Fatalf("ConversionRate() error = %v, want %v", err, tt.wantErr)
+            }
+            if math.Abs(got-tt.want) > 1e-9 {
+                t.Errorf("ConversionRate() = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestParseRatesCSV(t *testing.T) {
+    tests := []struct {
+        name    string
+        rows    string
+        want    int
+        wantErr bool
+    }{
+        {"header and rows", "date,base,quote,rate\n2024-01-02,eur,usd,1.1\n2024-01-01,GBP,USD,1.25\n", 2, false},
+        {"no header", "2024-01-02,EUR,USD,1.1\n", 1, false},
+        {"missing rate", "2024-01-02,EUR,USD\n", 0, true},
+        {"bad currency", "2024-01-02,EURO,USD,1.1\n", 0, true},
+        {"zero rate", "2024-01-02,EUR,USD,0\n", 0, true},
+        {"bad date after the header", "date,base,quote,rate\nyesterday,EUR,USD,1.1\n", 0, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            f, err := os.Open(writeRates(t, tt.rows).Path)
+            if err != nil {
+                t.Fatal(err)
+            }
+            defer f.Close()
+
+            rates, err := ParseRatesCSV(f)
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("ParseRatesCSV() error = %v, wantErr %v", err, tt.wantErr)
+            }
+            if len(rates) != tt.want {
+                t.Fatalf("ParseRatesCSV() returned %d rates, want %d", len(rates), tt.want)
+            }
+            for i := 1; i < len(rates); i++ {
+                if rates[i].Date.Before(rates[i-1].Date) {
+                    t.Errorf("rates are not oldest first: %v", rates)
+                }
+            }
+        })
+    }
+}
@@ -0,0 +1,53 @@
+package fx
+// Path: fx/store.go
+
+import (
+    "context"
+    "errors"
+    "io"
+    "os"
+    "time"
+
+    "github.com/jalong4/stock-service-go/config"
+    "go.mongodb.org/mongo-driver/mongo"
+)
+
+// MongoProvider serves FX rates stored in the fxRates collection
+type MongoProvider struct{}
+
+// NewMongoProvider returns a provider backed by the fxRates collection
+func NewMongoProvider() (RateProvider, error) {
+    return MongoProvider{}, nil
+}
+
+// RateOn returns the pair's rate on the latest stored day on or before date
+func (MongoProvider) RateOn(ctx context.Context, base string, quote string, date time.Time) (float64, error) {
+    rate, err := config.GetFxRateOn(base, quote, date)
+    if errors.Is(err, mongo.ErrNoDocuments) {
+        return 0, ErrNoRate
+    }
+    if err != nil {
+        return 0, err
+    }
+    return rate.Rate, nil
+}
+
+// ImportCSV stores the "date,base,quote,rate" rows read from r in the fxRates collection
+func ImportCSV(r io.Reader) (int64, error) {
+    rates, err := ParseRatesCSV(r)
+    if err != nil {
+        return 0, err
+    }
+    return config.UpsertFxRates(rates)
+}
+
+// ImportCSVFile stores the rates in the file at path, the same layout the file provider reads
+func ImportCSVFile(path string) (int64, error) {
+    file, err := os.Open(path)
+    if err != nil {
+        return 0, err
+    }
+    defer file.Close()
+
+    return ImportCSV(file)
+}
@@ -0,0 +1,247 @@
+package jobs
+// Path: jobs/alerts.go
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "os"
+    "time"
+
+    "github.com/jalong4/stock-service-go/config"
+    "github.com/jalong4/stock-service-go/email"
+    "github.com/jalong4/stock-service-go/fx"
+    "github.com/jalong4/stock-service-go/models"
+    "github.com/jalong4/stock-service-go/portfolio"
+    "github.com/jalong4/stock-service-go/prices"
+    "github.com/jalong4/stock-service-go/webhooks"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// defaultAlertInterval is how often alerts are evaluated when ALERT_INTERVAL is not set
+const defaultAlertInterval = 5 * time.Minute
+
+// StartAlerts evaluates every active alert each ALERT_INTERVAL (a duration such as "1m").
+// Setting ALERT_INTERVAL to "off" disables the job.
+func StartAlerts() {
+    setting := os.Getenv("ALERT_INTERVAL")
+    if setting == "off" {
+        log.Println("Alert evaluation disabled")
+        return
+    }
+
+    interval := defaultAlertInterval
+    if setting != "" {
+        parsed, err := time.ParseDuration(setting)
+        if err != nil || parsed <= 0 {
+            log.Printf("Invalid ALERT_INTERVAL %q, using %s", setting, defaultAlertInterval)
+        } else {
+            interval = parsed
+        }
+    }
+
+    go func() {
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        for range ticker.C {
+            ctx, cancel := context.WithTimeout(context.Background(), interval)
+            count, err := EvaluateAlerts(ctx)
+            cancel()
+            if err != nil {
+                log.Printf("Failed to evaluate alerts: %v", err)
+                continue
+            }
+            if count > 0 {
+                log.Printf("%d alerts triggered", count)
+            }
+        }
+    }()
+
+    log.Printf("Alerts evaluated every %s", interval)
+}
+
+// EvaluateAlerts checks every active alert against the latest quotes and returns how many fired.
+// An alert fires only when its condition becomes true, and each firing is recorded in the trigger history.
+func EvaluateAlerts(ctx context.Context) (int, error) {
+    alerts, err := config.GetActiveAlerts()
+    if err != nil {
+        return 0, err
+    }
+
+    byUser := map[primitive.ObjectID][]models.Alert{}
+    for _, alert := range alerts {
+        byUser[alert.UserID] = append(byUser[alert.UserID], alert)
+    }
+
+    fired := 0
+    for userID, userAlerts := range byUser {
+        count, err := evaluateUserAlerts(ctx, userID, userAlerts)
+        fired += count
+        if err != nil {
+            log.Printf("Failed to evaluate alerts of user %s: %v", userID.Hex(), err)
+        }
+    }
+    return fired, nil
+}
+
+// portfolioState is the valuation of a user's holdings in their base currency
+type portfolioState struct {
+    valuation portfolio.Valuation
+    byTicker  map[string]float64 // Market value of each ticker in the base currency
+}
+
+// evaluateUserAlerts evaluates one user's alerts, valuing their portfolio only if an alert needs it.
+// An alert that fails to save is logged and skipped so the user's other alerts are still evaluated.
+func evaluateUserAlerts(ctx context.Context, userID primitive.ObjectID, alerts []models.Alert) (int, error) {
+    var tickers []string
+    needsPortfolio := false
+    for _, alert := range alerts {
+        if alert.Type == models.AlertPrice {
+            tickers = append(tickers, alert.Ticker)
+        } else {
+            needsPortfolio = true
+        }
+    }
+
+    user, err := config.GetUserByID(userID.Hex())
+    if err != nil {
+        return 0, err
+    }
+
+    quotes := portfolio.FetchQuotes(ctx, prices.GetProvider(), tickers)
+
+    var state *portfolioState
+    if needsPortfolio {
+        state, err = valuePortfolio(ctx, user)
+        if err != nil {
+            return 0, err
+        }
+    }
+
+    fired := 0
+    now := time.Now()
+    for _, alert := range alerts {
+        value, ok := alertValue(alert, quotes, state)
+        if !ok {
+            continue // Nothing to compare until the price or portfolio is known
+        }
+
+        met := thresholdMet(alert.Operator, alert.Threshold, value)
+        if !met || alert.Triggered {
+            if err := config.SetAlertState(alert.ID, met, value, now, nil); err != nil {
+                log.Printf("Failed to save the state of alert %s: %v", alert.ID.Hex(), err)
+            }
+            continue
+        }
+
+        // Only the evaluation that moves the alert from armed to triggered reports the crossing
+        claimed, err := config.ClaimAlertTrigger(alert.ID, value, now)
+        if err != nil {
+            log.Printf("Failed to claim the trigger of alert %s: %v", alert.ID.Hex(), err)
+            continue
+        }
+        if !claimed {
+            continue
+        }
+
+        trigger := models.AlertTrigger{
+            AlertID:     alert.ID,
+            UserID:      alert.UserID,
+            Type:        alert.Type,
+            Ticker:      alert.Ticker,
+            Operator:    alert.Operator,
+            Threshold:   alert.Threshold,
+            Value:       value,
+            Message:     fmt.Sprintf("%s (now %v)", alert.Describe(), value),
+            TriggeredAt: now,
+        }
+        if err := config.AddAlertTrigger(trigger); err != nil {
+            // Re-arm the alert so the next evaluation reports the crossing instead of losing it
+            log.Printf("Failed to record the trigger of alert %s: %v", alert.ID.Hex(), err)
+            if err := config.SetAlertState(alert.ID, false, value, now, nil); err != nil {
+                log.Printf("Failed to re-arm alert %s: %v", alert.ID.Hex(), err)
+            }
+            continue
+        }
+        webhooks.Publish(alert.UserID, models.EventAlertTriggered, trigger)
+        if alert.Email {
+            email.Notify(user.Email, "alert", map[string]interface{}{"FirstName": user.FirstName, "Alert": alert, "Trigger": trigger})
+        }
+        fired++
+    }
+
+    return fired, nil
+}
+
+// thresholdMet reports whether value is strictly past the threshold in the operator's direction
+func thresholdMet(operator string, threshold float64, value float64) bool {
+    if operator == models.AlertBelow {
+        return value < threshold
+    }
+    return value > threshold
+}
+
+// alertValue returns the current value the alert compares to its threshold, and false if it is not known
+func alertValue(alert models.Alert, quotes map[string]*prices.Quote, state *portfolioState) (float64, bool) {
+    switch alert.Type {
+    case models.AlertPrice:
+        quote := quotes[alert.Ticker]
+        if quote == nil {
+            return 0, false
+        }
+        return quote.Price, true
+
+    case models.AlertPortfolioDayChange:
+        previous := state.valuation.TotalMarketValue - state.valuation.DayChange
+        if previous <= 0 {
+            return 0, false
+        }
+        return portfolio.Round2(state.valuation.DayChange / previous * 100), true
+
+    case models.AlertPositionWeight:
+        if state.valuation.TotalMarketValue <= 0 {
+            return 0, false
+        }
+        return portfolio.Round2(state.byTicker[alert.Ticker] / state.valuation.TotalMarketValue * 100), true
+    }
+    return 0, false
+}
+
+// valuePortfolio values the user's holdings in their base currency with the latest quotes
+func valuePortfolio(ctx context.Context, user *models.User) (*portfolioState, error) {
+    accounts, err := config.GetAccounts(user.ID)
+    if err != nil {
+        return nil, err
+    }
+
+    holdings, err := config.GetAllHoldings(user.ID)
+    if err != nil {
+        return nil, err
+    }
+
+    accountCurrencies := map[primitive.ObjectID]string{}
+    var currencies []string
+    for _, account := range accounts {
+        accountCurrencies[account.ID] = account.Currency
+        currencies = append(currencies, account.Currency)
+    }
+    for i := range holdings {
+        if holdings[i].Currency == "" {
+            holdings[i].Currency = accountCurrencies[holdings[i].AccountID]
+        }
+        if holdings[i].Currency == "" {
+            holdings[i].Currency = models.DefaultCurrency
+        }
+    }
+
+    converter := fx.NewConverter(ctx, fx.GetProvider(), user.GetBaseCurrency(), currencies, time.Now())
+    valued, valuation := portfolio.ValueHoldings(ctx, prices.GetProvider(), holdings, converter)
+
+    state := &portfolioState{valuation: valuation, byTicker: map[string]float64{}}
+    for _, item := range valued {
+        if item.BaseMarketValue != nil {
+            state.byTicker[item.Ticker] += *item.BaseMarketValue
+        }
+    }
+    return state, nil
+}
@@ -0,0 +1,69 @@
+package jobs
+
+import (
+    "testing"
+
+    "github.com/jalong4/stock-service-go/models"
+    "github.com/jalong4/stock-service-go/portfolio"
+    "github.com/jalong4/stock-service-go/prices"
+)
+
+func TestThresholdMet(t *testing.T) {
+    tests := []struct {
+        operator  string
+        threshold float64
+        value     float64
+        want      bool
+    }{
+        {models.AlertAbove, 100, 100.01, true},
+        {models.AlertAbove, 100, 100, false},
+        {models.AlertAbove, 100, 99, false},
+        {models.AlertBelow, 100, 99.99, true},
+        {models.AlertBelow, 100, 100, false},
+        {models.AlertBelow, 100, 101, false},
+        {models.AlertBelow, -2, -2.5, true},
+        {models.AlertAbove, -2, -1.5, true},
+    }
+
+    for _, tt := range tests {
+        if got := thresholdMet(tt.operator, tt.threshold, tt.value); got != tt.want {
+            t.Errorf("thresholdMet(%s, %v, %v) = %v, want %v", tt.operator, tt.threshold, tt.value, got, tt.want)
+        }
+    }
+}
+
+func TestAlertValue(t *testing.T) {
+    quotes := map[string]*prices.Quote{"ABC": {Ticker: "ABC", Price: 42.5}, "NOQ": nil}
+    state := &portfolioState{
+        valuation: portfolio.Valuation{TotalMarketValue: 1000, DayChange: 50},
+        byTicker:  map[string]float64{"ABC": 250},
+    }
+    empty := &portfolioState{byTicker: map[string]float64{}}
+
+    tests := []struct {
+        name   string
+        alert  models.Alert
+        state  *portfolioState
+        want   float64
+        wantOK bool
+    }{
+        {"price", models.Alert{Type: models.AlertPrice, Ticker: "ABC"}, nil, 42.5, true},
+        {"price without a quote", models.Alert{Type: models.AlertPrice, Ticker: "NOQ"}, nil, 0, false},
+        {"price of an unknown ticker", models.Alert{Type: models.AlertPrice, Ticker: "XYZ"}, nil, 0, false},
+        {"day change from the previous value", models.Alert{Type: models.AlertPortfolioDayChange}, state, 5.26, true},
+        {"day change of an empty portfolio", models.Alert{Type: models.AlertPortfolioDayChange}, empty, 0, false},
+        {"position weight", models.Alert{Type: models.AlertPositionWeight, Ticker: "ABC"}, state, 25, true},
+        {"weight of a ticker not held", models.Alert{Type: models.AlertPositionWeight, Ticker: "XYZ"}, state, 0, true},
+        {"weight in an empty portfolio", models.Alert{Type: models.AlertPositionWeight, Ticker: "ABC"}, empty, 0, false},
+        {"unknown type", models.Alert{Type: "volume"}, state, 0, false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, ok := alertValue(tt.alert, quotes, tt.state)
+            if ok != tt.wantOK || got != tt.want {
+                t.Errorf("alertValue() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+            }
+        })
+    }
+}
@@ -0,0 +1,141 @@
+package jobs
+// Path: jobs/snapshots.go
+
+import (
+    "context"
+    "log"
+    "os"
+    "time"
+
+    "github.com/jalong4/stock-service-go/config"
+    "github.com/jalong4/stock-service-go/fx"
+    "github.com/jalong4/stock-service-go/models"
+    "github.com/jalong4/stock-service-go/portfolio"
+    "github.com/jalong4/stock-service-go/prices"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// defaultSnapshotTime is after the US market close in UTC
+const defaultSnapshotTime = "21:30"
+
+// StartSnapshots records end of day snapshots every day at SNAPSHOT_TIME (HH:MM in UTC).
+// Setting SNAPSHOT_TIME to "off" disables the job.
+func StartSnapshots() {
+    at := os.Getenv("SNAPSHOT_TIME")
+    if at == "off" {
+        log.Println("Daily snapshots disabled")
+        return
+    }
+    if at == "" {
+        at = defaultSnapshotTime
+    }
+
+    runAt, err := time.Parse("15:04", at)
+    if err != nil {
+        log.Printf("Invalid SNAPSHOT_TIME %q, using %s", at, defaultSnapshotTime)
+        runAt, _ = time.Parse("15:04", defaultSnapshotTime)
+    }
+
+    go func() {
+        for {
+            next := nextDailyRun(time.Now().UTC(), runAt.Hour(), runAt.Minute())
+            time.Sleep(time.Until(next))
+
+            ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+            count, failed, err := RecordSnapshots(ctx, next)
+            cancel()
+            if err != nil {
+                log.Printf("Failed to record snapshots: %v", err)
+                continue
+            }
+            log.Printf("Recorded %d account snapshots for %s, %d failed", count, next.Format("2006-01-02"), failed)
+        }
+    }()
+
+    log.Printf("Daily snapshots scheduled at %s UTC", runAt.Format("15:04"))
+}
+
+// RecordSnapshots values every user's accounts with the current prices and stores them as the day's snapshot.
+// An account's value is its holdings' market value plus its cash, matching the holdings summary.
+// It returns how many snapshots were recorded and how many accounts failed, which are logged and skipped.
+func RecordSnapshots(ctx context.Context, date time.Time) (int, int, error) {
+    accounts, err := config.GetEveryAccount()
+    if err != nil {
+        return 0, 0, err
+    }
+    holdings, err := config.GetEveryHolding()
+    if err != nil {
+        return 0, 0, err
+    }
+
+    byAccount := map[primitive.ObjectID][]models.Holding{}
+    for _, holding := range holdings {
+        byAccount[holding.AccountID] = append(byAccount[holding.AccountID], holding)
+    }
+
+    day := StartOfDay(date)
+    count, failed := 0, 0
+    for _, account := range accounts {
+        cash, err := accountCash(account)
+        if err != nil {
+            log.Printf("Failed to retrieve the cash of account %s for user %s: %v", account.ID.Hex(), account.UserID.Hex(), err)
+            failed++
+            continue
+        }
+        accountHoldings := byAccount[account.ID]
+        if len(accountHoldings) == 0 && cash == 0 {
+            continue // Nothing held yet
+        }
+
+        // Snapshots are kept in the account's own currency and converted when they are reported
+        _, valuation := portfolio.ValueHoldings(ctx, prices.GetProvider(), accountHoldings, fx.Converter{})
+        snapshot := models.PortfolioSnapshot{
+            UserID:      account.UserID,
+            Account:     account.Name,
+            AccountID:   account.ID,
+            Date:        day,
+            Currency:    account.Currency,
+            MarketValue: portfolio.Round2(valuation.TotalMarketValue),
+            Cash:        cash,
+            CostBasis:   portfolio.Round2(valuation.TotalCost),
+            Unpriced:    valuation.Unpriced,
+            CreatedAt:   time.Now(),
+        }
+        if err := config.SaveSnapshot(snapshot); err != nil {
+            log.Printf("Failed to save snapshot of account %s for user %s: %v", account.ID.Hex(), account.UserID.Hex(), err)
+            failed++
+            continue
+        }
+        count++
+    }
+
+    return count, failed, nil
+}
+
+// accountCash returns the account's cash balance from its transactions and cash flows
+func accountCash(account models.Account) (float64, error) {
+    transactions, err := config.GetTransactions(account.UserID, account.ID, "")
+    if err != nil {
+        return 0, err
+    }
+    cashFlows, err := config.GetCashFlows(account.UserID, account.ID)
+    if err != nil {
+        return 0, err
+    }
+    return portfolio.CashBalances(transactions, cashFlows)[account.ID], nil
+}
+
+// StartOfDay returns midnight UTC of the day containing t
+func StartOfDay(t time.Time) time.Time {
+    t = t.UTC()
+    return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// nextDailyRun returns the next time after now at hour:minute UTC
+func nextDailyRun(now time.Time, hour int, minute int) time.Time {
+    next := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, time.UTC)
+    if !next.After(now) {
+        next = next.AddDate(0, 0, 1)
+    }
+    return next
+}
@@ -4,9 +4,16 @@ import (
     "fmt"
     "log"
     "os"
+    "strings"
 
     "github.com/jalong4/stock-service-go/config"
+    "github.com/jalong4/stock-service-go/email"
+    "github.com/jalong4/stock-service-go/fx"
+    "github.com/jalong4/stock-service-go/jobs"
+    "github.com/jalong4/stock-service-go/prices"
     "github.com/jalong4/stock-service-go/routes"
+    "github.com/jalong4/stock-service-go/securities"
+    "github.com/jalong4/stock-service-go/webhooks"
     "github.com/gin-gonic/gin"
 )
 
@@ -15,6 +22,88 @@ func main() {
     config.LoadEnv()    // Load environment variables
     config.ConnectDB()  // Establish MongoDB connection
 
+    if err := config.EnsureTokenIndexes(); err != nil {
+        log.Printf("Failed to create token indexes: %v", err)
+    }
+
+    if err := config.EnsurePriceIndexes(); err != nil {
+        log.Printf("Failed to create price indexes: %v", err)
+    }
+
+    if err := config.EnsureFxRateIndexes(); err != nil {
+        log.Printf("Failed to create FX rate indexes: %v", err)
+    }
+
+    if err := config.EnsureSecurityIndexes(); err != nil {
+        log.Printf("Failed to create security indexes: %v", err)
+    }
+
+    if err := config.EnsureAlertIndexes(); err != nil {
+        log.Printf("Failed to create alert indexes: %v", err)
+    }
+
+    if err := config.EnsureWebhookIndexes(); err != nil {
+        log.Printf("Failed to create webhook indexes: %v", err)
+    }
+
+    if err := config.EnsureCorporateActionIndexes(); err != nil {
+        log.Printf("Failed to create corporate action indexes: %v", err)
+    }
+
+    if err := config.EnsureAccountIndexes(); err != nil {
+        log.Printf("Failed to create account indexes: %v", err)
+    }
+
+    // Promote the existing users listed in ADMIN_EMAILS. Registering with a listed address does not make an admin,
+    // since addresses are not verified.
+    if adminEmails := os.Getenv("ADMIN_EMAILS"); adminEmails != "" {
+        count, err := config.PromoteAdmins(strings.Split(adminEmails, ","))
+        if err != nil {
+            log.Printf("Failed to promote admins: %v", err)
+        } else if count > 0 {
+            log.Printf("Promoted %d users listed in ADMIN_EMAILS to admin", count)
+        }
+    }
+
+    // Link records that still name their account with free text to account records
+    if err := config.MigrateAccounts(); err != nil {
+        log.Printf("Failed to migrate accounts: %v", err)
+    }
+
+    // Load daily closes from CSV files for offline use
+    if importPath := os.Getenv("PRICE_IMPORT_PATH"); importPath != "" {
+        if err := prices.ImportCSVDir(importPath); err != nil {
+            log.Printf("Failed to import prices: %v", err)
+        }
+    }
+
+    // Load FX rates from a CSV file for offline use
+    if importPath := os.Getenv("FX_IMPORT_PATH"); importPath != "" {
+        count, err := fx.ImportCSVFile(importPath)
+        if err != nil {
+            log.Printf("Failed to import FX rates: %v", err)
+        } else {
+            log.Printf("Imported %d FX rates", count)
+        }
+    }
+
+    // Load the security master from a CSV file
+    if importPath := os.Getenv("SECURITIES_IMPORT_PATH"); importPath != "" {
+        count, err := securities.ImportCSVFile(importPath)
+        if err != nil {
+            log.Printf("Failed to import securities: %v", err)
+        } else {
+            log.Printf("Imported %d securities", count)
+        }
+    }
+
+    prices.Setup() // Select the market price provider
+    fx.Setup() // Select the FX rate provider
+    email.Setup() // Select the mailer
+    webhooks.Setup() // Resume pending webhook deliveries
+    jobs.StartSnapshots() // Record daily portfolio snapshots
+    jobs.StartAlerts() // Evaluate alerts on a schedule
+
 	router := gin.Default()
 
 	// Setup routes
@@ -1,11 +1,18 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// User roles
+const (
+    RoleAdmin = "admin"
+    RoleUser  = "user"
+)
+
 // User represents a user in the database
 type User struct {
     ID              primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
@@ -14,10 +21,37 @@ type User struct {
     Email           string    `bson:"email" json:"email"`
     Password        string    `bson:"password" json:"-"` // '-' in JSON tag to prevent it from being sent to the client
     Timezone        string    `bson:"timezone" json:"timezone"`
+    BaseCurrency    string    `bson:"baseCurrency,omitempty" json:"baseCurrency,omitempty"` // Currency summaries are reported in
 	ProfileImageURL string    `json:"profileImageUrl"`
+    Role            string    `bson:"role" json:"role"`
+    Benchmark       string    `bson:"benchmark,omitempty" json:"benchmark,omitempty"` // Ticker to compare performance against
     Date            time.Time `bson:"date" json:"date"`
 }
 
+// GetRole returns the user's role, treating users created before roles existed as ordinary users
+func (u *User) GetRole() string {
+    if u.Role == "" {
+        return RoleUser
+    }
+    return u.Role
+}
+
+// DefaultCurrency is the currency of users and accounts that don't choose one
+const DefaultCurrency = "USD"
+
+// GetBaseCurrency returns the currency the user's summaries are reported in
+func (u *User) GetBaseCurrency() string {
+    if u.BaseCurrency == "" {
+        return DefaultCurrency
+    }
+    return u.BaseCurrency
+}
+
+// IsValidRole reports whether role is one of the known user roles
+func IsValidRole(role string) bool {
+    return role == RoleAdmin || role == RoleUser
+}
+
 // RegistrationRequest struct represents the registration request
 type RegistrationRequest struct {
     FirstName       string `json:"firstName"`
@@ -26,16 +60,499 @@ type RegistrationRequest struct {
     Password        string `json:"password"`
     Password2       string `json:"password2"`
     Timezone        string `json:"timezone"`
+    BaseCurrency    string `json:"baseCurrency"`
     ProfileImageURL string `json:"profileImageUrl"`
 }
 
 
+// RefreshToken records an issued refresh token so that it can only be exchanged once
+type RefreshToken struct {
+    ID        primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+    TokenID   string             `bson:"tokenId" json:"tokenId"`
+    UserID    primitive.ObjectID `bson:"userId" json:"userId"`
+    Used      bool               `bson:"used" json:"used"`
+    IssuedAt  time.Time          `bson:"issuedAt" json:"issuedAt"`
+    ExpiresAt time.Time          `bson:"expiresAt" json:"expiresAt"`
+}
+
+// PasswordReset records a password reset token sent by email. Only a hash of the token is stored.
+type PasswordReset struct {
+    ID        primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+    TokenHash string             `bson:"tokenHash" json:"-"`
+    UserID    primitive.ObjectID `bson:"userId" json:"userId"`
+    Used      bool               `bson:"used" json:"used"`
+    CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+    ExpiresAt time.Time          `bson:"expiresAt" json:"expiresAt"`
+}
+
+// RevokedToken records an access token that was invalidated before it expired
+type RevokedToken struct {
+    ID        primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+    TokenID   string             `bson:"tokenId" json:"tokenId"`
+    UserID    primitive.ObjectID `bson:"userId" json:"userId"`
+    ExpiresAt time.Time          `bson:"expiresAt" json:"expiresAt"`
+}
+
+// TokenCutoff invalidates every token issued to a user before NotBefore
+type TokenCutoff struct {
+    UserID    primitive.ObjectID `bson:"userId" json:"userId"`
+    NotBefore time.Time          `bson:"notBefore" json:"notBefore"`
+}
 
 // Holding represents the structure of a holding record in the database
 type Holding struct {
     ID         primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+    UserID     primitive.ObjectID `bson:"userId" json:"userId"`
     Ticker     string             `bson:"ticker" json:"ticker"`
     Quantity   float64            `bson:"quantity" json:"quantity"`
-    TotalCost  float64            `bson:"totalCost" json:"totalCost"`
-    Account    string             `bson:"account" json:"account"`
-}
\ No newline at end of file
+    TotalCost  float64            `bson:"totalCost" json:"totalCost"` // Sum of the lots' cost, kept for clients
+    Account    string             `bson:"account" json:"account"`     // Name of the account, kept in step with AccountID
+    AccountID  primitive.ObjectID `bson:"accountId,omitempty" json:"accountId,omitempty"`
+    Currency   string             `bson:"currency,omitempty" json:"currency,omitempty"` // The account's currency, which cost and prices are in
+    Lots       []Lot              `bson:"lots,omitempty" json:"lots,omitempty"`
+}
+
+// CostBasis returns the remaining cost basis of the holding from its open lots.
+// Holdings entered before lots were tracked fall back to TotalCost.
+func (h Holding) CostBasis() float64 {
+    if len(h.Lots) == 0 {
+        return h.TotalCost
+    }
+
+    basis := 0.0
+    for _, lot := range h.Lots {
+        basis += lot.Cost
+    }
+    return basis
+}
+
+// Lot is a quantity of shares acquired by a single buy or transfer in
+type Lot struct {
+    TransactionID primitive.ObjectID `bson:"transactionId" json:"transactionId"`
+    Date          time.Time          `bson:"date" json:"date"`
+    Quantity      float64            `bson:"quantity" json:"quantity"`
+    Cost          float64            `bson:"cost" json:"cost"` // Basis of the remaining quantity, including fees
+}
+
+// CostPerShare returns the lot's basis per share
+func (l Lot) CostPerShare() float64 {
+    if l.Quantity == 0 {
+        return 0
+    }
+    return l.Cost / l.Quantity
+}
+
+// Cost basis methods used to choose the lots consumed by a sell
+const (
+    CostBasisFIFO        = "fifo"
+    CostBasisLIFO        = "lifo"
+    CostBasisAverage     = "average"
+    CostBasisSpecificLot = "specificLot"
+)
+
+// IsValidCostBasisMethod reports whether method is one of the known cost basis methods
+func IsValidCostBasisMethod(method string) bool {
+    switch method {
+    case CostBasisFIFO, CostBasisLIFO, CostBasisAverage, CostBasisSpecificLot:
+        return true
+    }
+    return false
+}
+
+// Account types
+const (
+    AccountTypeTaxable = "taxable"
+    AccountTypeIRA     = "ira"
+    AccountTypeRothIRA = "rothIra"
+    AccountType401k    = "401k"
+    AccountTypeOther   = "other"
+)
+
+// IsValidAccountType reports whether t is one of the known account types
+func IsValidAccountType(t string) bool {
+    switch t {
+    case AccountTypeTaxable, AccountTypeIRA, AccountTypeRothIRA, AccountType401k, AccountTypeOther:
+        return true
+    }
+    return false
+}
+
+// Account is a brokerage or retirement account of a user. Names are unique per user.
+type Account struct {
+    ID              primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+    UserID          primitive.ObjectID `bson:"userId" json:"userId"`
+    Name            string             `bson:"name" json:"name"`
+    Institution     string             `bson:"institution" json:"institution"`
+    Type            string             `bson:"type" json:"type"`
+    Currency        string             `bson:"currency" json:"currency"`
+    CostBasisMethod string             `bson:"costBasisMethod,omitempty" json:"costBasisMethod,omitempty"` // Used by sells that don't choose one, defaults to fifo
+    CreatedAt       time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// IsTaxable reports whether gains in the account are taxed when realized
+func (a Account) IsTaxable() bool {
+    return a.Type == AccountTypeTaxable || a.Type == AccountTypeOther
+}
+
+// Transaction types
+const (
+    TransactionBuy         = "buy"
+    TransactionSell        = "sell"
+    TransactionTransferIn  = "transferIn"
+    TransactionTransferOut = "transferOut"
+    TransactionDividend    = "dividend"
+    TransactionInterest    = "interest"
+
+    // Recorded by corporate actions only
+    TransactionSplit          = "split"          // Multiplies the quantity of every open lot by Ratio
+    TransactionCostAdjustment = "costAdjustment" // Multiplies the cost of every open lot by Ratio
+)
+
+// Transaction represents a single entry in a user's ledger. Holdings are derived from these.
+type Transaction struct {
+    ID                primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+    UserID            primitive.ObjectID `bson:"userId" json:"userId"`
+    Account           string             `bson:"account" json:"account"`
+    AccountID         primitive.ObjectID `bson:"accountId,omitempty" json:"accountId,omitempty"`
+    Ticker            string             `bson:"ticker" json:"ticker"`
+    Type              string             `bson:"type" json:"type"`
+    Date              time.Time          `bson:"date" json:"date"`
+    Quantity          float64            `bson:"quantity" json:"quantity"`
+    Price             float64            `bson:"price" json:"price"`
+    Fees              float64            `bson:"fees" json:"fees"`
+    Notes             string             `bson:"notes,omitempty" json:"notes,omitempty"`
+    CostBasisMethod   string             `bson:"costBasisMethod,omitempty" json:"costBasisMethod,omitempty"`     // Sells and transfers out only
+    LotID             primitive.ObjectID `bson:"lotId,omitempty" json:"lotId,omitempty"`                         // Lot sold with the specificLot method
+    AcquiredDate      *time.Time         `bson:"acquiredDate,omitempty" json:"acquiredDate,omitempty"`           // Transfers in only, keeps the original holding period
+    Amount            float64            `bson:"amount,omitempty" json:"amount,omitempty"`                       // Dividends and interest only, the gross amount paid
+    WithholdingTax    float64            `bson:"withholdingTax,omitempty" json:"withholdingTax,omitempty"`       // Dividends and interest only
+    Reinvested        bool               `bson:"reinvested,omitempty" json:"reinvested,omitempty"`               // Dividends and interest only, Quantity shares were bought at Price
+    Ratio             float64            `bson:"ratio,omitempty" json:"ratio,omitempty"`                         // Splits and cost adjustments only
+    CorporateActionID primitive.ObjectID `bson:"corporateActionId,omitempty" json:"corporateActionId,omitempty"` // Set on transactions recorded by a corporate action
+    CreatedAt         time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// IsValidTransactionType reports whether t is one of the known transaction types
+func IsValidTransactionType(t string) bool {
+    switch t {
+    case TransactionBuy, TransactionSell, TransactionTransferIn, TransactionTransferOut, TransactionDividend, TransactionInterest:
+        return true
+    }
+    return false
+}
+
+// IsIncome reports whether the transaction is a dividend or interest payment
+func (t Transaction) IsIncome() bool {
+    return t.Type == TransactionDividend || t.Type == TransactionInterest
+}
+
+// NetIncome returns the amount of a dividend or interest payment after withholding tax
+func (t Transaction) NetIncome() float64 {
+    return t.Amount - t.WithholdingTax
+}
+
+// IsPurchase reports whether the transaction bought shares, by a buy or by reinvesting income
+func (t Transaction) IsPurchase() bool {
+    return t.Type == TransactionBuy || (t.IsIncome() && t.Reinvested && t.Quantity > 0)
+}
+
+// Holding period classifications of realized gains
+const (
+    ShortTerm = "shortTerm" // Held one year or less
+    LongTerm  = "longTerm"  // Held more than one year
+)
+
+// RealizedLot records the part of a lot closed by a sell, with its gain or loss
+type RealizedLot struct {
+    ID                primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+    UserID            primitive.ObjectID `bson:"userId" json:"userId"`
+    Account           string             `bson:"account" json:"account"`
+    AccountID         primitive.ObjectID `bson:"accountId,omitempty" json:"accountId,omitempty"`
+    Ticker            string             `bson:"ticker" json:"ticker"`
+    SellTransactionID primitive.ObjectID `bson:"sellTransactionId" json:"sellTransactionId"`
+    LotTransactionID  primitive.ObjectID `bson:"lotTransactionId" json:"lotTransactionId"`
+    AcquiredDate      time.Time          `bson:"acquiredDate" json:"acquiredDate"`
+    DisposedDate      time.Time          `bson:"disposedDate" json:"disposedDate"`
+    Quantity          float64            `bson:"quantity" json:"quantity"`
+    Proceeds          float64            `bson:"proceeds" json:"proceeds"`
+    Basis             float64            `bson:"basis" json:"basis"`
+    Gain              float64            `bson:"gain" json:"gain"`
+    Term              string             `bson:"term" json:"term"`
+}
+
+// DailyPrice is a ticker's stored closing price for a day
+type DailyPrice struct {
+    ID     primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+    Ticker string             `bson:"ticker" json:"ticker"`
+    Date   time.Time          `bson:"date" json:"date"`
+    Close  float64            `bson:"close" json:"close"`
+}
+
+// Security is an instrument's reference data from the security master
+type Security struct {
+    ID         primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+    Symbol     string             `bson:"symbol" json:"symbol"`
+    Name       string             `bson:"name" json:"name"`
+    Exchange   string             `bson:"exchange" json:"exchange"`
+    Type       string             `bson:"type" json:"type"`             // Such as stock, etf, fund or bond
+    Currency   string             `bson:"currency" json:"currency"`     // Currency the security trades in
+    AssetClass string             `bson:"assetClass" json:"assetClass"` // Such as equity, fixedIncome or cash
+    Sector     string             `bson:"sector" json:"sector"`
+    Country    string             `bson:"country" json:"country"`
+    CUSIP      string             `bson:"cusip,omitempty" json:"cusip,omitempty"`
+    ISIN       string             `bson:"isin,omitempty" json:"isin,omitempty"`
+    UpdatedAt  time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// Groups target allocations can be set by
+const (
+    TargetByTicker     = "ticker"
+    TargetByAssetClass = "assetClass"
+)
+
+// TargetAllocation is the mix of holdings a user wants their portfolio to have
+type TargetAllocation struct {
+    ID             primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+    UserID         primitive.ObjectID `bson:"userId" json:"userId"`
+    By             string             `bson:"by" json:"by"`
+    Targets        []TargetWeight     `bson:"targets" json:"targets"`
+    Tolerance      float64            `bson:"tolerance" json:"tolerance"`           // Percentage points a weight may drift before trading
+    MinTradeAmount float64            `bson:"minTradeAmount" json:"minTradeAmount"` // In the user's base currency
+    UpdatedAt      time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// TargetWeight is the percentage of the portfolio wanted in a ticker or asset class
+type TargetWeight struct {
+    Group  string  `bson:"group" json:"group"`
+    Weight float64 `bson:"weight" json:"weight"`
+}
+
+// Alert types
+const (
+    AlertPrice              = "price"              // The ticker's latest price
+    AlertPortfolioDayChange = "portfolioDayChange" // Percentage change of the portfolio's market value today
+    AlertPositionWeight     = "positionWeight"     // The ticker's percentage of the portfolio's market value
+)
+
+// Alert operators
+const (
+    AlertAbove = "above"
+    AlertBelow = "below"
+)
+
+// Alert watches a price or portfolio value and fires when it crosses Threshold. It fires once each time the
+// condition becomes true, and is re-armed when the condition clears.
+type Alert struct {
+    ID              primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+    UserID          primitive.ObjectID `bson:"userId" json:"userId"`
+    Type            string             `bson:"type" json:"type"`
+    Ticker          string             `bson:"ticker,omitempty" json:"ticker,omitempty"` // Price and position weight alerts only
+    Operator        string             `bson:"operator" json:"operator"`
+    Threshold       float64            `bson:"threshold" json:"threshold"`
+    Notes           string             `bson:"notes,omitempty" json:"notes,omitempty"`
+    Active          bool               `bson:"active" json:"active"`
+    Email           bool               `bson:"email" json:"email"` // Email the owner when it fires
+    Triggered       bool               `bson:"triggered" json:"triggered"` // The condition held when last evaluated
+    LastValue       *float64           `bson:"lastValue,omitempty" json:"lastValue,omitempty"`
+    LastEvaluatedAt *time.Time         `bson:"lastEvaluatedAt,omitempty" json:"lastEvaluatedAt,omitempty"`
+    LastTriggeredAt *time.Time         `bson:"lastTriggeredAt,omitempty" json:"lastTriggeredAt,omitempty"`
+    CreatedAt       time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// IsValidAlertType reports whether t is one of the known alert types
+func IsValidAlertType(t string) bool {
+    return t == AlertPrice || t == AlertPortfolioDayChange || t == AlertPositionWeight
+}
+
+// Describe returns the alert's condition in words, such as "AAPL price above 250"
+func (a Alert) Describe() string {
+    switch a.Type {
+    case AlertPrice:
+        return fmt.Sprintf("%s price %s %v", a.Ticker, a.Operator, a.Threshold)
+    case AlertPositionWeight:
+        return fmt.Sprintf("%s weight %s %v%%", a.Ticker, a.Operator, a.Threshold)
+    default:
+        return fmt.Sprintf("Portfolio day change %s %v%%", a.Operator, a.Threshold)
+    }
+}
+
+// AlertTrigger records an alert firing
+type AlertTrigger struct {
+    ID          primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+    AlertID     primitive.ObjectID `bson:"alertId" json:"alertId"`
+    UserID      primitive.ObjectID `bson:"userId" json:"userId"`
+    Type        string             `bson:"type" json:"type"`
+    Ticker      string             `bson:"ticker,omitempty" json:"ticker,omitempty"`
+    Operator    string             `bson:"operator" json:"operator"`
+    Threshold   float64            `bson:"threshold" json:"threshold"`
+    Value       float64            `bson:"value" json:"value"`
+    Message     string             `bson:"message" json:"message"`
+    TriggeredAt time.Time          `bson:"triggeredAt" json:"triggeredAt"`
+}
+
+// Webhook events
+const (
+    EventHoldingCreated = "holding.created"
+    EventHoldingUpdated = "holding.updated"
+    EventHoldingDeleted = "holding.deleted"
+    EventAlertTriggered = "alert.triggered"
+    EventPing           = "ping" // Sent on request to check a webhook, whatever its events
+)
+
+// IsValidWebhookEvent reports whether event is one webhooks can subscribe to
+func IsValidWebhookEvent(event string) bool {
+    return event == EventHoldingCreated || event == EventHoldingUpdated || event == EventHoldingDeleted || event == EventAlertTriggered
+}
+
+// Webhook subscribes a URL to a user's events. Deliveries are signed with Secret.
+type Webhook struct {
+    ID        primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+    UserID    primitive.ObjectID `bson:"userId" json:"userId"`
+    URL       string             `bson:"url" json:"url"`
+    Secret    string             `bson:"secret" json:"-"`
+    Events    []string           `bson:"events" json:"events"`
+    Active    bool               `bson:"active" json:"active"`
+    CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+    UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// Webhook delivery statuses
+const (
+    DeliveryPending   = "pending"
+    DeliverySucceeded = "succeeded"
+    DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery records sending an event to a webhook and the outcome of its latest attempt
+type WebhookDelivery struct {
+    ID             primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+    WebhookID      primitive.ObjectID `bson:"webhookId" json:"webhookId"`
+    UserID         primitive.ObjectID `bson:"userId" json:"userId"`
+    Event          string             `bson:"event" json:"event"`
+    URL            string             `bson:"url" json:"url"`
+    Payload        string             `bson:"payload" json:"payload"` // The signed JSON body
+    Status         string             `bson:"status" json:"status"`
+    Attempts       int                `bson:"attempts" json:"attempts"`
+    ResponseStatus int                `bson:"responseStatus,omitempty" json:"responseStatus,omitempty"`
+    Error          string             `bson:"error,omitempty" json:"error,omitempty"`
+    CreatedAt      time.Time          `bson:"createdAt" json:"createdAt"`
+    LastAttemptAt  *time.Time         `bson:"lastAttemptAt,omitempty" json:"lastAttemptAt,omitempty"`
+    NextAttemptAt  *time.Time         `bson:"nextAttemptAt,omitempty" json:"nextAttemptAt,omitempty"`
+    DeliveredAt    *time.Time         `bson:"deliveredAt,omitempty" json:"deliveredAt,omitempty"`
+}
+
+// FxRate is the value of one unit of the base currency in the quote currency on a day
+type FxRate struct {
+    ID    primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+    Base  string             `bson:"base" json:"base"`
+    Quote string             `bson:"quote" json:"quote"`
+    Date  time.Time          `bson:"date" json:"date"`
+    Rate  float64            `bson:"rate" json:"rate"`
+}
+
+// PortfolioSnapshot records the end of day value of one of a user's accounts
+type PortfolioSnapshot struct {
+    ID          primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+    UserID      primitive.ObjectID `bson:"userId" json:"userId"`
+    Account     string             `bson:"account" json:"account"`
+    AccountID   primitive.ObjectID `bson:"accountId,omitempty" json:"accountId,omitempty"`
+    Date        time.Time          `bson:"date" json:"date"`
+    Currency    string             `bson:"currency,omitempty" json:"currency,omitempty"` // The account's currency, which the values are in
+    MarketValue float64            `bson:"marketValue" json:"marketValue"` // The holdings' market value
+    Cash        float64            `bson:"cash" json:"cash"`               // The account's cash balance
+    CostBasis   float64            `bson:"costBasis" json:"costBasis"`
+    Unpriced    int                `bson:"unpriced" json:"unpriced"` // Holdings left out of the market value for lack of a price
+    CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// Cash flow types
+const (
+    CashFlowDeposit    = "deposit"
+    CashFlowWithdrawal = "withdrawal"
+    CashFlowFee        = "fee" // Charged by the broker, so not money the owner moved
+)
+
+// CashFlow is money moved into or out of an account other than by trades and income
+type CashFlow struct {
+    ID        primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+    UserID    primitive.ObjectID `bson:"userId" json:"userId"`
+    Account   string             `bson:"account" json:"account"`
+    AccountID primitive.ObjectID `bson:"accountId,omitempty" json:"accountId,omitempty"`
+    Type      string             `bson:"type" json:"type"`
+    Date      time.Time          `bson:"date" json:"date"`
+    Amount    float64            `bson:"amount" json:"amount"` // Always positive, the type gives the direction
+    Notes     string             `bson:"notes,omitempty" json:"notes,omitempty"`
+    CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// SignedAmount returns the amount as seen by the account, negative for withdrawals and fees
+func (f CashFlow) SignedAmount() float64 {
+    if f.Type == CashFlowWithdrawal || f.Type == CashFlowFee {
+        return -f.Amount
+    }
+    return f.Amount
+}
+
+// IsExternal reports whether the owner moved the money, as opposed to a fee that reduces the account's return
+func (f CashFlow) IsExternal() bool {
+    return f.Type != CashFlowFee
+}
+
+// IsValidCashFlowType reports whether t is one of the known cash flow types
+func IsValidCashFlowType(t string) bool {
+    return t == CashFlowDeposit || t == CashFlowWithdrawal || t == CashFlowFee
+}
+
+// Corporate action types
+const (
+    CorporateActionSplit        = "split"
+    CorporateActionReverseSplit = "reverseSplit"
+    CorporateActionRename       = "rename"
+    CorporateActionSpinOff      = "spinOff"
+    CorporateActionCashMerger   = "cashMerger"
+)
+
+// CorporateAction is an event that changes every holding of a ticker, applied to all users when recorded
+type CorporateAction struct {
+    ID             primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+    Type           string             `bson:"type" json:"type"`
+    Ticker         string             `bson:"ticker" json:"ticker"`
+    NewTicker      string             `bson:"newTicker,omitempty" json:"newTicker,omitempty"`           // Renames and spin-offs
+    Date           time.Time          `bson:"date" json:"date"`                                         // Effective date, trades from this day on are after the action
+    Ratio          float64            `bson:"ratio,omitempty" json:"ratio,omitempty"`                   // New shares per old share for splits, reverse splits and spin-offs
+    CostAllocation float64            `bson:"costAllocation,omitempty" json:"costAllocation,omitempty"` // Spin-offs only, the fraction of the cost basis moved to the new ticker
+    CashPerShare   float64            `bson:"cashPerShare,omitempty" json:"cashPerShare,omitempty"`     // Cash mergers only
+    Notes          string             `bson:"notes,omitempty" json:"notes,omitempty"`
+    AppliedAt      *time.Time         `bson:"appliedAt,omitempty" json:"appliedAt,omitempty"` // Set once every affected position has been adjusted
+    CreatedBy      primitive.ObjectID `bson:"createdBy" json:"createdBy"`
+    CreatedAt      time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// IsValidCorporateActionType reports whether t is one of the known corporate action types
+func IsValidCorporateActionType(t string) bool {
+    switch t {
+    case CorporateActionSplit, CorporateActionReverseSplit, CorporateActionRename, CorporateActionSpinOff, CorporateActionCashMerger:
+        return true
+    }
+    return false
+}
+
+// CorporateActionAdjustment is the audit record of a corporate action applied to one position
+type CorporateActionAdjustment struct {
+    ID                primitive.ObjectID   `bson:"_id,omitempty" json:"_id,omitempty"`
+    CorporateActionID primitive.ObjectID   `bson:"corporateActionId" json:"corporateActionId"`
+    UserID            primitive.ObjectID   `bson:"userId" json:"userId"`
+    Account           string               `bson:"account" json:"account"`
+    AccountID         primitive.ObjectID   `bson:"accountId,omitempty" json:"accountId,omitempty"`
+    Ticker            string               `bson:"ticker" json:"ticker"`
+    QuantityBefore    float64              `bson:"quantityBefore" json:"quantityBefore"`
+    CostBefore        float64              `bson:"costBefore" json:"costBefore"`
+    QuantityAfter     float64              `bson:"quantityAfter" json:"quantityAfter"`
+    CostAfter         float64              `bson:"costAfter" json:"costAfter"`
+    NewTicker         string               `bson:"newTicker,omitempty" json:"newTicker,omitempty"`
+    NewQuantity       float64              `bson:"newQuantity,omitempty" json:"newQuantity,omitempty"`   // Shares of the new ticker received
+    NewCost           float64              `bson:"newCost,omitempty" json:"newCost,omitempty"`           // Cost basis moved to the new ticker
+    CashProceeds      float64              `bson:"cashProceeds,omitempty" json:"cashProceeds,omitempty"`
+    TransactionIDs    []primitive.ObjectID `bson:"transactionIds" json:"transactionIds"`                 // Ledger entries recorded for the action
+    CreatedAt         time.Time            `bson:"createdAt" json:"createdAt"`
+}
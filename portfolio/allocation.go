@@ -0,0 +1,64 @@
+package portfolio
+// Path: portfolio/allocation.go
+
+import (
+    "sort"
+)
+
+// AllocationItem is an amount to allocate, a holding or an account's cash, in the base currency
+type AllocationItem struct {
+    Group       string
+    MarketValue *float64 // Nil when the holding has no price
+    Cost        float64
+}
+
+// AllocationWeight is the share of the portfolio in one group
+type AllocationWeight struct {
+    Group             string  `json:"group"`
+    MarketValue       float64 `json:"marketValue"`
+    Cost              float64 `json:"cost"`
+    MarketValueWeight float64 `json:"marketValueWeight"` // Percentage of the total market value
+    CostWeight        float64 `json:"costWeight"`        // Percentage of the total cost
+    Count             int     `json:"count"`
+}
+
+// Allocate totals the items of each group and weighs the groups by market value and by cost, largest first.
+// Items with no market value only count towards the cost weights.
+func Allocate(items []AllocationItem) (weights []AllocationWeight, totalMarketValue float64, totalCost float64) {
+    groups := map[string]*AllocationWeight{}
+    for _, item := range items {
+        weight := groups[item.Group]
+        if weight == nil {
+            weight = &AllocationWeight{Group: item.Group}
+            groups[item.Group] = weight
+        }
+        if item.MarketValue != nil {
+            weight.MarketValue += *item.MarketValue
+            totalMarketValue += *item.MarketValue
+        }
+        weight.Cost += item.Cost
+        weight.Count++
+        totalCost += item.Cost
+    }
+
+    weights = make([]AllocationWeight, 0, len(groups))
+    for _, weight := range groups {
+        if totalMarketValue != 0 {
+            weight.MarketValueWeight = Round2(weight.MarketValue / totalMarketValue * 100)
+        }
+        if totalCost != 0 {
+            weight.CostWeight = Round2(weight.Cost / totalCost * 100)
+        }
+        weight.MarketValue = Round2(weight.MarketValue)
+        weight.Cost = Round2(weight.Cost)
+        weights = append(weights, *weight)
+    }
+
+    sort.Slice(weights, func(i, j int) bool {
+        if weights[i].MarketValue != weights[j].MarketValue {
+            return weights[i].MarketValue > weights[j].MarketValue
+        }
+        return weights[i].Group < weights[j].Group
+    })
+    return weights, totalMarketValue, totalCost
+}
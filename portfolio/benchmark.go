@@ -0,0 +1,94 @@
+package portfolio
+// Path: portfolio/benchmark.go
+
+import (
+    "time"
+
+    "github.com/jalong4/stock-service-go/prices"
+)
+
+// GrowthAmount is the starting value of the growth series compared against a benchmark
+const GrowthAmount = 10000.0
+
+// GrowthPoint is the value on a day of GrowthAmount invested in the portfolio and in the benchmark
+type GrowthPoint struct {
+    Date      time.Time `json:"date"`
+    Portfolio *float64  `json:"portfolio"`
+    Benchmark *float64  `json:"benchmark"` // null until the benchmark has a close
+}
+
+// BenchmarkComparison compares the time-weighted return of a portfolio with a benchmark over a period
+type BenchmarkComparison struct {
+    Benchmark       string        `json:"benchmark"`
+    From            time.Time     `json:"from"`
+    To              time.Time     `json:"to"`
+    PortfolioReturn *float64      `json:"portfolioReturn"` // Percent
+    BenchmarkReturn *float64      `json:"benchmarkReturn"` // Percent
+    ExcessReturn    *float64      `json:"excessReturn"`    // Portfolio minus benchmark, in percentage points
+    Growth          []GrowthPoint `json:"growth"`
+}
+
+// CompareToBenchmark compares the series from the start of from through the end of to with the
+// benchmark's closes, oldest first. The benchmark is measured from its last close before the period.
+func CompareToBenchmark(series Series, ticker string, closes []prices.DailyClose, from time.Time, to time.Time) BenchmarkComparison {
+    performance := series.Performance(from, to)
+    comparison := BenchmarkComparison{
+        Benchmark:       ticker,
+        From:            performance.From,
+        To:              performance.To,
+        PortfolioReturn: performance.TWR,
+        Growth:          []GrowthPoint{},
+    }
+
+    first := series.dayIndex(performance.From)
+    last := series.dayIndex(performance.To)
+    if first > last {
+        return comparison
+    }
+
+    // Link the daily portfolio returns the same way Performance does
+    portfolioGrowth := 1.0
+    baseline, lastClose := 0.0, 0.0
+    next := 0
+    for i := first - 1; i <= last; i++ {
+        day := series.Start.AddDate(0, 0, i)
+        dayEnd := day.AddDate(0, 0, 1)
+
+        for next < len(closes) && closes[next].Date.Before(dayEnd) {
+            next++
+        }
+        if next > 0 {
+            lastClose = closes[next-1].Close
+        }
+
+        if i == first-1 {
+            baseline = lastClose
+            continue
+        }
+        if baseline <= 0 {
+            baseline = lastClose // The benchmark has no history before the period
+        }
+
+        base := series.Values[i-1] + series.Flows[i]
+        if base > 0.005 {
+            portfolioGrowth *= series.Values[i] / base
+        }
+
+        point := GrowthPoint{Date: day, Portfolio: float64Ptr(Round2(GrowthAmount * portfolioGrowth))}
+        if baseline > 0 {
+            point.Benchmark = float64Ptr(Round2(GrowthAmount * lastClose / baseline))
+        }
+        comparison.Growth = append(comparison.Growth, point)
+    }
+
+    if baseline > 0 {
+        benchmarkReturn := (lastClose/baseline - 1) * 100
+        comparison.BenchmarkReturn = float64Ptr(Round2(benchmarkReturn))
+
+        if comparison.PortfolioReturn != nil {
+            comparison.ExcessReturn = float64Ptr(Round2(*comparison.PortfolioReturn - benchmarkReturn))
+        }
+    }
+
+    return comparison
+}
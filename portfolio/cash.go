@@ -0,0 +1,40 @@
+package portfolio
+// Path: portfolio/cash.go
+
+import (
+    "github.com/jalong4/stock-service-go/models"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CashEffect returns how much a transaction changes its account's cash, negative for debits.
+// Transfers and adjustments move shares without touching cash.
+func CashEffect(t models.Transaction) float64 {
+    switch t.Type {
+    case models.TransactionBuy:
+        return -(t.Quantity*t.Price + t.Fees)
+    case models.TransactionSell:
+        return t.Quantity*t.Price - t.Fees
+    case models.TransactionDividend, models.TransactionInterest:
+        if t.Reinvested {
+            return t.NetIncome() - (t.Quantity*t.Price + t.Fees)
+        }
+        return t.NetIncome()
+    }
+    return 0
+}
+
+// CashBalances returns the cash in each account, by account ID, after the transactions and cash flows
+func CashBalances(transactions []models.Transaction, cashFlows []models.CashFlow) map[primitive.ObjectID]float64 {
+    balances := map[primitive.ObjectID]float64{}
+    for _, t := range transactions {
+        balances[t.AccountID] += CashEffect(t)
+    }
+    for _, f := range cashFlows {
+        balances[f.AccountID] += f.SignedAmount()
+    }
+
+    for account, balance := range balances {
+        balances[account] = Round2(balance)
+    }
+    return balances
+}
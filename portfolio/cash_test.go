@@ -0,0 +1,67 @@
+package portfolio
+
+import (
+    "testing"
+
+    "github.com/jalong4/stock-service-go/models"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCashBalances(t *testing.T) {
+    brokerage, ira := primitive.NewObjectID(), primitive.NewObjectID()
+
+    // The IRA's older records still carry the brokerage's name, which must not move their cash
+    transactions := []models.Transaction{
+        {AccountID: brokerage, Account: "Brokerage", Type: models.TransactionBuy, Quantity: 10, Price: 50, Fees: 1},
+        {AccountID: brokerage, Account: "Brokerage", Type: models.TransactionSell, Quantity: 5, Price: 60, Fees: 1},
+        {AccountID: ira, Account: "Brokerage", Type: models.TransactionDividend, Amount: 20, WithholdingTax: 3},
+        {AccountID: ira, Account: "IRA", Type: models.TransactionTransferIn, Quantity: 5, Price: 10},
+    }
+    cashFlows := []models.CashFlow{
+        {AccountID: brokerage, Account: "Brokerage", Type: models.CashFlowDeposit, Amount: 1000},
+        {AccountID: ira, Account: "Brokerage", Type: models.CashFlowWithdrawal, Amount: 7},
+    }
+
+    balances := CashBalances(transactions, cashFlows)
+    want := map[primitive.ObjectID]float64{brokerage: 1000 - 501 + 299, ira: 17 - 7}
+    if len(balances) != len(want) {
+        t.Fatalf("CashBalances() = %v, want %v", balances, want)
+    }
+    for accountID, balance := range want {
+        if balances[accountID] != balance {
+            t.Errorf("CashBalances()[%s] = %v, want %v", accountID.Hex(), balances[accountID], balance)
+        }
+    }
+}
+
+func TestAddYieldOnCost(t *testing.T) {
+    brokerage, ira := primitive.NewObjectID(), primitive.NewObjectID()
+    valued := []ValuedHolding{
+        {Holding: models.Holding{AccountID: brokerage, Account: "Brokerage", Ticker: "ABC", Lots: []models.Lot{{Quantity: 10, Cost: 1000}}}},
+        {Holding: models.Holding{AccountID: ira, Account: "IRA", Ticker: "ABC", Lots: []models.Lot{{Quantity: 10, Cost: 500}}}},
+    }
+    income := []models.Transaction{
+        {AccountID: brokerage, Account: "Brokerage", Ticker: "ABC", Type: models.TransactionDividend, Amount: 30, WithholdingTax: 5},
+        {AccountID: ira, Account: "Brokerage", Ticker: "ABC", Type: models.TransactionDividend, Amount: 10},
+        {AccountID: ira, Account: "IRA", Ticker: "XYZ", Type: models.TransactionDividend, Amount: 99},
+    }
+
+    AddYieldOnCost(valued, income)
+
+    tests := []struct {
+        account         string
+        wantIncome      float64
+        wantYieldOnCost float64
+    }{
+        {"Brokerage", 30, 3},
+        {"IRA", 10, 2},
+    }
+    for i, tt := range tests {
+        if got := *valued[i].TrailingIncome; got != tt.wantIncome {
+            t.Errorf("%s TrailingIncome = %v, want %v", tt.account, got, tt.wantIncome)
+        }
+        if got := *valued[i].YieldOnCost; got != tt.wantYieldOnCost {
+            t.Errorf("%s YieldOnCost = %v, want %v", tt.account, got, tt.wantYieldOnCost)
+        }
+    }
+}
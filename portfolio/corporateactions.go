@@ -0,0 +1,105 @@
+package portfolio
+// Path: portfolio/corporateactions.go
+
+import (
+    "fmt"
+    "time"
+
+    "github.com/jalong4/stock-service-go/models"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LotsAt returns the lots of a position that were open just before date
+func LotsAt(transactions []models.Transaction, date time.Time) ([]models.Lot, error) {
+    var before []models.Transaction
+    for _, t := range transactions {
+        if t.Date.Before(date) {
+            before = append(before, t)
+        }
+    }
+
+    position, err := BuildPosition(before)
+    if err != nil {
+        return nil, err
+    }
+    return position.Lots, nil
+}
+
+// CorporateActionTransactions returns the ledger entries that apply a corporate action to a position holding
+// the given lots: those for the action's ticker and those opening lots in its new ticker. The total cost basis
+// is preserved, except for cash mergers which sell the position. Lots moved to a new ticker keep their
+// acquisition dates.
+func CorporateActionTransactions(action models.CorporateAction, userID primitive.ObjectID, account string, lots []models.Lot) ([]models.Transaction, []models.Transaction) {
+    entry := func(ticker string, transactionType string) models.Transaction {
+        return models.Transaction{
+            UserID:            userID,
+            Account:           account,
+            Ticker:            ticker,
+            Type:              transactionType,
+            Date:              action.Date,
+            Notes:             describeCorporateAction(action),
+            CorporateActionID: action.ID,
+            CreatedAt:         time.Now(),
+        }
+    }
+
+    quantity := 0.0
+    for _, lot := range lots {
+        quantity += lot.Quantity
+    }
+
+    // Each lot moved to the new ticker becomes its own transfer in, so it keeps its date and cost
+    movedLots := func(quantityRatio float64, costRatio float64) []models.Transaction {
+        var moved []models.Transaction
+        for _, lot := range lots {
+            acquired := lot.Date
+            transferIn := entry(action.NewTicker, models.TransactionTransferIn)
+            transferIn.Quantity = lot.Quantity * quantityRatio
+            transferIn.Price = lot.Cost * costRatio / transferIn.Quantity
+            transferIn.AcquiredDate = &acquired
+            moved = append(moved, transferIn)
+        }
+        return moved
+    }
+
+    switch action.Type {
+    case models.CorporateActionSplit, models.CorporateActionReverseSplit:
+        split := entry(action.Ticker, models.TransactionSplit)
+        split.Ratio = action.Ratio
+        return []models.Transaction{split}, nil
+
+    case models.CorporateActionRename:
+        transferOut := entry(action.Ticker, models.TransactionTransferOut)
+        transferOut.Quantity = quantity
+        transferOut.CostBasisMethod = models.CostBasisFIFO
+        return []models.Transaction{transferOut}, movedLots(1, 1)
+
+    case models.CorporateActionSpinOff:
+        adjustment := entry(action.Ticker, models.TransactionCostAdjustment)
+        adjustment.Ratio = 1 - action.CostAllocation
+        return []models.Transaction{adjustment}, movedLots(action.Ratio, action.CostAllocation)
+
+    case models.CorporateActionCashMerger:
+        sell := entry(action.Ticker, models.TransactionSell)
+        sell.Quantity = quantity
+        sell.Price = action.CashPerShare
+        sell.CostBasisMethod = models.CostBasisFIFO
+        return []models.Transaction{sell}, nil
+    }
+    return nil, nil
+}
+
+// describeCorporateAction returns a short description of the action for the ledger entries it records
+func describeCorporateAction(action models.CorporateAction) string {
+    switch action.Type {
+    case models.CorporateActionSplit, models.CorporateActionReverseSplit:
+        return fmt.Sprintf("%s of %s, %v new shares per share", action.Type, action.Ticker, action.Ratio)
+    case models.CorporateActionRename:
+        return fmt.Sprintf("%s renamed to %s", action.Ticker, action.NewTicker)
+    case models.CorporateActionSpinOff:
+        return fmt.Sprintf("%s spun off %s, %v shares per share with %v%% of the cost basis", action.Ticker, action.NewTicker, action.Ratio, action.CostAllocation*100)
+    case models.CorporateActionCashMerger:
+        return fmt.Sprintf("%s acquired for %v cash per share", action.Ticker, action.CashPerShare)
+    }
+    return action.Type
+}
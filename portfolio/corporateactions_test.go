@@ -0,0 +1,126 @@
+package portfolio
+
+import (
+    "testing"
+
+    "github.com/jalong4/stock-service-go/models"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCorporateActionTransactions(t *testing.T) {
+    actionID, userID := primitive.NewObjectID(), primitive.NewObjectID()
+    effective := day(2024, 6, 10)
+
+    // 10 shares at 100 and 30 shares at 50, 2500 of cost in all
+    ledger := []models.Transaction{
+        {ID: primitive.NewObjectID(), Ticker: "OLD", Type: models.TransactionBuy, Date: day(2023, 1, 5), Quantity: 10, Price: 100},
+        {ID: primitive.NewObjectID(), Ticker: "OLD", Type: models.TransactionBuy, Date: day(2024, 3, 1), Quantity: 30, Price: 50},
+    }
+    // Bought on the effective date, so already at the adjusted quantity and price
+    onEffectiveDate := models.Transaction{ID: primitive.NewObjectID(), Ticker: "OLD", Type: models.TransactionBuy, Date: effective, Quantity: 4, Price: 25}
+
+    tests := []struct {
+        name            string
+        action          models.CorporateAction
+        wantQuantity    float64
+        wantCost        float64
+        wantNewQuantity float64
+        wantNewCost     float64
+    }{
+        {
+            "split",
+            models.CorporateAction{Type: models.CorporateActionSplit, Ratio: 2},
+            80, 2500, 0, 0,
+        },
+        {
+            "reverse split",
+            models.CorporateAction{Type: models.CorporateActionReverseSplit, Ratio: 0.1},
+            4, 2500, 0, 0,
+        },
+        {
+            "rename",
+            models.CorporateAction{Type: models.CorporateActionRename, NewTicker: "NEW"},
+            0, 0, 40, 2500,
+        },
+        {
+            "spin-off",
+            models.CorporateAction{Type: models.CorporateActionSpinOff, NewTicker: "NEW", Ratio: 0.5, CostAllocation: 0.2},
+            40, 2000, 20, 500,
+        },
+        {
+            "cash merger",
+            models.CorporateAction{Type: models.CorporateActionCashMerger, CashPerShare: 120},
+            0, 0, 0, 0,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            action := tt.action
+            action.ID, action.Ticker, action.Date = actionID, "OLD", effective
+
+            lots, err := LotsAt(append(ledger, onEffectiveDate), effective)
+            if err != nil {
+                t.Fatalf("LotsAt() error = %v", err)
+            }
+            if len(lots) != 2 {
+                t.Fatalf("LotsAt() returned %d lots, want the 2 opened before the effective date", len(lots))
+            }
+
+            entries, newEntries := CorporateActionTransactions(action, userID, "Brokerage", lots)
+            for _, entry := range append(append([]models.Transaction{}, entries...), newEntries...) {
+                if entry.CorporateActionID != actionID || !entry.Date.Equal(effective) || entry.UserID != userID {
+                    t.Errorf("entry %+v is not stamped with the action, its date and the user", entry)
+                }
+            }
+
+            position, err := BuildPosition(append(append([]models.Transaction{}, ledger...), entries...))
+            if err != nil {
+                t.Fatalf("BuildPosition() error = %v", err)
+            }
+            if !closeTo(position.Quantity, tt.wantQuantity) || !closeTo(position.TotalCost, tt.wantCost) {
+                t.Errorf("old ticker = %v shares costing %v, want %v costing %v",
+                    position.Quantity, position.TotalCost, tt.wantQuantity, tt.wantCost)
+            }
+
+            newPosition, err := BuildPosition(newEntries)
+            if err != nil {
+                t.Fatalf("BuildPosition() of the new ticker error = %v", err)
+            }
+            if !closeTo(newPosition.Quantity, tt.wantNewQuantity) || !closeTo(newPosition.TotalCost, tt.wantNewCost) {
+                t.Errorf("new ticker = %v shares costing %v, want %v costing %v",
+                    newPosition.Quantity, newPosition.TotalCost, tt.wantNewQuantity, tt.wantNewCost)
+            }
+            for i, lot := range newPosition.Lots {
+                if !lot.Date.Equal(lots[i].Date) {
+                    t.Errorf("new lot %d acquired %v, want the original %v", i, lot.Date, lots[i].Date)
+                }
+            }
+        })
+    }
+}
+
+func TestCorporateActionBeforeSameDayTrades(t *testing.T) {
+    effective := day(2024, 6, 10)
+    action := models.CorporateAction{ID: primitive.NewObjectID(), Type: models.CorporateActionSplit, Ticker: "OLD", Date: effective, Ratio: 3}
+
+    ledger := []models.Transaction{
+        {ID: primitive.NewObjectID(), Ticker: "OLD", Type: models.TransactionBuy, Date: day(2024, 1, 2), Quantity: 10, Price: 30},
+        // Sells 25 of the 30 post-split shares on the effective date, which only replays if the split comes first
+        {ID: primitive.NewObjectID(), Ticker: "OLD", Type: models.TransactionSell, Date: effective, Quantity: 25, Price: 12},
+    }
+
+    lots, err := LotsAt(ledger, effective)
+    if err != nil {
+        t.Fatalf("LotsAt() error = %v", err)
+    }
+    entries, _ := CorporateActionTransactions(action, primitive.NewObjectID(), "Brokerage", lots)
+
+    position, err := BuildPosition(append(ledger, entries...))
+    if err != nil {
+        t.Fatalf("BuildPosition() error = %v", err)
+    }
+    if !closeTo(position.Quantity, 5) || !closeTo(position.TotalCost, 50) {
+        t.Errorf("position = %v shares costing %v, want 5 costing 50", position.Quantity, position.TotalCost)
+    }
+}
@@ -0,0 +1,127 @@
+package portfolio
+// Path: portfolio/costbasis.go
+
+import (
+    "fmt"
+
+    "github.com/jalong4/stock-service-go/models"
+)
+
+// consumeLots removes the transaction's quantity from the open lots using its cost basis method.
+// It returns the lots left open and the consumed part of each lot touched.
+func consumeLots(lots []models.Lot, t models.Transaction) ([]models.Lot, []models.Lot, error) {
+    held := 0.0
+    for _, lot := range lots {
+        held += lot.Quantity
+    }
+    if t.Quantity > held+quantityEpsilon {
+        return nil, nil, fmt.Errorf("%s of %v %s on %s exceeds the %v shares held",
+            t.Type, t.Quantity, t.Ticker, t.Date.Format("2006-01-02"), held)
+    }
+
+    method := t.CostBasisMethod
+    if method == "" {
+        method = models.CostBasisFIFO
+    }
+
+    switch method {
+    case models.CostBasisFIFO:
+        return consumeInOrder(lots, t.Quantity, false)
+    case models.CostBasisLIFO:
+        return consumeInOrder(lots, t.Quantity, true)
+    case models.CostBasisAverage:
+        return consumeAverage(lots, t.Quantity, held)
+    case models.CostBasisSpecificLot:
+        return consumeSpecificLot(lots, t)
+    }
+    return nil, nil, fmt.Errorf("unknown cost basis method: %s", method)
+}
+
+// consumeInOrder takes shares from the oldest lots first, or the newest first when newestFirst is set
+func consumeInOrder(lots []models.Lot, quantity float64, newestFirst bool) ([]models.Lot, []models.Lot, error) {
+    remaining := make([]models.Lot, len(lots))
+    copy(remaining, lots)
+
+    var consumed []models.Lot
+    for n := 0; n < len(remaining) && quantity > quantityEpsilon; n++ {
+        i := n
+        if newestFirst {
+            i = len(remaining) - 1 - n
+        }
+
+        taken := takeFromLot(&remaining[i], quantity)
+        consumed = append(consumed, taken)
+        quantity -= taken.Quantity
+    }
+
+    return openLots(remaining), consumed, nil
+}
+
+// consumeAverage takes the same fraction of every lot, so each share sold carries the average cost
+func consumeAverage(lots []models.Lot, quantity float64, held float64) ([]models.Lot, []models.Lot, error) {
+    remaining := make([]models.Lot, len(lots))
+    copy(remaining, lots)
+
+    fraction := 1.0
+    if quantity < held {
+        fraction = quantity / held
+    }
+
+    var consumed []models.Lot
+    for i := range remaining {
+        consumed = append(consumed, takeFromLot(&remaining[i], remaining[i].Quantity*fraction))
+    }
+
+    return openLots(remaining), consumed, nil
+}
+
+// consumeSpecificLot takes shares from the lot opened by the transaction's LotID
+func consumeSpecificLot(lots []models.Lot, t models.Transaction) ([]models.Lot, []models.Lot, error) {
+    remaining := make([]models.Lot, len(lots))
+    copy(remaining, lots)
+
+    for i := range remaining {
+        if remaining[i].TransactionID != t.LotID {
+            continue
+        }
+        if t.Quantity > remaining[i].Quantity+quantityEpsilon {
+            return nil, nil, fmt.Errorf("%s of %v %s exceeds the %v shares left in lot %s",
+                t.Type, t.Quantity, t.Ticker, remaining[i].Quantity, t.LotID.Hex())
+        }
+
+        taken := takeFromLot(&remaining[i], t.Quantity)
+        return openLots(remaining), []models.Lot{taken}, nil
+    }
+
+    return nil, nil, fmt.Errorf("lot %s is not an open lot of %s", t.LotID.Hex(), t.Ticker)
+}
+
+// takeFromLot removes up to quantity shares from the lot, moving their share of the cost with them
+func takeFromLot(lot *models.Lot, quantity float64) models.Lot {
+    if quantity > lot.Quantity {
+        quantity = lot.Quantity
+    }
+
+    taken := *lot
+    taken.Quantity = quantity
+    taken.Cost = lot.CostPerShare() * quantity
+
+    lot.Quantity -= quantity
+    lot.Cost -= taken.Cost
+    if lot.Quantity < quantityEpsilon {
+        lot.Quantity = 0
+        lot.Cost = 0
+    }
+    return taken
+}
+
+// openLots drops the lots that were fully consumed
+func openLots(lots []models.Lot) []models.Lot {
+    var open []models.Lot
+    for _, lot := range lots {
+        if lot.Quantity > 0 {
+            open = append(open, lot)
+        }
+    }
+    return open
+}
@@ -0,0 +1,49 @@
+package portfolio
+// Path: portfolio/gains.go
+
+import (
+    "time"
+
+    "github.com/jalong4/stock-service-go/models"
+)
+
+// HoldingTerm classifies a holding period as long term when the shares were held for more than one year
+func HoldingTerm(acquired time.Time, disposed time.Time) string {
+    if disposed.After(acquired.AddDate(1, 0, 0)) {
+        return models.LongTerm
+    }
+    return models.ShortTerm
+}
+
+// RealizedLots converts the position's sells into realized lots. The proceeds of each sell,
+// net of fees, are split across the consumed lots by quantity. Transfers out are not sales.
+func RealizedLots(position Position) []models.RealizedLot {
+    var realized []models.RealizedLot
+    for _, disposal := range position.Disposals {
+        sell := disposal.Transaction
+        if sell.Type != models.TransactionSell || sell.Quantity == 0 {
+            continue
+        }
+
+        netProceeds := sell.Quantity*sell.Price - sell.Fees
+        for _, lot := range disposal.Lots {
+            proceeds := netProceeds * lot.Quantity / sell.Quantity
+            realized = append(realized, models.RealizedLot{
+                UserID:            sell.UserID,
+                Account:           sell.Account,
+                AccountID:         sell.AccountID,
+                Ticker:            sell.Ticker,
+                SellTransactionID: sell.ID,
+                LotTransactionID:  lot.TransactionID,
+                AcquiredDate:      lot.Date,
+                DisposedDate:      sell.Date,
+                Quantity:          lot.Quantity,
+                Proceeds:          proceeds,
+                Basis:             lot.Cost,
+                Gain:              proceeds - lot.Cost,
+                Term:              HoldingTerm(lot.Date, sell.Date),
+            })
+        }
+    }
+    return realized
+}
@@ -0,0 +1,113 @@
+package portfolio
+// Path: portfolio/harvest.go
+
+import (
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/jalong4/stock-service-go/models"
+    "github.com/jalong4/stock-service-go/prices"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WashSaleWindowDays is how many days before or after a sale at a loss a purchase of the same security
+// makes it a wash sale
+const WashSaleWindowDays = 30
+
+// HarvestCriteria select the lots worth selling for their loss
+type HarvestCriteria struct {
+    MinLoss    float64   // Smallest unrealized loss, as a positive amount
+    MinLossPct float64   // Smallest unrealized loss as a percentage of the lot's basis
+    SaleDate   time.Time // When the lots would be sold
+}
+
+// HarvestCandidate is an open lot with an unrealized loss. Amounts are in the holding's currency.
+type HarvestCandidate struct {
+    Account           string             `json:"account"`
+    Ticker            string             `json:"ticker"`
+    Currency          string             `json:"currency,omitempty"`
+    LotID             primitive.ObjectID `json:"lotId"` // Sell it with the specificLot cost basis method
+    AcquiredDate      time.Time          `json:"acquiredDate"`
+    Quantity          float64            `json:"quantity"`
+    Basis             float64            `json:"basis"`
+    Price             float64            `json:"price"`
+    MarketValue       float64            `json:"marketValue"`
+    UnrealizedLoss    float64            `json:"unrealizedLoss"`
+    UnrealizedLossPct float64            `json:"unrealizedLossPct"`
+    Term              string             `json:"term"`
+    WashSale          bool               `json:"washSale"`          // Selling now would be a wash sale
+    WashSalePurchases []WashSalePurchase `json:"washSalePurchases"` // The purchases that cause it
+}
+
+// WashSalePurchase is a purchase of the same security within the wash sale window
+type WashSalePurchase struct {
+    TransactionID primitive.ObjectID `json:"transactionId"`
+    Account       string             `json:"account"`
+    Date          time.Time          `json:"date"`
+    Quantity      float64            `json:"quantity"`
+}
+
+// HarvestCandidates lists the lots of the holdings whose unrealized loss meets the criteria, largest loss first.
+// Each is checked for purchases of the same ticker in any account within the wash sale window around the sale
+// date. A lot's own purchase doesn't count against it. Holdings without a quote or without lots are skipped.
+func HarvestCandidates(holdings []models.Holding, quotes map[string]*prices.Quote, transactions []models.Transaction, criteria HarvestCriteria) []HarvestCandidate {
+    windowStart := criteria.SaleDate.AddDate(0, 0, -WashSaleWindowDays)
+    windowEnd := criteria.SaleDate.AddDate(0, 0, WashSaleWindowDays)
+
+    purchases := map[string][]models.Transaction{}
+    for _, t := range transactions {
+        if t.IsPurchase() && !t.Date.Before(windowStart) && !t.Date.After(windowEnd) {
+            ticker := strings.ToUpper(t.Ticker)
+            purchases[ticker] = append(purchases[ticker], t)
+        }
+    }
+
+    candidates := []HarvestCandidate{}
+    for _, holding := range holdings {
+        quote := quotes[holding.Ticker]
+        if quote == nil {
+            continue
+        }
+
+        for _, lot := range holding.Lots {
+            marketValue := lot.Quantity * quote.Price
+            loss := lot.Cost - marketValue
+            if loss <= 0 || loss < criteria.MinLoss || lot.Cost == 0 || loss/lot.Cost*100 < criteria.MinLossPct {
+                continue
+            }
+
+            candidate := HarvestCandidate{
+                Account:           holding.Account,
+                Ticker:            holding.Ticker,
+                Currency:          holding.Currency,
+                LotID:             lot.TransactionID,
+                AcquiredDate:      lot.Date,
+                Quantity:          lot.Quantity,
+                Basis:             Round2(lot.Cost),
+                Price:             quote.Price,
+                MarketValue:       Round2(marketValue),
+                UnrealizedLoss:    Round2(loss),
+                UnrealizedLossPct: Round2(loss / lot.Cost * 100),
+                Term:              HoldingTerm(lot.Date, criteria.SaleDate),
+                WashSalePurchases: []WashSalePurchase{},
+            }
+            for _, purchase := range purchases[strings.ToUpper(holding.Ticker)] {
+                if purchase.ID == lot.TransactionID {
+                    continue
+                }
+                candidate.WashSale = true
+                candidate.WashSalePurchases = append(candidate.WashSalePurchases, WashSalePurchase{
+                    TransactionID: purchase.ID,
+                    Account:       purchase.Account,
+                    Date:          purchase.Date,
+                    Quantity:      purchase.Quantity,
+                })
+            }
+            candidates = append(candidates, candidate)
+        }
+    }
+
+    sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].UnrealizedLoss > candidates[j].UnrealizedLoss })
+    return candidates
+}
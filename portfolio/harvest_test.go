@@ -0,0 +1,132 @@
+package portfolio
+
+import (
+    "testing"
+    "time"
+
+    "github.com/jalong4/stock-service-go/models"
+    "github.com/jalong4/stock-service-go/prices"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestHarvestCandidatesWashSales(t *testing.T) {
+    saleDate := day(2024, 6, 15)
+    lotID := primitive.NewObjectID()
+
+    // One lot of 10 shares costing 1000, now worth 600
+    holding := models.Holding{
+        Account:  "Brokerage",
+        Ticker:   "ABC",
+        Quantity: 10,
+        Lots:     []models.Lot{{TransactionID: lotID, Date: day(2023, 1, 10), Quantity: 10, Cost: 1000}},
+    }
+    quotes := map[string]*prices.Quote{"ABC": {Price: 60}}
+
+    purchase := func(ticker string, date time.Time, mutate func(*models.Transaction)) models.Transaction {
+        t := models.Transaction{ID: primitive.NewObjectID(), Account: "IRA", Ticker: ticker, Type: models.TransactionBuy, Date: date, Quantity: 1, Price: 60}
+        if mutate != nil {
+            mutate(&t)
+        }
+        return t
+    }
+
+    tests := []struct {
+        name         string
+        transactions []models.Transaction
+        wantWashSale bool
+        wantCount    int
+    }{
+        {"no other purchases", nil, false, 0},
+        {"the lot's own purchase", []models.Transaction{purchase("ABC", day(2023, 1, 10), func(t *models.Transaction) { t.ID = lotID })}, false, 0},
+        {"bought 30 days before", []models.Transaction{purchase("ABC", saleDate.AddDate(0, 0, -30), nil)}, true, 1},
+        {"bought 31 days before", []models.Transaction{purchase("ABC", saleDate.AddDate(0, 0, -31), nil)}, false, 0},
+        {"bought 30 days after", []models.Transaction{purchase("ABC", saleDate.AddDate(0, 0, 30), nil)}, true, 1},
+        {"bought 31 days after", []models.Transaction{purchase("ABC", saleDate.AddDate(0, 0, 31), nil)}, false, 0},
+        {"ticker in another case", []models.Transaction{purchase("abc", saleDate.AddDate(0, 0, -5), nil)}, true, 1},
+        {"another ticker", []models.Transaction{purchase("XYZ", saleDate.AddDate(0, 0, -5), nil)}, false, 0},
+        {
+            "reinvested dividend",
+            []models.Transaction{purchase("ABC", saleDate.AddDate(0, 0, -5), func(t *models.Transaction) {
+                t.Type, t.Reinvested, t.Amount = models.TransactionDividend, true, 60
+            })},
+            true, 1,
+        },
+        {
+            "cash dividend",
+            []models.Transaction{purchase("ABC", saleDate.AddDate(0, 0, -5), func(t *models.Transaction) {
+                t.Type, t.Quantity, t.Amount = models.TransactionDividend, 0, 60
+            })},
+            false, 0,
+        },
+        {
+            "sale and transfer in are not purchases",
+            []models.Transaction{
+                purchase("ABC", saleDate.AddDate(0, 0, -5), func(t *models.Transaction) { t.Type = models.TransactionSell }),
+                purchase("ABC", saleDate.AddDate(0, 0, -4), func(t *models.Transaction) { t.Type = models.TransactionTransferIn }),
+            },
+            false, 0,
+        },
+        {
+            "several purchases",
+            []models.Transaction{purchase("ABC", saleDate.AddDate(0, 0, -20), nil), purchase("ABC", saleDate.AddDate(0, 0, 10), nil)},
+            true, 2,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            candidates := HarvestCandidates([]models.Holding{holding}, quotes, tt.transactions, HarvestCriteria{SaleDate: saleDate})
+            if len(candidates) != 1 {
+                t.Fatalf("HarvestCandidates() returned %d candidates, want 1", len(candidates))
+            }
+
+            candidate := candidates[0]
+            if candidate.WashSale != tt.wantWashSale || len(candidate.WashSalePurchases) != tt.wantCount {
+                t.Errorf("WashSale = %v with %d purchases, want %v with %d",
+                    candidate.WashSale, len(candidate.WashSalePurchases), tt.wantWashSale, tt.wantCount)
+            }
+            if candidate.UnrealizedLoss != 400 || candidate.Term != models.LongTerm {
+                t.Errorf("candidate = %v loss, %s, want 400, %s", candidate.UnrealizedLoss, candidate.Term, models.LongTerm)
+            }
+        })
+    }
+}
+
+func TestHarvestCandidatesCriteria(t *testing.T) {
+    saleDate := day(2024, 6, 15)
+    holdings := []models.Holding{
+        {Ticker: "BIG", Lots: []models.Lot{{TransactionID: primitive.NewObjectID(), Date: day(2024, 1, 2), Quantity: 10, Cost: 1000}}}, // 500 loss, 50%
+        {Ticker: "SMALL", Lots: []models.Lot{{TransactionID: primitive.NewObjectID(), Date: day(2024, 1, 2), Quantity: 10, Cost: 1000}}}, // 50 loss, 5%
+        {Ticker: "GAIN", Lots: []models.Lot{{TransactionID: primitive.NewObjectID(), Date: day(2024, 1, 2), Quantity: 10, Cost: 1000}}},
+        {Ticker: "NOQUOTE", Lots: []models.Lot{{TransactionID: primitive.NewObjectID(), Date: day(2024, 1, 2), Quantity: 10, Cost: 1000}}},
+    }
+    quotes := map[string]*prices.Quote{"BIG": {Price: 50}, "SMALL": {Price: 95}, "GAIN": {Price: 120}}
+
+    tests := []struct {
+        name     string
+        criteria HarvestCriteria
+        want     []string
+    }{
+        {"every loss, largest first", HarvestCriteria{SaleDate: saleDate}, []string{"BIG", "SMALL"}},
+        {"minimum loss", HarvestCriteria{SaleDate: saleDate, MinLoss: 100}, []string{"BIG"}},
+        {"minimum loss percentage", HarvestCriteria{SaleDate: saleDate, MinLossPct: 10}, []string{"BIG"}},
+        {"nothing large enough", HarvestCriteria{SaleDate: saleDate, MinLoss: 1000}, []string{}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            candidates := HarvestCandidates(holdings, quotes, nil, tt.criteria)
+            if len(candidates) != len(tt.want) {
+                t.Fatalf("HarvestCandidates() returned %d candidates, want %v", len(candidates), tt.want)
+            }
+            for i, candidate := range candidates {
+                if candidate.Ticker != tt.want[i] {
+                    t.Errorf("candidate %d = %s, want %s", i, candidate.Ticker, tt.want[i])
+                }
+                if candidate.Term != models.ShortTerm {
+                    t.Errorf("candidate %s term = %s, want %s", candidate.Ticker, candidate.Term, models.ShortTerm)
+                }
+            }
+        })
+    }
+}
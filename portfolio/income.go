@@ -0,0 +1,31 @@
+package portfolio
+// Path: portfolio/income.go
+
+import (
+    "github.com/jalong4/stock-service-go/models"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AddYieldOnCost sets each holding's income over the trailing twelve months, gross of withholding tax,
+// and that income as a percentage of the holding's cost. income should only cover the trailing twelve months.
+func AddYieldOnCost(valued []ValuedHolding, income []models.Transaction) {
+    type position struct {
+        accountID primitive.ObjectID
+        ticker    string
+    }
+    totals := map[position]float64{}
+    for _, t := range income {
+        if t.IsIncome() {
+            totals[position{t.AccountID, t.Ticker}] += t.Amount
+        }
+    }
+
+    for i := range valued {
+        total := totals[position{valued[i].AccountID, valued[i].Ticker}]
+        valued[i].TrailingIncome = float64Ptr(Round2(total))
+
+        if cost := valued[i].CostBasis(); cost > 0 {
+            valued[i].YieldOnCost = float64Ptr(Round2(total / cost * 100))
+        }
+    }
+}
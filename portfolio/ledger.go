@@ -0,0 +1,126 @@
+package portfolio
+// Path: portfolio/ledger.go
+
+import (
+    "fmt"
+    "sort"
+
+    "github.com/jalong4/stock-service-go/models"
+)
+
+// quantityEpsilon absorbs floating point noise when a position is fully closed
+const quantityEpsilon = 1e-9
+
+// Position is the result of replaying the ledger for one ticker in one account
+type Position struct {
+    Quantity  float64
+    TotalCost float64
+    Lots      []models.Lot // Open lots, oldest first
+    Disposals []Disposal   // Lots consumed by each sell or transfer out, in ledger order
+}
+
+// Disposal records which lots a sell or transfer out consumed
+type Disposal struct {
+    Transaction models.Transaction
+    Lots        []models.Lot // The consumed part of each lot
+}
+
+// Basis returns the total basis of the consumed lots
+func (d Disposal) Basis() float64 {
+    basis := 0.0
+    for _, lot := range d.Lots {
+        basis += lot.Cost
+    }
+    return basis
+}
+
+// SortTransactions orders transactions by date, then by creation time so same-day entries replay in the order recorded.
+// Corporate actions take effect before any other transaction at the same time, since trades on the effective date
+// are already at the adjusted quantities and prices.
+func SortTransactions(transactions []models.Transaction) {
+    sort.SliceStable(transactions, func(i, j int) bool {
+        if !transactions[i].Date.Equal(transactions[j].Date) {
+            return transactions[i].Date.Before(transactions[j].Date)
+        }
+        iAction, jAction := !transactions[i].CorporateActionID.IsZero(), !transactions[j].CorporateActionID.IsZero()
+        if iAction != jAction {
+            return iAction
+        }
+        return transactions[i].CreatedAt.Before(transactions[j].CreatedAt)
+    })
+}
+
+// BuildPosition replays the transactions of a single position in date order.
+// Buys, transfers in and reinvested income open lots; sells and transfers out consume lots
+// using the cost basis method recorded on the transaction, defaulting to FIFO.
+func BuildPosition(transactions []models.Transaction) (Position, error) {
+    sorted := make([]models.Transaction, len(transactions))
+    copy(sorted, transactions)
+    SortTransactions(sorted)
+
+    var position Position
+    for _, t := range sorted {
+        switch t.Type {
+        case models.TransactionBuy, models.TransactionTransferIn:
+            acquired := t.Date
+            if t.AcquiredDate != nil {
+                acquired = *t.AcquiredDate
+            }
+            position.Lots = insertLot(position.Lots, models.Lot{
+                TransactionID: t.ID,
+                Date:          acquired,
+                Quantity:      t.Quantity,
+                Cost:          t.Quantity*t.Price + t.Fees,
+            })
+
+        case models.TransactionSell, models.TransactionTransferOut:
+            remaining, consumed, err := consumeLots(position.Lots, t)
+            if err != nil {
+                return Position{}, err
+            }
+            position.Lots = remaining
+            position.Disposals = append(position.Disposals, Disposal{Transaction: t, Lots: consumed})
+
+        case models.TransactionDividend, models.TransactionInterest:
+            // Reinvested income buys new shares, income paid in cash leaves the position unchanged
+            if t.Reinvested {
+                position.Lots = insertLot(position.Lots, models.Lot{
+                    TransactionID: t.ID,
+                    Date:          t.Date,
+                    Quantity:      t.Quantity,
+                    Cost:          t.Quantity*t.Price + t.Fees,
+                })
+            }
+
+        case models.TransactionSplit:
+            for i := range position.Lots {
+                position.Lots[i].Quantity *= t.Ratio
+            }
+
+        case models.TransactionCostAdjustment:
+            for i := range position.Lots {
+                position.Lots[i].Cost *= t.Ratio
+            }
+
+        default:
+            return Position{}, fmt.Errorf("unknown transaction type: %s", t.Type)
+        }
+    }
+
+    for _, lot := range position.Lots {
+        position.Quantity += lot.Quantity
+        position.TotalCost += lot.Cost
+    }
+
+    return position, nil
+}
+
+// insertLot adds the lot to lots kept in acquisition date order, after any lots acquired the same day, so a
+// transfer in of shares acquired earlier is consumed in its place by FIFO and LIFO
+func insertLot(lots []models.Lot, lot models.Lot) []models.Lot {
+    i := sort.Search(len(lots), func(i int) bool { return lots[i].Date.After(lot.Date) })
+    lots = append(lots, models.Lot{})
+    copy(lots[i+1:], lots[i:])
+    lots[i] = lot
+    return lots
+}
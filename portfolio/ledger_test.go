@@ -0,0 +1,196 @@
+package portfolio
+
+import (
+    "math"
+    "testing"
+    "time"
+
+    "github.com/jalong4/stock-service-go/models"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func day(year int, month time.Month, d int) time.Time {
+    return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func closeTo(got, want float64) bool {
+    return math.Abs(got-want) < 1e-6
+}
+
+func TestBuildPosition(t *testing.T) {
+    first, second, third := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+    // 10 shares at 10, 10 at 20 and 10 at 30, with a 1.00 fee on the first lot
+    buys := []models.Transaction{
+        {ID: first, Type: models.TransactionBuy, Date: day(2023, 1, 3), Quantity: 10, Price: 10, Fees: 1},
+        {ID: second, Type: models.TransactionBuy, Date: day(2023, 2, 1), Quantity: 10, Price: 20},
+        {ID: third, Type: models.TransactionBuy, Date: day(2023, 3, 1), Quantity: 10, Price: 30},
+    }
+    sell := func(quantity float64, method string, lot primitive.ObjectID) models.Transaction {
+        return models.Transaction{Type: models.TransactionSell, Date: day(2023, 4, 3), Quantity: quantity, Price: 40, CostBasisMethod: method, LotID: lot}
+    }
+
+    tests := []struct {
+        name         string
+        sell         models.Transaction
+        wantQuantity float64
+        wantCost     float64
+        wantBasis    float64 // Basis of the shares sold
+        wantLots     int
+        wantErr      bool
+    }{
+        {"fifo by default", sell(15, "", primitive.NilObjectID), 15, 400, 201, 2, false},
+        {"fifo", sell(15, models.CostBasisFIFO, primitive.NilObjectID), 15, 400, 201, 2, false},
+        {"lifo", sell(15, models.CostBasisLIFO, primitive.NilObjectID), 15, 201, 400, 2, false},
+        {"average", sell(15, models.CostBasisAverage, primitive.NilObjectID), 15, 300.5, 300.5, 3, false},
+        {"specific lot", sell(4, models.CostBasisSpecificLot, second), 26, 521, 80, 3, false},
+        {"specific lot closed", sell(10, models.CostBasisSpecificLot, first), 20, 500, 101, 2, false},
+        {"whole position", sell(30, models.CostBasisFIFO, primitive.NilObjectID), 0, 0, 601, 0, false},
+        {"more than held", sell(31, models.CostBasisFIFO, primitive.NilObjectID), 0, 0, 0, 0, true},
+        {"more than the lot", sell(11, models.CostBasisSpecificLot, third), 0, 0, 0, 0, true},
+        {"unknown lot", sell(1, models.CostBasisSpecificLot, primitive.NewObjectID()), 0, 0, 0, 0, true},
+        {"unknown method", sell(1, "hifo", primitive.NilObjectID), 0, 0, 0, 0, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            transactions := append(append([]models.Transaction{}, buys...), tt.sell)
+            position, err := BuildPosition(transactions)
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("BuildPosition() error = %v, wantErr %v", err, tt.wantErr)
+            }
+            if err != nil {
+                return
+            }
+
+            if !closeTo(position.Quantity, tt.wantQuantity) {
+                t.Errorf("Quantity = %v, want %v", position.Quantity, tt.wantQuantity)
+            }
+            if !closeTo(position.TotalCost, tt.wantCost) {
+                t.Errorf("TotalCost = %v, want %v", position.TotalCost, tt.wantCost)
+            }
+            if len(position.Lots) != tt.wantLots {
+                t.Errorf("open lots = %d, want %d", len(position.Lots), tt.wantLots)
+            }
+            if len(position.Disposals) != 1 {
+                t.Fatalf("disposals = %d, want 1", len(position.Disposals))
+            }
+            if basis := position.Disposals[0].Basis(); !closeTo(basis, tt.wantBasis) {
+                t.Errorf("disposal basis = %v, want %v", basis, tt.wantBasis)
+            }
+        })
+    }
+}
+
+func TestBuildPositionReplaysInDateOrder(t *testing.T) {
+    acquired := day(2020, 6, 1)
+
+    tests := []struct {
+        name         string
+        transactions []models.Transaction
+        wantQuantity float64
+        wantCost     float64
+        wantFirstLot time.Time
+    }{
+        {
+            "sell recorded before an earlier buy",
+            []models.Transaction{
+                {Type: models.TransactionSell, Date: day(2023, 3, 1), Quantity: 5, Price: 50},
+                {Type: models.TransactionBuy, Date: day(2023, 1, 1), Quantity: 10, Price: 10},
+            },
+            5, 50, day(2023, 1, 1),
+        },
+        {
+            "transfer in keeps the acquired date",
+            []models.Transaction{
+                {Type: models.TransactionTransferIn, Date: day(2023, 1, 1), Quantity: 10, Price: 10, AcquiredDate: &acquired},
+            },
+            10, 100, acquired,
+        },
+        {
+            "reinvested dividend opens a lot, cash dividend does not",
+            []models.Transaction{
+                {Type: models.TransactionBuy, Date: day(2023, 1, 1), Quantity: 10, Price: 10},
+                {Type: models.TransactionDividend, Date: day(2023, 2, 1), Amount: 5},
+                {Type: models.TransactionDividend, Date: day(2023, 3, 1), Amount: 5, Reinvested: true, Quantity: 0.5, Price: 10},
+            },
+            10.5, 105, day(2023, 1, 1),
+        },
+        {
+            "transfer out consumes lots",
+            []models.Transaction{
+                {Type: models.TransactionBuy, Date: day(2023, 1, 1), Quantity: 10, Price: 10},
+                {Type: models.TransactionTransferOut, Date: day(2023, 2, 1), Quantity: 10, Price: 10},
+            },
+            0, 0, time.Time{},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            position, err := BuildPosition(tt.transactions)
+            if err != nil {
+                t.Fatalf("BuildPosition() error = %v", err)
+            }
+            if !closeTo(position.Quantity, tt.wantQuantity) || !closeTo(position.TotalCost, tt.wantCost) {
+                t.Errorf("position = %v shares costing %v, want %v costing %v",
+                    position.Quantity, position.TotalCost, tt.wantQuantity, tt.wantCost)
+            }
+            if len(position.Lots) > 0 && !position.Lots[0].Date.Equal(tt.wantFirstLot) {
+                t.Errorf("first lot date = %v, want %v", position.Lots[0].Date, tt.wantFirstLot)
+            }
+        })
+    }
+}
+
+func TestBuildPositionConsumesLotsByAcquiredDate(t *testing.T) {
+    acquired, sameDay := day(2020, 6, 1), day(2023, 3, 1)
+
+    // Bought 10 at 10 in March and 10 at 20 in May. Between them 10 shares costing 5 were transferred in,
+    // acquired in 2020 or on the day of the March buy.
+    ledger := func(transferAcquired *time.Time, sell models.Transaction) []models.Transaction {
+        return []models.Transaction{
+            {Type: models.TransactionBuy, Date: day(2023, 3, 1), Quantity: 10, Price: 10},
+            {Type: models.TransactionTransferIn, Date: day(2023, 4, 1), Quantity: 10, Price: 5, AcquiredDate: transferAcquired},
+            {Type: models.TransactionBuy, Date: day(2023, 5, 1), Quantity: 10, Price: 20},
+            sell,
+        }
+    }
+    sell := func(method string) models.Transaction {
+        return models.Transaction{Type: models.TransactionSell, Date: day(2023, 6, 1), Quantity: 15, Price: 30, CostBasisMethod: method}
+    }
+
+    tests := []struct {
+        name          string
+        transactions  []models.Transaction
+        wantBasis     float64
+        wantFirstSold time.Time
+        wantLotDates  []time.Time // Open lots after the sell
+    }{
+        {"fifo sells the older transferred shares first", ledger(&acquired, sell(models.CostBasisFIFO)), 100, acquired, []time.Time{day(2023, 3, 1), day(2023, 5, 1)}},
+        {"lifo sells them last", ledger(&acquired, sell(models.CostBasisLIFO)), 250, day(2023, 5, 1), []time.Time{acquired, day(2023, 3, 1)}},
+        {"same day lots in recording order", ledger(&sameDay, sell(models.CostBasisFIFO)), 125, sameDay, []time.Time{sameDay, day(2023, 5, 1)}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            position, err := BuildPosition(tt.transactions)
+            if err != nil {
+                t.Fatalf("BuildPosition() error = %v", err)
+            }
+            disposal := position.Disposals[0]
+            if !closeTo(disposal.Basis(), tt.wantBasis) || !disposal.Lots[0].Date.Equal(tt.wantFirstSold) {
+                t.Errorf("sold basis %v starting with the lot of %v, want %v starting with %v",
+                    disposal.Basis(), disposal.Lots[0].Date, tt.wantBasis, tt.wantFirstSold)
+            }
+            if len(position.Lots) != len(tt.wantLotDates) {
+                t.Fatalf("open lots = %d, want %d", len(position.Lots), len(tt.wantLotDates))
+            }
+            for i, lot := range position.Lots {
+                if !lot.Date.Equal(tt.wantLotDates[i]) {
+                    t.Errorf("open lot %d date = %v, want %v", i, lot.Date, tt.wantLotDates[i])
+                }
+            }
+        })
+    }
+}
@@ -0,0 +1,296 @@
+package portfolio
+// Path: portfolio/performance.go
+
+import (
+    "fmt"
+    "math"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/jalong4/stock-service-go/models"
+    "github.com/jalong4/stock-service-go/prices"
+)
+
+// Series is an account's value at the end of each day and the external flows during each day.
+// Flows are positive when money or securities come into the account.
+type Series struct {
+    Start  time.Time // Day of Values[0], midnight UTC
+    Values []float64
+    Flows  []float64
+}
+
+// Performance is the return of a series over a period
+type Performance struct {
+    From          time.Time `json:"from"`
+    To            time.Time `json:"to"`
+    StartValue    float64   `json:"startValue"`
+    EndValue      float64   `json:"endValue"`
+    NetFlows      float64   `json:"netFlows"`
+    TWR           *float64  `json:"twr"`           // Time-weighted return in percent
+    AnnualizedTWR *float64  `json:"annualizedTwr"` // Only for periods of a year or more
+    MWR           *float64  `json:"mwr"`           // Money-weighted return over the period in percent
+    XIRR          *float64  `json:"xirr"`          // Money-weighted return as an annual rate in percent
+}
+
+// BuildSeries values one account every day from start to end. Holdings are priced with the last
+// close on or before each day, falling back to the last trade price. Accounts with recorded cash
+// flows are valued including their cash, and only deposits, withdrawals and transfers count as
+// external flows. Accounts without cash flows are valued on their securities alone, so their buys
+// and sells are the external flows. Shares moved between tickers by corporate actions are never flows.
+func BuildSeries(transactions []models.Transaction, cashFlows []models.CashFlow, closes map[string][]prices.DailyClose, start time.Time, end time.Time) Series {
+    sorted := make([]models.Transaction, len(transactions))
+    copy(sorted, transactions)
+    SortTransactions(sorted)
+
+    flows := make([]models.CashFlow, len(cashFlows))
+    copy(flows, cashFlows)
+    sort.SliceStable(flows, func(i, j int) bool { return flows[i].Date.Before(flows[j].Date) })
+
+    cashTracked := len(flows) > 0
+    days := int(end.Sub(start).Hours()/24) + 1
+    series := Series{Start: start, Values: make([]float64, days), Flows: make([]float64, days)}
+
+    quantities := map[string]float64{}
+    lastTrade := map[string]float64{}
+    closeIndex := map[string]int{}
+    cash := 0.0
+    nextTransaction, nextFlow := 0, 0
+
+    for i := 0; i < days; i++ {
+        day := start.AddDate(0, 0, i)
+        dayEnd := day.AddDate(0, 0, 1)
+
+        // Price of a ticker as of this day
+        price := func(ticker string) float64 {
+            tickerCloses := closes[ticker]
+            index := closeIndex[ticker]
+            for index < len(tickerCloses) && tickerCloses[index].Date.Before(dayEnd) {
+                index++
+            }
+            closeIndex[ticker] = index
+            if index > 0 {
+                return tickerCloses[index-1].Close
+            }
+            return lastTrade[ticker]
+        }
+
+        flow := 0.0
+        for ; nextTransaction < len(sorted) && sorted[nextTransaction].Date.Before(dayEnd); nextTransaction++ {
+            t := sorted[nextTransaction]
+            if t.Price > 0 {
+                lastTrade[t.Ticker] = t.Price
+            }
+
+            // Trades and income stay in the account's cash, or are money moving in and out when cash is not tracked
+            if effect := CashEffect(t); effect != 0 {
+                if cashTracked {
+                    cash += effect
+                } else {
+                    flow -= effect
+                }
+            }
+
+            switch t.Type {
+            case models.TransactionBuy:
+                quantities[t.Ticker] += t.Quantity
+            case models.TransactionSell:
+                quantities[t.Ticker] -= t.Quantity
+            case models.TransactionTransferIn:
+                quantities[t.Ticker] += t.Quantity
+                if t.CorporateActionID.IsZero() {
+                    flow += t.Quantity * price(t.Ticker)
+                }
+            case models.TransactionTransferOut:
+                quantities[t.Ticker] -= t.Quantity
+                if t.CorporateActionID.IsZero() {
+                    flow -= t.Quantity * price(t.Ticker)
+                }
+            case models.TransactionSplit:
+                quantities[t.Ticker] *= t.Ratio
+            case models.TransactionDividend, models.TransactionInterest:
+                if t.Reinvested {
+                    quantities[t.Ticker] += t.Quantity
+                }
+            }
+        }
+
+        for ; nextFlow < len(flows) && flows[nextFlow].Date.Before(dayEnd); nextFlow++ {
+            cash += flows[nextFlow].SignedAmount()
+            if flows[nextFlow].IsExternal() {
+                flow += flows[nextFlow].SignedAmount()
+            }
+        }
+
+        value := cash
+        for ticker, quantity := range quantities {
+            if quantity > quantityEpsilon {
+                value += quantity * price(ticker)
+            }
+        }
+
+        series.Values[i] = value
+        series.Flows[i] = flow
+    }
+
+    return series
+}
+
+// Add returns the day by day sum of two series covering the same days
+func (s Series) Add(other Series) Series {
+    if len(s.Values) == 0 {
+        return other
+    }
+
+    sum := Series{Start: s.Start, Values: make([]float64, len(s.Values)), Flows: make([]float64, len(s.Flows))}
+    for i := range s.Values {
+        sum.Values[i] = s.Values[i] + other.Values[i]
+        sum.Flows[i] = s.Flows[i] + other.Flows[i]
+    }
+    return sum
+}
+
+// Scale returns the series with every value and flow multiplied by rate, e.g. to convert it into another currency
+func (s Series) Scale(rate float64) Series {
+    scaled := Series{Start: s.Start, Values: make([]float64, len(s.Values)), Flows: make([]float64, len(s.Flows))}
+    for i := range s.Values {
+        scaled.Values[i] = s.Values[i] * rate
+        scaled.Flows[i] = s.Flows[i] * rate
+    }
+    return scaled
+}
+
+// Performance returns the returns of the series from the start of from through the end of to.
+// Flows are assumed to happen at the start of their day.
+func (s Series) Performance(from time.Time, to time.Time) Performance {
+    first := s.dayIndex(from)
+    last := s.dayIndex(to)
+    if first < 1 {
+        first = 1 // Index 0 is the baseline before anything happened
+    }
+    if last > len(s.Values)-1 {
+        last = len(s.Values) - 1
+    }
+
+    performance := Performance{From: s.Start.AddDate(0, 0, first), To: s.Start.AddDate(0, 0, last)}
+    if first > last {
+        return performance
+    }
+
+    performance.StartValue = Round2(s.Values[first-1])
+    performance.EndValue = Round2(s.Values[last])
+
+    growth := 1.0
+    linked := false
+    cashFlows := []datedFlow{{date: s.Start.AddDate(0, 0, first-1), amount: -s.Values[first-1]}}
+    for i := first; i <= last; i++ {
+        performance.NetFlows += s.Flows[i]
+        if s.Flows[i] != 0 {
+            cashFlows = append(cashFlows, datedFlow{date: s.Start.AddDate(0, 0, i), amount: -s.Flows[i]})
+        }
+
+        base := s.Values[i-1] + s.Flows[i]
+        if base > 0.005 {
+            growth *= s.Values[i] / base
+            linked = true
+        }
+    }
+    cashFlows = append(cashFlows, datedFlow{date: performance.To, amount: s.Values[last]})
+    performance.NetFlows = Round2(performance.NetFlows)
+
+    days := float64(last - first + 1)
+    if linked {
+        twr := (growth - 1) * 100
+        performance.TWR = float64Ptr(Round2(twr))
+
+        if days >= 365 {
+            performance.AnnualizedTWR = float64Ptr(Round2((math.Pow(growth, 365/days) - 1) * 100))
+        }
+    }
+
+    if rate, ok := xirr(cashFlows); ok {
+        performance.XIRR = float64Ptr(Round2(rate * 100))
+        performance.MWR = float64Ptr(Round2((math.Pow(1+rate, days/365) - 1) * 100))
+    }
+
+    return performance
+}
+
+// dayIndex returns the index of the day in the series
+func (s Series) dayIndex(day time.Time) int {
+    return int(math.Floor(day.Sub(s.Start).Hours() / 24))
+}
+
+// PeriodStart returns the first day of a named period ending on to: 1M, 3M, YTD, 1Y or ITD (since inception)
+func PeriodStart(period string, to time.Time, inception time.Time) (time.Time, error) {
+    switch strings.ToUpper(period) {
+    case "1M":
+        return monthsBefore(to, 1).AddDate(0, 0, 1), nil
+    case "3M":
+        return monthsBefore(to, 3).AddDate(0, 0, 1), nil
+    case "YTD":
+        return time.Date(to.Year(), time.January, 1, 0, 0, 0, 0, time.UTC), nil
+    case "1Y":
+        return monthsBefore(to, 12).AddDate(0, 0, 1), nil
+    case "ITD":
+        return inception, nil
+    }
+    return time.Time{}, fmt.Errorf("unknown period %q, expected 1M, 3M, YTD, 1Y or ITD", period)
+}
+
+// monthsBefore returns the same day the given number of months earlier, clamped to the last day of that month.
+// AddDate would normalize March 31 minus a month to March 3 instead of February 28.
+func monthsBefore(t time.Time, months int) time.Time {
+    first := time.Date(t.Year(), t.Month()-time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+    lastDay := first.AddDate(0, 1, -1).Day()
+    day := t.Day()
+    if day > lastDay {
+        day = lastDay
+    }
+    return first.AddDate(0, 0, day-1)
+}
+
+type datedFlow struct {
+    date   time.Time
+    amount float64
+}
+
+// xirr solves for the annual rate that makes the net present value of the flows zero.
+// It needs at least one negative and one positive flow.
+func xirr(flows []datedFlow) (float64, bool) {
+    hasNegative, hasPositive := false, false
+    for _, flow := range flows {
+        hasNegative = hasNegative || flow.amount < -0.005
+        hasPositive = hasPositive || flow.amount > 0.005
+    }
+    if !hasNegative || !hasPositive {
+        return 0, false
+    }
+
+    // Solve for the log of the annual growth factor, which keeps very short periods with large
+    // annualized rates inside a bounded search
+    origin := flows[0].date
+    npv := func(logGrowth float64) float64 {
+        total := 0.0
+        for _, flow := range flows {
+            years := flow.date.Sub(origin).Hours() / 24 / 365
+            total += flow.amount * math.Exp(-logGrowth*years)
+        }
+        return total
+    }
+
+    // The net present value falls as the rate rises, so bisect between a near total loss and a huge gain
+    low, high := -20.0, 20.0
+    if npv(low) < 0 || npv(high) > 0 {
+        return 0, false
+    }
+    for i := 0; i < 200 && high-low > 1e-12; i++ {
+        mid := (low + high) / 2
+        if npv(mid) > 0 {
+            low = mid
+        } else {
+            high = mid
+        }
+    }
+    return math.Expm1((low + high) / 2), true
+}
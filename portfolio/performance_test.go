@@ -0,0 +1,132 @@
+package portfolio
+
+import (
+    "testing"
+    "time"
+)
+
+func TestPeriodStart(t *testing.T) {
+    inception := day(2019, 7, 15)
+
+    tests := []struct {
+        period  string
+        to      time.Time
+        want    time.Time
+        wantErr bool
+    }{
+        {"1M", day(2024, 5, 15), day(2024, 4, 16), false},
+        {"1M", day(2023, 3, 31), day(2023, 3, 1), false},
+        {"1M", day(2024, 3, 31), day(2024, 3, 1), false},
+        {"1M", day(2024, 3, 29), day(2024, 3, 1), false},
+        {"1M", day(2024, 5, 31), day(2024, 5, 1), false},
+        {"1M", day(2024, 1, 31), day(2024, 1, 1), false},
+        {"3M", day(2024, 5, 31), day(2024, 3, 1), false},
+        {"3M", day(2023, 5, 30), day(2023, 3, 1), false},
+        {"3m", day(2024, 2, 15), day(2023, 11, 16), false},
+        {"YTD", day(2024, 5, 15), day(2024, 1, 1), false},
+        {"1Y", day(2024, 5, 15), day(2023, 5, 16), false},
+        {"1Y", day(2024, 2, 29), day(2023, 3, 1), false},
+        {"ITD", day(2024, 5, 15), inception, false},
+        {"5Y", day(2024, 5, 15), time.Time{}, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.period+" to "+tt.to.Format("2006-01-02"), func(t *testing.T) {
+            got, err := PeriodStart(tt.period, tt.to, inception)
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("PeriodStart() error = %v, wantErr %v", err, tt.wantErr)
+            }
+            if !got.Equal(tt.want) {
+                t.Errorf("PeriodStart() = %s, want %s", got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+            }
+        })
+    }
+}
+
+func TestPerformanceTWR(t *testing.T) {
+    start := day(2024, 1, 1)
+
+    tests := []struct {
+        name    string
+        values  []float64
+        flows   []float64
+        wantTWR *float64
+        wantNet float64
+    }{
+        {"steady growth", []float64{100, 110, 121}, []float64{0, 0, 0}, float64Ptr(21), 0},
+        {"deposit is not a gain", []float64{100, 110, 160}, []float64{0, 0, 50}, float64Ptr(10), 50},
+        {"withdrawal is not a loss", []float64{100, 110, 60}, []float64{0, 0, -50}, float64Ptr(10), -50},
+        {"first deposit into an empty account", []float64{0, 100, 105}, []float64{0, 100, 0}, float64Ptr(5), 100},
+        {"nothing invested", []float64{0, 0, 0}, []float64{0, 0, 0}, nil, 0},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            series := Series{Start: start, Values: tt.values, Flows: tt.flows}
+            performance := series.Performance(start, start.AddDate(0, 0, len(tt.values)-1))
+
+            switch {
+            case tt.wantTWR == nil && performance.TWR != nil:
+                t.Errorf("TWR = %v, want none", *performance.TWR)
+            case tt.wantTWR != nil && performance.TWR == nil:
+                t.Errorf("TWR = none, want %v", *tt.wantTWR)
+            case tt.wantTWR != nil && !closeTo(*performance.TWR, *tt.wantTWR):
+                t.Errorf("TWR = %v, want %v", *performance.TWR, *tt.wantTWR)
+            }
+            if !closeTo(performance.NetFlows, tt.wantNet) {
+                t.Errorf("NetFlows = %v, want %v", performance.NetFlows, tt.wantNet)
+            }
+        })
+    }
+}
+
+func TestXIRR(t *testing.T) {
+    start := day(2023, 1, 1)
+
+    tests := []struct {
+        name   string
+        flows  []datedFlow
+        want   float64
+        wantOK bool
+    }{
+        {"ten percent over a year", []datedFlow{{start, -100}, {start.AddDate(0, 0, 365), 110}}, 0.10, true},
+        {"loss over a year", []datedFlow{{start, -100}, {start.AddDate(0, 0, 365), 80}}, -0.20, true},
+        {"ten percent over two years", []datedFlow{{start, -100}, {start.AddDate(0, 0, 730), 121}}, 0.10, true},
+        {
+            "deposit halfway",
+            []datedFlow{{start, -100}, {start.AddDate(0, 0, 365), -100}, {start.AddDate(0, 0, 730), 231}},
+            0.10, true,
+        },
+        {"no return flow", []datedFlow{{start, -100}, {start.AddDate(0, 0, 365), -10}}, 0, false},
+        {"no investment", []datedFlow{{start, 100}}, 0, false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, ok := xirr(tt.flows)
+            if ok != tt.wantOK {
+                t.Fatalf("xirr() ok = %v, want %v", ok, tt.wantOK)
+            }
+            if ok && !closeTo(Round2(got*100), tt.want*100) {
+                t.Errorf("xirr() = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestSeriesScaleKeepsReturns(t *testing.T) {
+    start := day(2024, 1, 1)
+    series := Series{Start: start, Values: []float64{100, 110, 160}, Flows: []float64{0, 0, 50}}
+    scaled := series.Scale(1.25)
+
+    if !closeTo(scaled.Values[2], 200) || !closeTo(scaled.Flows[2], 62.5) {
+        t.Errorf("Scale(1.25) = %v, %v", scaled.Values, scaled.Flows)
+    }
+    to := start.AddDate(0, 0, 2)
+    if got, want := *scaled.Performance(start, to).TWR, *series.Performance(start, to).TWR; !closeTo(got, want) {
+        t.Errorf("TWR after Scale = %v, want %v", got, want)
+    }
+    if series.Values[2] != 160 {
+        t.Errorf("Scale changed the original series")
+    }
+}
@@ -0,0 +1,235 @@
+package portfolio
+// Path: portfolio/rebalance.go
+
+import (
+    "fmt"
+    "math"
+    "sort"
+)
+
+// CashGroup is the group account cash is allocated to
+const CashGroup = "cash"
+
+// Trade actions
+const (
+    TradeBuy  = "buy"
+    TradeSell = "sell"
+)
+
+// RebalancePosition is a priced holding, with its value and price in the base currency
+type RebalancePosition struct {
+    Ticker      string
+    Account     string
+    Group       string
+    Taxable     bool
+    MarketValue float64
+    Price       float64
+}
+
+// RebalanceOptions control which trades a rebalance proposes
+type RebalanceOptions struct {
+    Tolerance         float64 // Percentage points a group may drift from its target before it is traded
+    MinTradeAmount    float64 // Smaller trades are left out
+    AvoidTaxableSells bool    // Only sell in tax-advantaged accounts
+    ByTicker          bool    // Groups are tickers, so a group with no holding can still be bought
+}
+
+// GroupDrift compares a group's weight with its target
+type GroupDrift struct {
+    Group        string  `json:"group"`
+    MarketValue  float64 `json:"marketValue"`
+    Weight       float64 `json:"weight"`
+    TargetWeight float64 `json:"targetWeight"`
+    Drift        float64 `json:"drift"` // Weight minus target weight, in percentage points
+    OutOfBand    bool    `json:"outOfBand"`
+}
+
+// Trade is a proposed buy or sell, its amount in the base currency
+type Trade struct {
+    Action   string   `json:"action"`
+    Group    string   `json:"group"`
+    Ticker   string   `json:"ticker"` // Empty when the group has no holding to buy more of
+    Account  string   `json:"account"`
+    Amount   float64  `json:"amount"`
+    Quantity *float64 `json:"quantity"` // Approximate shares at the latest price
+}
+
+// RebalancePlan is the drift of each group and the trades that bring the out of band groups back to target
+type RebalancePlan struct {
+    TotalValue float64      `json:"totalValue"`
+    Groups     []GroupDrift `json:"groups"`
+    Trades     []Trade      `json:"trades"`
+    Notes      []string     `json:"notes"`
+}
+
+// Rebalance proposes the trades that bring each group drifting outside the tolerance band back to its target
+// weight. targets are percentages by group, and groups without one are targeted at zero. Cash is not traded,
+// it receives the proceeds of sells and funds buys, which are scaled down when there isn't enough of it.
+func Rebalance(positions []RebalancePosition, cash float64, targets map[string]float64, options RebalanceOptions) RebalancePlan {
+    plan := RebalancePlan{Groups: []GroupDrift{}, Trades: []Trade{}, Notes: []string{}}
+
+    values := map[string]float64{}
+    if cash != 0 || targets[CashGroup] != 0 {
+        values[CashGroup] = cash
+    }
+    total := cash
+    for _, position := range positions {
+        values[position.Group] += position.MarketValue
+        total += position.MarketValue
+    }
+    for group := range targets {
+        if _, ok := values[group]; !ok {
+            values[group] = 0
+        }
+    }
+
+    plan.TotalValue = Round2(total)
+    if total <= 0 {
+        plan.Notes = append(plan.Notes, "The portfolio has no value to rebalance")
+        return plan
+    }
+
+    groups := make([]string, 0, len(values))
+    for group := range values {
+        groups = append(groups, group)
+    }
+    sort.Strings(groups)
+
+    var sells []Trade
+    wanted := map[string]float64{}
+    wantedTotal := 0.0
+    for _, group := range groups {
+        weight := values[group] / total * 100
+        drift := weight - targets[group]
+        outOfBand := math.Abs(drift) > options.Tolerance
+        plan.Groups = append(plan.Groups, GroupDrift{
+            Group:        group,
+            MarketValue:  Round2(values[group]),
+            Weight:       Round2(weight),
+            TargetWeight: targets[group],
+            Drift:        Round2(drift),
+            OutOfBand:    outOfBand,
+        })
+        if !outOfBand || group == CashGroup {
+            continue
+        }
+
+        delta := targets[group]/100*total - values[group]
+        if delta < 0 {
+            groupSells, unsold := sellGroup(group, -delta, positions, options)
+            sells = append(sells, groupSells...)
+            if unsold > 0.005 {
+                plan.Notes = append(plan.Notes, fmt.Sprintf("%s: %.2f left unsold to avoid selling in taxable accounts", group, unsold))
+            }
+        } else {
+            wanted[group] = delta
+            wantedTotal += delta
+        }
+    }
+
+    // Sells too small to make are left out first, so buys are only funded by the proceeds of sells the plan makes
+    skipped := 0
+    keep := func(trade Trade) bool {
+        if trade.Amount < options.MinTradeAmount || trade.Amount < 0.005 {
+            skipped++
+            return false
+        }
+        trade.Amount = Round2(trade.Amount)
+        plan.Trades = append(plan.Trades, trade)
+        return true
+    }
+
+    // Buys are funded by the cash above its target and the proceeds of the sells
+    available := cash - targets[CashGroup]/100*total
+    for _, sell := range sells {
+        if keep(sell) {
+            available += sell.Amount
+        }
+    }
+    scale := 1.0
+    if wantedTotal > available {
+        scale = math.Max(available, 0) / wantedTotal
+        plan.Notes = append(plan.Notes, fmt.Sprintf("Buys scaled to %.2f%% of what reaching the targets needs, the cash available", scale*100))
+    }
+
+    for _, group := range groups {
+        if wanted[group] == 0 {
+            continue
+        }
+        buy := buyGroup(group, wanted[group]*scale, positions, options)
+        if buy.Ticker == "" {
+            plan.Notes = append(plan.Notes, fmt.Sprintf("%s: no holding to buy more of, choose a security", group))
+        }
+        keep(buy)
+    }
+
+    if skipped > 0 {
+        plan.Notes = append(plan.Notes, fmt.Sprintf("%d trades below the minimum trade amount left out", skipped))
+    }
+
+    return plan
+}
+
+// sellGroup sells amount of the group's holdings, tax-advantaged accounts and larger holdings first.
+// It returns the amount that could not be sold.
+func sellGroup(group string, amount float64, positions []RebalancePosition, options RebalanceOptions) ([]Trade, float64) {
+    var candidates []RebalancePosition
+    for _, position := range positions {
+        if position.Group == group && !(position.Taxable && options.AvoidTaxableSells) {
+            candidates = append(candidates, position)
+        }
+    }
+    sort.SliceStable(candidates, func(i, j int) bool {
+        if candidates[i].Taxable != candidates[j].Taxable {
+            return !candidates[i].Taxable
+        }
+        return candidates[i].MarketValue > candidates[j].MarketValue
+    })
+
+    var trades []Trade
+    for _, position := range candidates {
+        if amount <= 0 {
+            break
+        }
+        sold := math.Min(amount, position.MarketValue)
+        trades = append(trades, Trade{
+            Action:   TradeSell,
+            Group:    group,
+            Ticker:   position.Ticker,
+            Account:  position.Account,
+            Amount:   sold,
+            Quantity: shares(sold, position.Price),
+        })
+        amount -= sold
+    }
+    return trades, amount
+}
+
+// buyGroup buys amount more of the group's largest holding, in the account that holds it
+func buyGroup(group string, amount float64, positions []RebalancePosition, options RebalanceOptions) Trade {
+    trade := Trade{Action: TradeBuy, Group: group, Amount: amount}
+    if options.ByTicker {
+        trade.Ticker = group
+    }
+
+    var largest *RebalancePosition
+    for i, position := range positions {
+        if position.Group == group && (largest == nil || position.MarketValue > largest.MarketValue) {
+            largest = &positions[i]
+        }
+    }
+    if largest != nil {
+        trade.Ticker = largest.Ticker
+        trade.Account = largest.Account
+        trade.Quantity = shares(amount, largest.Price)
+    }
+    return trade
+}
+
+// shares returns the approximate number of shares amount buys at price
+func shares(amount float64, price float64) *float64 {
+    if price <= 0 {
+        return nil
+    }
+    return float64Ptr(math.Round(amount/price*10000) / 10000)
+}
@@ -0,0 +1,123 @@
+package portfolio
+
+import (
+    "fmt"
+    "reflect"
+    "testing"
+)
+
+func TestRebalance(t *testing.T) {
+    positions := []RebalancePosition{
+        {Ticker: "STK", Account: "IRA", Group: "stocks", MarketValue: 600, Price: 10},
+        {Ticker: "TAX", Account: "Brokerage", Group: "stocks", Taxable: true, MarketValue: 200, Price: 20},
+        {Ticker: "BND", Account: "IRA", Group: "bonds", MarketValue: 200, Price: 50},
+    }
+
+    tests := []struct {
+        name       string
+        positions  []RebalancePosition
+        cash       float64
+        targets    map[string]float64
+        options    RebalanceOptions
+        wantTrades []string
+        wantNotes  int
+    }{
+        {
+            name:       "on target",
+            positions:  positions,
+            targets:    map[string]float64{"stocks": 80, "bonds": 20},
+            wantTrades: []string{},
+        },
+        {
+            name:       "inside the tolerance band",
+            positions:  positions,
+            targets:    map[string]float64{"stocks": 78, "bonds": 22},
+            options:    RebalanceOptions{Tolerance: 5},
+            wantTrades: []string{},
+        },
+        {
+            name:       "outside the tolerance band",
+            positions:  positions,
+            targets:    map[string]float64{"stocks": 78, "bonds": 22},
+            options:    RebalanceOptions{Tolerance: 1},
+            wantTrades: []string{"sell STK IRA 20", "buy BND IRA 20"},
+        },
+        {
+            name:       "tax-advantaged accounts sold first",
+            positions:  positions,
+            targets:    map[string]float64{"stocks": 10, "bonds": 90},
+            wantTrades: []string{"sell STK IRA 600", "sell TAX Brokerage 100", "buy BND IRA 700"},
+        },
+        {
+            name:       "taxable sells avoided and buys scaled to the cash available",
+            positions:  positions,
+            targets:    map[string]float64{"stocks": 10, "bonds": 90},
+            options:    RebalanceOptions{AvoidTaxableSells: true},
+            wantTrades: []string{"sell STK IRA 600", "buy BND IRA 600"},
+            wantNotes:  2,
+        },
+        {
+            name:       "cash above its target funds buys",
+            positions:  positions,
+            cash:       250,
+            targets:    map[string]float64{"stocks": 64, "bonds": 36},
+            options:    RebalanceOptions{Tolerance: 1},
+            wantTrades: []string{"buy BND IRA 250"},
+        },
+        {
+            name:       "cash held at its target",
+            positions:  positions,
+            cash:       250,
+            targets:    map[string]float64{"stocks": 60, "bonds": 20, CashGroup: 20},
+            options:    RebalanceOptions{Tolerance: 1},
+            wantTrades: []string{"sell STK IRA 50", "buy BND IRA 50"},
+        },
+        {
+            name:       "trades below the minimum left out",
+            positions:  positions,
+            targets:    map[string]float64{"stocks": 78, "bonds": 22},
+            options:    RebalanceOptions{Tolerance: 1, MinTradeAmount: 50},
+            wantTrades: []string{},
+            wantNotes:  2,
+        },
+        {
+            name: "buys not funded by sells below the minimum",
+            positions: []RebalancePosition{
+                {Ticker: "STK", Account: "IRA", Group: "stocks", MarketValue: 500, Price: 10},
+                {Ticker: "BND", Account: "IRA", Group: "bonds", MarketValue: 500, Price: 50},
+            },
+            targets:    map[string]float64{"stocks": 47, "bonds": 47, "gold": 6},
+            options:    RebalanceOptions{Tolerance: 1, MinTradeAmount: 50},
+            wantTrades: []string{},
+            wantNotes:  3,
+        },
+        {
+            name:       "group with nothing to buy",
+            positions:  positions[:1],
+            targets:    map[string]float64{"stocks": 50, "gold": 50},
+            wantTrades: []string{"sell STK IRA 300", "buy  gold 300"},
+            wantNotes:  1,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            plan := Rebalance(tt.positions, tt.cash, tt.targets, tt.options)
+
+            trades := []string{}
+            for _, trade := range plan.Trades {
+                account := trade.Account
+                if trade.Ticker == "" {
+                    account = trade.Group
+                }
+                trades = append(trades, fmt.Sprintf("%s %s %s %v", trade.Action, trade.Ticker, account, trade.Amount))
+            }
+            if !reflect.DeepEqual(trades, tt.wantTrades) {
+                t.Errorf("Rebalance() trades = %q, want %q", trades, tt.wantTrades)
+            }
+            if len(plan.Notes) != tt.wantNotes {
+                t.Errorf("Rebalance() notes = %q, want %d", plan.Notes, tt.wantNotes)
+            }
+        })
+    }
+}
@@ -0,0 +1,206 @@
+package portfolio
+// Path: portfolio/valuation.go
+
+import (
+    "context"
+    "errors"
+    "log"
+    "math"
+    "sync"
+
+    "github.com/jalong4/stock-service-go/fx"
+    "github.com/jalong4/stock-service-go/models"
+    "github.com/jalong4/stock-service-go/prices"
+)
+
+// maxConcurrentQuotes limits the quote requests made at once for one valuation
+const maxConcurrentQuotes = 8
+
+// ValuedHolding is a holding with its market value. The valuation fields are null when no price is available.
+type ValuedHolding struct {
+    models.Holding
+    Price             *float64 `json:"price"`
+    MarketValue       *float64 `json:"marketValue"`
+    UnrealizedGain    *float64 `json:"unrealizedGain"`
+    UnrealizedGainPct *float64 `json:"unrealizedGainPct"`
+    DayChange         *float64 `json:"dayChange"`
+    DayChangePct      *float64 `json:"dayChangePct"`
+    TrailingIncome    *float64 `json:"trailingIncome"` // Dividends and interest over the last twelve months
+    YieldOnCost       *float64 `json:"yieldOnCost"`    // Trailing income as a percentage of cost
+    FxRate            *float64 `json:"fxRate"`         // Base currency units per unit of the holding's currency
+    BaseCostBasis     *float64 `json:"baseCostBasis"`
+    BaseMarketValue   *float64 `json:"baseMarketValue"`
+}
+
+// Valuation totals a set of valued holdings in one currency, converted at the latest FX rates.
+// Gains only include holdings that could be priced, and totals leave out holdings with no FX rate.
+type Valuation struct {
+    Currency         string
+    TotalCost        float64
+    TotalMarketValue float64
+    TotalGain        float64
+    TotalGainPct     float64
+    DayChange        float64
+    Unpriced         int
+    Unconverted      int
+    ByCurrency       map[string]*CurrencyTotals // Unconverted totals of each currency held
+}
+
+// CurrencyTotals are the cost and market value of the holdings in one currency
+type CurrencyTotals struct {
+    TotalCost        float64 `json:"totalCost"`
+    TotalMarketValue float64 `json:"totalMarketValue"`
+}
+
+// Round2 rounds an amount to two decimal places
+func Round2(value float64) float64 {
+    return math.Round(value*100) / 100
+}
+
+// ValueHoldings prices the holdings with the provider, fetching each ticker once. Holdings stay in their own
+// currency, while the totals are converted into the converter's base currency.
+func ValueHoldings(ctx context.Context, provider prices.PriceProvider, holdings []models.Holding, converter fx.Converter) ([]ValuedHolding, Valuation) {
+    var tickers []string
+    for _, holding := range holdings {
+        tickers = append(tickers, holding.Ticker)
+    }
+    quotes := FetchQuotes(ctx, provider, tickers)
+
+    valuation := Valuation{Currency: converter.Base, ByCurrency: map[string]*CurrencyTotals{}}
+    pricedCost := 0.0
+    valued := make([]ValuedHolding, 0, len(holdings))
+    for _, holding := range holdings {
+        cost := holding.CostBasis()
+        marketValue := 0.0
+        dayChange := 0.0
+
+        item := ValuedHolding{Holding: holding}
+        quote := quotes[holding.Ticker]
+        if quote != nil {
+            marketValue = holding.Quantity * quote.Price
+            gain := marketValue - cost
+            item.Price = float64Ptr(quote.Price)
+            item.MarketValue = float64Ptr(Round2(marketValue))
+            item.UnrealizedGain = float64Ptr(Round2(gain))
+            if cost != 0 {
+                item.UnrealizedGainPct = float64Ptr(Round2(gain / cost * 100))
+            }
+
+            if quote.PreviousClose != 0 {
+                dayChange = holding.Quantity * (quote.Price - quote.PreviousClose)
+                item.DayChange = float64Ptr(Round2(dayChange))
+                item.DayChangePct = float64Ptr(Round2((quote.Price - quote.PreviousClose) / quote.PreviousClose * 100))
+            }
+        } else {
+            valuation.Unpriced++
+        }
+
+        if holding.Currency != "" {
+            totals := valuation.ByCurrency[holding.Currency]
+            if totals == nil {
+                totals = &CurrencyTotals{}
+                valuation.ByCurrency[holding.Currency] = totals
+            }
+            totals.TotalCost += cost
+            totals.TotalMarketValue += marketValue
+        }
+
+        rate, ok := converter.Rate(holding.Currency)
+        if !ok {
+            valuation.Unconverted++
+            valued = append(valued, item)
+            continue
+        }
+        if converter.Base != "" {
+            item.FxRate = float64Ptr(rate)
+            item.BaseCostBasis = float64Ptr(Round2(cost * rate))
+            if quote != nil {
+                item.BaseMarketValue = float64Ptr(Round2(marketValue * rate))
+            }
+        }
+
+        valuation.TotalCost += cost * rate
+        if quote != nil {
+            valuation.TotalMarketValue += marketValue * rate
+            valuation.DayChange += dayChange * rate
+            pricedCost += cost * rate
+        }
+        valued = append(valued, item)
+    }
+
+    valuation.TotalGain = valuation.TotalMarketValue - pricedCost
+    if pricedCost != 0 {
+        valuation.TotalGainPct = valuation.TotalGain / pricedCost * 100
+    }
+
+    return valued, valuation
+}
+
+// FetchQuotes gets the latest quote of each distinct ticker, a few at a time.
+// Tickers without a price map to nil.
+func FetchQuotes(ctx context.Context, provider prices.PriceProvider, tickers []string) map[string]*prices.Quote {
+    quotes := map[string]*prices.Quote{}
+    var distinct []string
+    for _, ticker := range tickers {
+        if _, seen := quotes[ticker]; !seen {
+            quotes[ticker] = nil
+            distinct = append(distinct, ticker)
+        }
+    }
+
+    var mu sync.Mutex
+    var wg sync.WaitGroup
+    limit := make(chan struct{}, maxConcurrentQuotes)
+    for _, ticker := range distinct {
+        wg.Add(1)
+        go func(ticker string) {
+            defer wg.Done()
+            limit <- struct{}{}
+            defer func() { <-limit }()
+
+            quote, err := provider.LatestQuote(ctx, ticker)
+            if err != nil {
+                if !errors.Is(err, prices.ErrNoPrice) {
+                    log.Printf("Failed to get quote for %s: %v", ticker, err)
+                }
+                return
+            }
+
+            mu.Lock()
+            quotes[ticker] = &quote
+            mu.Unlock()
+        }(ticker)
+    }
+    wg.Wait()
+
+    return quotes
+}
+
+// Summary returns the valuation totals rounded for responses
+func (v Valuation) Summary(found int) map[string]interface{} {
+    summary := map[string]interface{}{
+        "found":            found,
+        "totalCost":        Round2(v.TotalCost),
+        "totalMarketValue": Round2(v.TotalMarketValue),
+        "totalGain":        Round2(v.TotalGain),
+        "totalGainPct":     Round2(v.TotalGainPct),
+        "dayChange":        Round2(v.DayChange),
+        "unpriced":         v.Unpriced,
+    }
+    if v.Currency == "" {
+        return summary
+    }
+
+    byCurrency := map[string]CurrencyTotals{}
+    for currency, totals := range v.ByCurrency {
+        byCurrency[currency] = CurrencyTotals{TotalCost: Round2(totals.TotalCost), TotalMarketValue: Round2(totals.TotalMarketValue)}
+    }
+    summary["currency"] = v.Currency
+    summary["unconverted"] = v.Unconverted
+    summary["byCurrency"] = byCurrency
+    return summary
+}
+
+func float64Ptr(value float64) *float64 {
+    return &value
+}
@@ -0,0 +1,220 @@
+package prices
+// Path: prices/cache.go
+
+import (
+    "context"
+    "errors"
+    "log"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "time"
+)
+
+// fetchTimeout bounds a provider call shared by coalesced requests, independent of any one caller
+const fetchTimeout = 30 * time.Second
+
+// Cache limits used by NewCachedProvider
+const (
+    defaultMaxEntries  = 10000
+    defaultNegativeTTL = 30 * time.Second
+)
+
+// CacheStats are the quote cache counters exposed for diagnostics
+type CacheStats struct {
+    Hits      uint64 `json:"hits"`      // Served fresh from the cache
+    StaleHits uint64 `json:"staleHits"` // Served stale while being refreshed in the background
+    Misses    uint64 `json:"misses"`    // Had to wait for the provider
+    Coalesced uint64 `json:"coalesced"` // Misses that joined a provider call already in flight
+    Fetches   uint64 `json:"fetches"`   // Provider calls made
+    Errors    uint64 `json:"errors"`    // Provider calls that failed
+    Evictions uint64 `json:"evictions"` // Entries dropped to stay within MaxEntries
+    Entries   int    `json:"entries"`
+    TTL       string `json:"ttl"`
+    StaleTTL  string `json:"staleTtl"`
+}
+
+// CachedProvider caches the latest quotes of another provider in memory.
+// Quotes younger than TTL are served from the cache. Quotes up to TTL+StaleTTL old are
+// served while a background refresh runs. Concurrent misses for the same ticker share a
+// single provider call. Daily closes are passed through uncached.
+// Tickers with no price are remembered for NegativeTTL, and at most MaxEntries tickers are
+// kept, dropping expired entries and then the oldest.
+type CachedProvider struct {
+    Provider    PriceProvider
+    TTL         time.Duration
+    StaleTTL    time.Duration
+    NegativeTTL time.Duration
+    MaxEntries  int
+
+    mu       sync.Mutex
+    entries  map[string]cacheEntry
+    inflight map[string]*quoteCall
+
+    hits, staleHits, misses, coalesced, fetches, errors, evictions atomic.Uint64
+}
+
+type cacheEntry struct {
+    quote   Quote
+    err     error // ErrNoPrice is cached briefly so unknown tickers are not refetched on every request
+    fetched time.Time
+}
+
+type quoteCall struct {
+    done  chan struct{}
+    quote Quote
+    err   error
+}
+
+// NewCachedProvider wraps provider with a quote cache
+func NewCachedProvider(provider PriceProvider, ttl time.Duration, staleTTL time.Duration) *CachedProvider {
+    return &CachedProvider{
+        Provider:    provider,
+        TTL:         ttl,
+        StaleTTL:    staleTTL,
+        NegativeTTL: defaultNegativeTTL,
+        MaxEntries:  defaultMaxEntries,
+        entries:     map[string]cacheEntry{},
+        inflight:    map[string]*quoteCall{},
+    }
+}
+
+// LatestQuote returns the cached quote for the ticker, fetching it from the provider when needed
+func (c *CachedProvider) LatestQuote(ctx context.Context, ticker string) (Quote, error) {
+    key := strings.ToUpper(ticker)
+
+    c.mu.Lock()
+    if entry, ok := c.entries[key]; ok {
+        age := time.Since(entry.fetched)
+        fresh, usable := c.lifetimes(entry)
+        if age < fresh {
+            c.mu.Unlock()
+            c.hits.Add(1)
+            return entry.quote, entry.err
+        }
+        if age < usable {
+            c.startFetchLocked(key, ticker)
+            c.mu.Unlock()
+            c.staleHits.Add(1)
+            return entry.quote, entry.err
+        }
+        delete(c.entries, key)
+    }
+
+    call, joined := c.startFetchLocked(key, ticker)
+    c.mu.Unlock()
+
+    c.misses.Add(1)
+    if joined {
+        c.coalesced.Add(1)
+    }
+
+    select {
+    case <-call.done:
+        return call.quote, call.err
+    case <-ctx.Done():
+        return Quote{}, ctx.Err()
+    }
+}
+
+// DailyCloses passes through to the wrapped provider
+func (c *CachedProvider) DailyCloses(ctx context.Context, ticker string, from time.Time, to time.Time) ([]DailyClose, error) {
+    return c.Provider.DailyCloses(ctx, ticker, from, to)
+}
+
+// Stats returns a snapshot of the cache counters
+func (c *CachedProvider) Stats() CacheStats {
+    c.mu.Lock()
+    entries := len(c.entries)
+    c.mu.Unlock()
+
+    return CacheStats{
+        Hits:      c.hits.Load(),
+        StaleHits: c.staleHits.Load(),
+        Misses:    c.misses.Load(),
+        Coalesced: c.coalesced.Load(),
+        Fetches:   c.fetches.Load(),
+        Errors:    c.errors.Load(),
+        Evictions: c.evictions.Load(),
+        Entries:   entries,
+        TTL:       c.TTL.String(),
+        StaleTTL:  c.StaleTTL.String(),
+    }
+}
+
+// startFetchLocked returns the provider call in flight for the ticker, starting one if there is none.
+// joined reports whether an existing call was returned. c.mu must be held.
+func (c *CachedProvider) startFetchLocked(key string, ticker string) (call *quoteCall, joined bool) {
+    if call, ok := c.inflight[key]; ok {
+        return call, true
+    }
+
+    call = &quoteCall{done: make(chan struct{})}
+    c.inflight[key] = call
+    c.fetches.Add(1)
+
+    go func() {
+        ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+        defer cancel()
+
+        call.quote, call.err = c.Provider.LatestQuote(ctx, ticker)
+
+        c.mu.Lock()
+        if call.err == nil || errors.Is(call.err, ErrNoPrice) {
+            c.storeLocked(key, cacheEntry{quote: call.quote, err: call.err, fetched: time.Now()})
+        } else {
+            // Keep serving the previous quote, if any, until it is too stale
+            c.errors.Add(1)
+            log.Printf("Failed to fetch quote for %s: %v", ticker, call.err)
+        }
+        delete(c.inflight, key)
+        c.mu.Unlock()
+
+        close(call.done)
+    }()
+
+    return call, false
+}
+
+// lifetimes returns how long the entry is served fresh and how long it is served at all. Entries for
+// tickers with no price last NegativeTTL, or TTL if that is shorter, and are never served stale.
+func (c *CachedProvider) lifetimes(entry cacheEntry) (fresh time.Duration, usable time.Duration) {
+    if entry.err != nil {
+        fresh = c.NegativeTTL
+        if fresh <= 0 || fresh > c.TTL {
+            fresh = c.TTL
+        }
+        return fresh, fresh
+    }
+    return c.TTL, c.TTL + c.StaleTTL
+}
+
+// storeLocked caches the entry, first making room for a new ticker when the cache is full. c.mu must be held.
+func (c *CachedProvider) storeLocked(key string, entry cacheEntry) {
+    if _, exists := c.entries[key]; !exists && c.MaxEntries > 0 && len(c.entries) >= c.MaxEntries {
+        c.evictLocked()
+    }
+    c.entries[key] = entry
+}
+
+// evictLocked drops the entries too old to serve and, if the cache is still full, the oldest one.
+// c.mu must be held.
+func (c *CachedProvider) evictLocked() {
+    now := time.Now()
+    oldestKey, oldest := "", time.Time{}
+    for key, entry := range c.entries {
+        if _, usable := c.lifetimes(entry); now.Sub(entry.fetched) >= usable {
+            delete(c.entries, key)
+            c.evictions.Add(1)
+            continue
+        }
+        if oldestKey == "" || entry.fetched.Before(oldest) {
+            oldestKey, oldest = key, entry.fetched
+        }
+    }
+
+    if len(c.entries) >= c.MaxEntries && oldestKey != "" {
+        delete(c.entries, oldestKey)
+        c.evictions.Add(1)
+    }
+}
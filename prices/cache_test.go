@@ -0,0 +1,168 @@
+package prices
+
+import (
+    "context"
+    "errors"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+// stubProvider prices each ticker at the number of quote calls made so far, and has no price for NONE.
+// When release is set, calls wait for it to be closed.
+type stubProvider struct {
+    calls   atomic.Int32
+    release chan struct{}
+}
+
+func (p *stubProvider) LatestQuote(ctx context.Context, ticker string) (Quote, error) {
+    n := p.calls.Add(1)
+    if p.release != nil {
+        <-p.release
+    }
+    if ticker == "NONE" {
+        return Quote{}, ErrNoPrice
+    }
+    return Quote{Ticker: ticker, Price: float64(n)}, nil
+}
+
+func (p *stubProvider) DailyCloses(ctx context.Context, ticker string, from time.Time, to time.Time) ([]DailyClose, error) {
+    return nil, ErrNoPrice
+}
+
+// waitFor polls until done reports true, failing the test after a second
+func waitFor(t *testing.T, done func() bool) {
+    t.Helper()
+    deadline := time.Now().Add(time.Second)
+    for !done() {
+        if time.Now().After(deadline) {
+            t.Fatal("timed out waiting for the cache")
+        }
+        time.Sleep(time.Millisecond)
+    }
+}
+
+// idle reports whether the cache has no provider call in flight
+func (c *CachedProvider) idle() bool {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return len(c.inflight) == 0
+}
+
+func TestCachedProviderCoalescesMisses(t *testing.T) {
+    stub := &stubProvider{release: make(chan struct{})}
+    cache := NewCachedProvider(stub, time.Minute, time.Minute)
+
+    const callers = 10
+    var wg sync.WaitGroup
+    results := make([]Quote, callers)
+    errs := make([]error, callers)
+    for i := 0; i < callers; i++ {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            results[i], errs[i] = cache.LatestQuote(context.Background(), "abc")
+        }(i)
+    }
+
+    waitFor(t, func() bool { return cache.Stats().Misses == callers })
+    close(stub.release)
+    wg.Wait()
+
+    for i := range results {
+        if errs[i] != nil || results[i].Price != 1 {
+            t.Errorf("caller %d got %v, %v, want the single fetch's quote", i, results[i], errs[i])
+        }
+    }
+    stats := cache.Stats()
+    if stub.calls.Load() != 1 || stats.Fetches != 1 || stats.Coalesced != callers-1 {
+        t.Errorf("%d provider calls, stats %+v, want 1 call with %d coalesced", stub.calls.Load(), stats, callers-1)
+    }
+}
+
+func TestCachedProviderExpiry(t *testing.T) {
+    const ttl, staleTTL, negativeTTL = time.Minute, 5 * time.Minute, 30 * time.Second
+
+    tests := []struct {
+        name      string
+        ticker    string
+        age       time.Duration
+        cachedErr error
+        wantPrice float64 // The cached quote is priced at 100, a refetched one at 1
+        wantErr   error
+        wantCalls int32
+        wantStale bool
+    }{
+        {"fresh", "ABC", ttl - time.Second, nil, 100, nil, 0, false},
+        {"stale, served while refreshing", "ABC", ttl + time.Second, nil, 100, nil, 1, true},
+        {"too stale, refetched", "ABC", ttl + staleTTL + time.Second, nil, 1, nil, 1, false},
+        {"no price, remembered", "ABC", negativeTTL - time.Second, ErrNoPrice, 0, ErrNoPrice, 0, false},
+        {"no price, expired and refetched", "ABC", negativeTTL + time.Second, ErrNoPrice, 1, nil, 1, false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            stub := &stubProvider{}
+            cache := NewCachedProvider(stub, ttl, staleTTL)
+            cache.NegativeTTL = negativeTTL
+
+            cached := cacheEntry{quote: Quote{Ticker: tt.ticker, Price: 100}, err: tt.cachedErr, fetched: time.Now().Add(-tt.age)}
+            if tt.cachedErr != nil {
+                cached.quote = Quote{}
+            }
+            cache.entries[tt.ticker] = cached
+
+            quote, err := cache.LatestQuote(context.Background(), tt.ticker)
+            if !errors.Is(err, tt.wantErr) || quote.Price != tt.wantPrice {
+                t.Fatalf("LatestQuote() = %v, %v, want price %v, error %v", quote.Price, err, tt.wantPrice, tt.wantErr)
+            }
+
+            waitFor(t, cache.idle)
+            if calls := stub.calls.Load(); calls != tt.wantCalls {
+                t.Errorf("provider called %d times, want %d", calls, tt.wantCalls)
+            }
+            if stale := cache.Stats().StaleHits == 1; stale != tt.wantStale {
+                t.Errorf("served stale = %v, want %v", stale, tt.wantStale)
+            }
+
+            // Whatever was fetched in the background replaces the cached entry
+            if tt.wantCalls > 0 {
+                quote, err := cache.LatestQuote(context.Background(), tt.ticker)
+                if err != nil || quote.Price != 1 {
+                    t.Errorf("LatestQuote() after refreshing = %v, %v, want the refreshed quote", quote.Price, err)
+                }
+            }
+        })
+    }
+}
+
+func TestCachedProviderEviction(t *testing.T) {
+    stub := &stubProvider{}
+    cache := NewCachedProvider(stub, time.Minute, time.Minute)
+    cache.MaxEntries = 2
+
+    for _, ticker := range []string{"AAA", "BBB", "CCC"} {
+        if _, err := cache.LatestQuote(context.Background(), ticker); err != nil {
+            t.Fatal(err)
+        }
+        time.Sleep(time.Millisecond)
+    }
+
+    stats := cache.Stats()
+    if stats.Entries != 2 || stats.Evictions != 1 {
+        t.Fatalf("stats %+v, want 2 entries after 1 eviction", stats)
+    }
+    if _, ok := cache.entries["AAA"]; ok {
+        t.Errorf("the oldest ticker was kept")
+    }
+
+    // An expired entry is dropped before the oldest live one
+    cache.entries["BBB"] = cacheEntry{quote: Quote{Ticker: "BBB"}, fetched: time.Now().Add(-time.Hour)}
+    if _, err := cache.LatestQuote(context.Background(), "DDD"); err != nil {
+        t.Fatal(err)
+    }
+    if _, ok := cache.entries["CCC"]; !ok || len(cache.entries) != 2 {
+        t.Errorf("entries %v, want CCC and DDD", cache.entries)
+    }
+}
@@ -0,0 +1,125 @@
+package prices
+// Path: prices/file.go
+
+import (
+    "context"
+    "encoding/csv"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// FileProvider serves prices from CSV files for offline use and fixtures.
+// Each ticker has a <TICKER>.csv file in the directory with "date,close" rows,
+// dates formatted as YYYY-MM-DD. A header row is optional.
+type FileProvider struct {
+    Dir string
+}
+
+// NewFileProviderFromEnv returns a FileProvider reading from PRICE_DATA_PATH, defaulting to ./data/prices
+func NewFileProviderFromEnv() (PriceProvider, error) {
+    dir := os.Getenv("PRICE_DATA_PATH")
+    if dir == "" {
+        dir = "./data/prices"
+    }
+    return &FileProvider{Dir: dir}, nil
+}
+
+// LatestQuote returns the last close in the ticker's file, with the close before it as the previous close
+func (p *FileProvider) LatestQuote(ctx context.Context, ticker string) (Quote, error) {
+    closes, err := p.readCloses(ticker)
+    if err != nil {
+        return Quote{}, err
+    }
+    if len(closes) == 0 {
+        return Quote{}, ErrNoPrice
+    }
+
+    last := closes[len(closes)-1]
+    quote := Quote{Ticker: ticker, Price: last.Close, Time: last.Date}
+    if len(closes) > 1 {
+        quote.PreviousClose = closes[len(closes)-2].Close
+    }
+    return quote, nil
+}
+
+// DailyCloses returns the closes in the ticker's file between from and to inclusive
+func (p *FileProvider) DailyCloses(ctx context.Context, ticker string, from time.Time, to time.Time) ([]DailyClose, error) {
+    closes, err := p.readCloses(ticker)
+    if errors.Is(err, ErrNoPrice) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    var result []DailyClose
+    for _, daily := range closes {
+        if !daily.Date.Before(from) && !daily.Date.After(to) {
+            result = append(result, daily)
+        }
+    }
+    return result, nil
+}
+
+// readCloses parses the ticker's file, returning ErrNoPrice if there is none
+func (p *FileProvider) readCloses(ticker string) ([]DailyClose, error) {
+    file, err := os.Open(filepath.Join(p.Dir, strings.ToUpper(filepath.Base(ticker))+".csv"))
+    if errors.Is(err, os.ErrNotExist) {
+        return nil, ErrNoPrice
+    }
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    closes, err := ParseClosesCSV(file)
+    if err != nil {
+        return nil, fmt.Errorf("%s prices: %w", ticker, err)
+    }
+    return closes, nil
+}
+
+// ParseClosesCSV reads "date,close" rows, skipping a header row if present, and returns them oldest first
+func ParseClosesCSV(r io.Reader) ([]DailyClose, error) {
+    reader := csv.NewReader(r)
+    reader.FieldsPerRecord = -1
+    reader.TrimLeadingSpace = true
+
+    var closes []DailyClose
+    for line := 1; ; line++ {
+        record, err := reader.Read()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return nil, err
+        }
+        if len(record) < 2 {
+            return nil, fmt.Errorf("line %d: expected date,close", line)
+        }
+
+        date, err := time.Parse("2006-01-02", strings.TrimSpace(record[0]))
+        if err != nil {
+            if line == 1 {
+                continue // Header row
+            }
+            return nil, fmt.Errorf("line %d: invalid date %q", line, record[0])
+        }
+
+        price, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+        if err != nil {
+            return nil, fmt.Errorf("line %d: invalid close %q", line, record[1])
+        }
+        closes = append(closes, DailyClose{Date: date, Close: price})
+    }
+
+    sort.Slice(closes, func(i, j int) bool { return closes[i].Date.Before(closes[j].Date) })
+    return closes, nil
+}
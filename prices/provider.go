@@ -0,0 +1,120 @@
+package prices
+// Path: prices/provider.go
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "strings"
+    "time"
+)
+
+// ErrNoPrice is returned when a provider has no price for a ticker
+var ErrNoPrice = errors.New("no price available")
+
+// Quote is the latest known price of a ticker
+type Quote struct {
+    Ticker        string    `json:"ticker"`
+    Price         float64   `json:"price"`
+    PreviousClose float64   `json:"previousClose"` // Zero when unknown
+    Time          time.Time `json:"time"`
+}
+
+// DailyClose is a ticker's closing price on a trading day
+type DailyClose struct {
+    Date  time.Time `json:"date"`
+    Close float64   `json:"close"`
+}
+
+// PriceProvider is a source of market prices
+type PriceProvider interface {
+    // LatestQuote returns the most recent price of the ticker
+    LatestQuote(ctx context.Context, ticker string) (Quote, error)
+    // DailyCloses returns the ticker's closes between from and to inclusive, oldest first
+    DailyCloses(ctx context.Context, ticker string, from time.Time, to time.Time) ([]DailyClose, error)
+}
+
+// providerFactories builds the providers selectable with the PRICE_PROVIDER variable
+var providerFactories = map[string]func() (PriceProvider, error){
+    "file":  NewFileProviderFromEnv,
+    "mongo": NewMongoProvider,
+    "none":  func() (PriceProvider, error) { return NoopProvider{}, nil },
+}
+
+var provider PriceProvider = NoopProvider{}
+
+// Setup selects the price provider named by PRICE_PROVIDER, defaulting to the file provider
+func Setup() {
+    name := strings.ToLower(os.Getenv("PRICE_PROVIDER"))
+    if name == "" {
+        name = "file"
+    }
+
+    selected, err := NewProvider(name)
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    ttl := durationFromEnv("QUOTE_CACHE_TTL", time.Minute)
+    staleTTL := durationFromEnv("QUOTE_CACHE_STALE_TTL", 5*time.Minute)
+    if ttl > 0 {
+        selected = NewCachedProvider(selected, ttl, staleTTL)
+    }
+
+    provider = selected
+    log.Printf("Using %s price provider with a %s quote cache", name, ttl)
+}
+
+// GetCacheStats returns the quote cache counters, or nil if quotes are not cached
+func GetCacheStats() *CacheStats {
+    cached, ok := provider.(*CachedProvider)
+    if !ok {
+        return nil
+    }
+    stats := cached.Stats()
+    return &stats
+}
+
+// durationFromEnv parses a duration such as "90s" from the environment variable, or returns the default
+func durationFromEnv(name string, defaultValue time.Duration) time.Duration {
+    value := os.Getenv(name)
+    if value == "" {
+        return defaultValue
+    }
+
+    duration, err := time.ParseDuration(value)
+    if err != nil {
+        log.Printf("Invalid %s %q, using %s", name, value, defaultValue)
+        return defaultValue
+    }
+    return duration
+}
+
+// NewProvider builds the named price provider
+func NewProvider(name string) (PriceProvider, error) {
+    factory, ok := providerFactories[name]
+    if !ok {
+        return nil, fmt.Errorf("unknown price provider: %q", name)
+    }
+    return factory()
+}
+
+// GetProvider returns the price provider chosen by Setup
+func GetProvider() PriceProvider {
+    return provider
+}
+
+// NoopProvider never has any prices, for deployments without market data
+type NoopProvider struct{}
+
+// LatestQuote always returns ErrNoPrice
+func (NoopProvider) LatestQuote(ctx context.Context, ticker string) (Quote, error) {
+    return Quote{}, ErrNoPrice
+}
+
+// DailyCloses always returns no closes
+func (NoopProvider) DailyCloses(ctx context.Context, ticker string, from time.Time, to time.Time) ([]DailyClose, error) {
+    return nil, nil
+}
@@ -0,0 +1,93 @@
+package prices
+// Path: prices/store.go
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "log"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "github.com/jalong4/stock-service-go/config"
+    "github.com/jalong4/stock-service-go/models"
+)
+
+// MongoProvider serves prices from the daily closes stored in the prices collection
+type MongoProvider struct{}
+
+// NewMongoProvider returns a provider backed by the prices collection
+func NewMongoProvider() (PriceProvider, error) {
+    return MongoProvider{}, nil
+}
+
+// LatestQuote returns the last stored close, with the close before it as the previous close
+func (MongoProvider) LatestQuote(ctx context.Context, ticker string) (Quote, error) {
+    latest, err := config.GetLatestDailyPrices(ticker, 2)
+    if err != nil {
+        return Quote{}, err
+    }
+    if len(latest) == 0 {
+        return Quote{}, ErrNoPrice
+    }
+
+    quote := Quote{Ticker: ticker, Price: latest[0].Close, Time: latest[0].Date}
+    if len(latest) > 1 {
+        quote.PreviousClose = latest[1].Close
+    }
+    return quote, nil
+}
+
+// DailyCloses returns the stored closes between from and to inclusive
+func (MongoProvider) DailyCloses(ctx context.Context, ticker string, from time.Time, to time.Time) ([]DailyClose, error) {
+    stored, err := config.GetDailyPrices(ticker, from, to)
+    if err != nil {
+        return nil, err
+    }
+
+    closes := make([]DailyClose, len(stored))
+    for i, price := range stored {
+        closes[i] = DailyClose{Date: price.Date, Close: price.Close}
+    }
+    return closes, nil
+}
+
+// ImportCSV stores the "date,close" rows read from r as the ticker's daily closes
+func ImportCSV(ticker string, r io.Reader) (int64, error) {
+    closes, err := ParseClosesCSV(r)
+    if err != nil {
+        return 0, err
+    }
+
+    dailyPrices := make([]models.DailyPrice, len(closes))
+    for i, daily := range closes {
+        dailyPrices[i] = models.DailyPrice{Ticker: ticker, Date: daily.Date, Close: daily.Close}
+    }
+    return config.UpsertDailyPrices(dailyPrices)
+}
+
+// ImportCSVDir stores every <TICKER>.csv file in dir, the same layout the file provider reads
+func ImportCSVDir(dir string) error {
+    files, err := filepath.Glob(filepath.Join(dir, "*.csv"))
+    if err != nil {
+        return err
+    }
+
+    for _, path := range files {
+        ticker := strings.ToUpper(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+
+        file, err := os.Open(path)
+        if err != nil {
+            return err
+        }
+        count, err := ImportCSV(ticker, file)
+        file.Close()
+        if err != nil {
+            return fmt.Errorf("%s: %w", path, err)
+        }
+        log.Printf("Imported %d daily prices for %s", count, ticker)
+    }
+    return nil
+}
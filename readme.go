@@ -14,6 +14,7 @@ type RouteInfo struct {
     Path         string
     Description  string
     RequiresAuth bool
+    RequiredRole string
 }
 
 type ReadmeData struct {
@@ -30,6 +31,7 @@ func GenerateReadme(router *gin.Engine) {
             Path:         route.Path,
             Description:  route.Description,
             RequiresAuth: route.RequiresAuth,
+            RequiredRole: route.RequiredRole,
         })
     }
 
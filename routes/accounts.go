@@ -0,0 +1,355 @@
+package routes
+
+// Path: routes/accounts.go
+import (
+    "fmt"
+    "log"
+    "net/http"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/jalong4/stock-service-go/config"
+    "github.com/jalong4/stock-service-go/models"
+    "github.com/jalong4/stock-service-go/portfolio"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+)
+
+// AccountRequest defines the structure of the request payload for creating or updating an account
+type AccountRequest struct {
+    Name            string `json:"name"`
+    Institution     string `json:"institution"`
+    Type            string `json:"type"`            // taxable, ira, rothIra, 401k or other, defaults to taxable
+    Currency        string `json:"currency"`        // Defaults to USD
+    CostBasisMethod string `json:"costBasisMethod"` // fifo, lifo, average or specificLot, defaults to fifo
+}
+
+// GetAccountsHandler handles requests to list the user's accounts
+func GetAccountsHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    accounts, err := config.GetAccounts(userID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve accounts"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "count":    len(accounts),
+        "accounts": accounts,
+    })
+}
+
+// AddAccountHandler handles requests to create an account
+func AddAccountHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    var req AccountRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+        return
+    }
+
+    account := models.Account{UserID: userID, CreatedAt: time.Now()}
+    if err := req.apply(&account); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    id, err := config.AddAccount(account)
+    if mongo.IsDuplicateKeyError(err) {
+        c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("You already have an account named %s", account.Name)})
+        return
+    }
+    if err != nil {
+        log.Printf("Failed to add account: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add account"})
+        return
+    }
+    account.ID, _ = primitive.ObjectIDFromHex(id)
+
+    c.JSON(http.StatusCreated, gin.H{
+        "message": fmt.Sprintf("Successfully added account %s with ID %s", account.Name, id),
+        "account": account,
+    })
+}
+
+// GetAccountByIDHandler handles requests to get an account by its ID
+func GetAccountByIDHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    id := c.Param("_id")
+    account, err := config.GetAccountByID(userID, id)
+    if isNotFound(err) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "No account found with ID: " + id})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve account"})
+        return
+    }
+
+    c.JSON(http.StatusOK, account)
+}
+
+// UpdateAccountHandler handles requests to update an account. Renaming it renames it on all of its records.
+func UpdateAccountHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    id := c.Param("_id")
+    account, err := config.GetAccountByID(userID, id)
+    if isNotFound(err) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "No account found with ID: " + id})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve account"})
+        return
+    }
+
+    var req AccountRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+        return
+    }
+
+    previousName := account.Name
+    previousCurrency := account.Currency
+    if err := req.apply(account); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    // Recorded amounts are in the account's currency, so it can only change while there are none
+    if account.Currency != previousCurrency {
+        inUse, err := config.IsAccountInUse(userID, account.ID)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check account usage"})
+            return
+        }
+        if inUse {
+            c.JSON(http.StatusConflict, gin.H{"error": "The currency of an account with holdings, transactions or cash flows cannot be changed"})
+            return
+        }
+    }
+
+    err = config.UpdateAccount(userID, previousName, *account)
+    if mongo.IsDuplicateKeyError(err) {
+        c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("You already have an account named %s", account.Name)})
+        return
+    }
+    if err != nil {
+        log.Printf("Failed to update account: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update account"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": fmt.Sprintf("Account %s updated successfully!", account.Name),
+        "account": account,
+    })
+}
+
+// DeleteAccountHandler handles requests to delete an account that has no holdings, transactions or cash flows
+func DeleteAccountHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    id := c.Param("_id")
+    account, err := config.GetAccountByID(userID, id)
+    if isNotFound(err) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "No account found with ID: " + id})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve account"})
+        return
+    }
+
+    inUse, err := config.IsAccountInUse(userID, account.ID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check account records"})
+        return
+    }
+    if inUse {
+        c.JSON(http.StatusConflict, gin.H{"error": "Account has holdings, transactions or cash flows and cannot be deleted"})
+        return
+    }
+
+    if _, err = config.DeleteAccountByID(userID, account.ID); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Account %s deleted successfully!", account.Name)})
+}
+
+// apply validates the request and copies it onto the account, filling in defaults for missing fields
+func (req AccountRequest) apply(account *models.Account) error {
+    account.Name = strings.TrimSpace(req.Name)
+    account.Institution = strings.TrimSpace(req.Institution)
+    account.Type = req.Type
+    account.Currency = strings.ToUpper(strings.TrimSpace(req.Currency))
+    account.CostBasisMethod = req.CostBasisMethod
+
+    if account.Name == "" {
+        return fmt.Errorf("Account name is required")
+    }
+    if account.Type == "" {
+        account.Type = models.AccountTypeTaxable
+    }
+    if !models.IsValidAccountType(account.Type) {
+        return fmt.Errorf("Invalid account type: %s", account.Type)
+    }
+    if account.Currency == "" {
+        account.Currency = models.DefaultCurrency
+    }
+    if !isCurrencyCode(account.Currency) {
+        return fmt.Errorf("Currency must be a three letter code")
+    }
+    if account.CostBasisMethod != "" && !models.IsValidCostBasisMethod(account.CostBasisMethod) {
+        return fmt.Errorf("Invalid cost basis method: %s", account.CostBasisMethod)
+    }
+    return nil
+}
+
+// resolveAccount finds one of the user's accounts by its ID or its name.
+// It responds with an error and returns false if there is no such account.
+func resolveAccount(c *gin.Context, userID primitive.ObjectID, ref string) (*models.Account, bool) {
+    ref = strings.TrimSpace(ref)
+
+    var account *models.Account
+    var err error
+    if _, hexErr := primitive.ObjectIDFromHex(ref); hexErr == nil {
+        account, err = config.GetAccountByID(userID, ref)
+    }
+    if account == nil {
+        account, err = config.GetAccountByName(userID, ref)
+    }
+
+    if isNotFound(err) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown account %q, create it with POST /accounts/ first", ref)})
+        return nil, false
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve account"})
+        return nil, false
+    }
+    return account, true
+}
+
+// queryAccountID resolves the optional ?account= filter, given by ID or exact name, to the account's ID.
+// It returns the nil ID when no account is given.
+func queryAccountID(c *gin.Context, userID primitive.ObjectID) (primitive.ObjectID, bool) {
+    ref := c.Query("account")
+    if strings.TrimSpace(ref) == "" {
+        return primitive.NilObjectID, true
+    }
+
+    account, ok := resolveAccount(c, userID, ref)
+    if !ok {
+        return primitive.NilObjectID, false
+    }
+    return account.ID, true
+}
+
+// GetCashBalancesHandler handles requests for the cash in each of the user's accounts
+func GetCashBalancesHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    balances, ok := cashBalances(c, userID)
+    if !ok {
+        return
+    }
+    currency, ok := loadReportingCurrency(c, userID)
+    if !ok {
+        return
+    }
+
+    // Balances are in their account's currency, the total in the base currency
+    total := 0.0
+    unconverted := 0
+    accounts := map[string]float64{}
+    currencies := map[string]string{}
+    for accountID, balance := range balances {
+        name := currency.accountName(accountID)
+        accounts[name] = balance
+        currencies[name] = currency.accountIDCurrency(accountID)
+        converted, ok := currency.converter.Convert(balance, currencies[name])
+        if !ok {
+            unconverted++
+            continue
+        }
+        total += converted
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "total":       portfolio.Round2(total),
+        "currency":    currency.converter.Base,
+        "unconverted": unconverted,
+        "accounts":    accounts,
+        "currencies":  currencies,
+    })
+}
+
+// cashBalances derives the cash in each of the user's accounts, by account ID, from their transactions and
+// cash flows. It responds with an error and returns false if they could not be retrieved.
+func cashBalances(c *gin.Context, userID primitive.ObjectID) (map[primitive.ObjectID]float64, bool) {
+    transactions, err := config.GetTransactions(userID, primitive.NilObjectID, "")
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve transactions"})
+        return nil, false
+    }
+    cashFlows, err := config.GetCashFlows(userID, primitive.NilObjectID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve cash flows"})
+        return nil, false
+    }
+
+    return portfolio.CashBalances(transactions, cashFlows), true
+}
+
+// addCashToSummary adds the cash in the accounts matching include to a holdings summary, along with the
+// total account value of cash plus the market value of the priced holdings. Totals are in the base currency,
+// and balances with no FX rate are counted as unconverted instead.
+func addCashToSummary(summary map[string]interface{}, valuation portfolio.Valuation, balances map[primitive.ObjectID]float64, currency reportingCurrency, include func(accountID primitive.ObjectID) bool) {
+    cash := 0.0
+    unconverted := 0
+    accountCash := map[string]float64{}
+    for accountID, balance := range balances {
+        if !include(accountID) {
+            continue
+        }
+        accountCash[currency.accountName(accountID)] = balance
+
+        converted, ok := currency.converter.Convert(balance, currency.accountIDCurrency(accountID))
+        if !ok {
+            unconverted++
+            continue
+        }
+        cash += converted
+    }
+
+    summary["unconverted"] = valuation.Unconverted + unconverted
+    summary["cash"] = portfolio.Round2(cash)
+    summary["accountCash"] = accountCash
+    summary["totalAccountValue"] = portfolio.Round2(valuation.TotalMarketValue + cash)
+}
@@ -0,0 +1,266 @@
+package routes
+
+// Path: routes/alerts.go
+import (
+    "fmt"
+    "log"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/jalong4/stock-service-go/config"
+    "github.com/jalong4/stock-service-go/jobs"
+    "github.com/jalong4/stock-service-go/models"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// defaultTriggerLimit is how many alert triggers are returned when ?limit= is not given
+const defaultTriggerLimit = 100
+
+// AlertRequest defines the structure of the request payload for creating or updating an alert
+type AlertRequest struct {
+    Type      string  `json:"type"`      // price, portfolioDayChange or positionWeight
+    Ticker    string  `json:"ticker"`    // Required for price and positionWeight alerts
+    Operator  string  `json:"operator"`  // above or below
+    Threshold float64 `json:"threshold"` // A price, or a percentage for portfolioDayChange and positionWeight
+    Notes     string  `json:"notes"`
+    Active    *bool   `json:"active"`    // Defaults to true
+    Email     *bool   `json:"email"`     // Email you when the alert fires, defaults to true
+}
+
+// GetAlertsHandler handles requests to list the user's alerts
+func GetAlertsHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    alerts, err := config.GetAlerts(userID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve alerts"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "count":  len(alerts),
+        "alerts": alerts,
+    })
+}
+
+// AddAlertHandler handles requests to create an alert
+func AddAlertHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    var req AlertRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+        return
+    }
+
+    alert := models.Alert{UserID: userID, CreatedAt: time.Now()}
+    if !req.apply(c, &alert) {
+        return
+    }
+
+    id, err := config.AddAlert(alert)
+    if err != nil {
+        log.Printf("Failed to add alert: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add alert"})
+        return
+    }
+    alert.ID, _ = primitive.ObjectIDFromHex(id)
+
+    c.JSON(http.StatusCreated, gin.H{
+        "message": fmt.Sprintf("Successfully added alert %s with ID %s", alert.Describe(), id),
+        "alert":   alert,
+    })
+}
+
+// GetAlertByIDHandler handles requests to get an alert by its ID
+func GetAlertByIDHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    id := c.Param("_id")
+    alert, err := config.GetAlertByID(userID, id)
+    if isNotFound(err) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "No alert found with ID: " + id})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve alert"})
+        return
+    }
+
+    c.JSON(http.StatusOK, alert)
+}
+
+// UpdateAlertHandler handles requests to update an alert. Changing its condition re-arms it.
+func UpdateAlertHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    id := c.Param("_id")
+    alert, err := config.GetAlertByID(userID, id)
+    if isNotFound(err) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "No alert found with ID: " + id})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve alert"})
+        return
+    }
+
+    var req AlertRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+        return
+    }
+
+    previous := alert.Describe()
+    if !req.apply(c, alert) {
+        return
+    }
+    if alert.Describe() != previous {
+        alert.Triggered = false
+        alert.LastValue = nil
+        alert.LastEvaluatedAt = nil
+    }
+
+    if err := config.UpdateAlert(userID, *alert); err != nil {
+        log.Printf("Failed to update alert: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update alert"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": fmt.Sprintf("Alert %s updated successfully!", alert.Describe()),
+        "alert":   alert,
+    })
+}
+
+// DeleteAlertHandler handles requests to delete an alert and its trigger history
+func DeleteAlertHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    id := c.Param("_id")
+    alert, err := config.GetAlertByID(userID, id)
+    if isNotFound(err) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "No alert found with ID: " + id})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve alert"})
+        return
+    }
+
+    if _, err = config.DeleteAlertByID(userID, alert.ID); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete alert"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Alert %s deleted successfully!", alert.Describe())})
+}
+
+// GetAlertTriggersHandler handles requests for the user's alert trigger history, newest first
+func GetAlertTriggersHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    alertID := primitive.NilObjectID
+    if value := c.Query("alertId"); value != "" {
+        parsed, err := primitive.ObjectIDFromHex(value)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alertId"})
+            return
+        }
+        alertID = parsed
+    }
+
+    limit := int64(defaultTriggerLimit)
+    if value := c.Query("limit"); value != "" {
+        parsed, err := strconv.ParseInt(value, 10, 64)
+        if err != nil || parsed <= 0 {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+            return
+        }
+        limit = parsed
+    }
+
+    triggers, err := config.GetAlertTriggers(userID, alertID, limit)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve alert triggers"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "count":    len(triggers),
+        "triggers": triggers,
+    })
+}
+
+// EvaluateAlertsHandler handles requests to evaluate every active alert now
+func EvaluateAlertsHandler(c *gin.Context) {
+    count, err := jobs.EvaluateAlerts(c.Request.Context())
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to evaluate alerts: %v", err)})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("%d alerts triggered", count)})
+}
+
+// apply validates the request and copies it onto the alert.
+// It responds with an error and returns false if the request is invalid.
+func (req AlertRequest) apply(c *gin.Context, alert *models.Alert) bool {
+    alert.Type = req.Type
+    alert.Ticker = strings.ToUpper(strings.TrimSpace(req.Ticker))
+    alert.Operator = req.Operator
+    alert.Threshold = req.Threshold
+    alert.Notes = strings.TrimSpace(req.Notes)
+    alert.Active = req.Active == nil || *req.Active
+    alert.Email = req.Email == nil || *req.Email
+
+    if !models.IsValidAlertType(alert.Type) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid alert type: %s", alert.Type)})
+        return false
+    }
+    if alert.Operator != models.AlertAbove && alert.Operator != models.AlertBelow {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Operator must be above or below"})
+        return false
+    }
+
+    if alert.Type == models.AlertPortfolioDayChange {
+        alert.Ticker = ""
+        return true
+    }
+    if alert.Ticker == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A ticker is required for %s alerts", alert.Type)})
+        return false
+    }
+    if alert.Type == models.AlertPrice && alert.Threshold <= 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Price threshold must be greater than zero"})
+        return false
+    }
+
+    ticker, ok := validateTicker(c, alert.Ticker)
+    if !ok {
+        return false
+    }
+    alert.Ticker = ticker
+    return true
+}
@@ -0,0 +1,147 @@
+package routes
+
+// Path: routes/allocation.go
+import (
+    "fmt"
+    "net/http"
+    "sort"
+
+    "github.com/gin-gonic/gin"
+    "github.com/jalong4/stock-service-go/config"
+    "github.com/jalong4/stock-service-go/models"
+    "github.com/jalong4/stock-service-go/portfolio"
+)
+
+// Groups for holdings missing from the security master and for account cash
+const (
+    unclassifiedGroup = "unclassified"
+    cashGroup         = portfolio.CashGroup
+)
+
+// allocationGroups returns the group of a holding for each ?by= of the allocation endpoint.
+// security is nil when the ticker is not in the security master.
+var allocationGroups = map[string]func(holding portfolio.ValuedHolding, security *models.Security) string{
+    "assetClass": func(holding portfolio.ValuedHolding, security *models.Security) string {
+        if security == nil {
+            return ""
+        }
+        return security.AssetClass
+    },
+    "sector": func(holding portfolio.ValuedHolding, security *models.Security) string {
+        if security == nil {
+            return ""
+        }
+        return security.Sector
+    },
+    "country": func(holding portfolio.ValuedHolding, security *models.Security) string {
+        if security == nil {
+            return ""
+        }
+        return security.Country
+    },
+    "account": func(holding portfolio.ValuedHolding, security *models.Security) string { return holding.Account },
+    "ticker":  func(holding portfolio.ValuedHolding, security *models.Security) string { return holding.Ticker },
+}
+
+// GetAllocationHandler handles requests for the weights of the portfolio's holdings and cash grouped by
+// ?by=assetClass|sector|country|account|ticker, in the user's base currency
+func GetAllocationHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    by := c.DefaultQuery("by", "assetClass")
+    groupOf, ok := allocationGroups[by]
+    if !ok {
+        c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid by %q, use assetClass, sector, country, account or ticker", by)})
+        return
+    }
+
+    holdings, err := config.GetAllHoldings(userID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve holdings"})
+        return
+    }
+    currency, ok := loadReportingCurrency(c, userID)
+    if !ok {
+        return
+    }
+    valued, valuation := valueHoldings(c, userID, holdings, currency)
+
+    var tickers []string
+    for _, holding := range holdings {
+        tickers = append(tickers, holding.Ticker)
+    }
+    securities, err := config.GetSecuritiesBySymbol(tickers)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve securities"})
+        return
+    }
+
+    var items []portfolio.AllocationItem
+    unclassified := map[string]bool{}
+    for _, holding := range valued {
+        if holding.BaseCostBasis == nil {
+            continue // No FX rate into the base currency
+        }
+
+        var security *models.Security
+        if found, ok := securities[holding.Ticker]; ok {
+            security = &found
+        }
+        group := groupOf(holding, security)
+        if group == "" {
+            group = unclassifiedGroup
+            unclassified[holding.Ticker] = true
+        }
+
+        items = append(items, portfolio.AllocationItem{
+            Group:       group,
+            MarketValue: holding.BaseMarketValue,
+            Cost:        *holding.BaseCostBasis,
+        })
+    }
+
+    // Cash is its own group, except when grouping by account
+    balances, ok := cashBalances(c, userID)
+    if !ok {
+        return
+    }
+    unconvertedCash := 0
+    for accountID, balance := range balances {
+        converted, ok := currency.converter.Convert(balance, currency.accountIDCurrency(accountID))
+        if !ok {
+            unconvertedCash++
+            continue
+        }
+        if converted == 0 {
+            continue
+        }
+
+        group := cashGroup
+        if by == "account" {
+            group = currency.accountName(accountID)
+        }
+        items = append(items, portfolio.AllocationItem{Group: group, MarketValue: &converted, Cost: converted})
+    }
+
+    weights, totalMarketValue, totalCost := portfolio.Allocate(items)
+
+    unclassifiedTickers := make([]string, 0, len(unclassified))
+    for ticker := range unclassified {
+        unclassifiedTickers = append(unclassifiedTickers, ticker)
+    }
+    sort.Strings(unclassifiedTickers)
+
+    c.JSON(http.StatusOK, gin.H{
+        "by":               by,
+        "currency":         valuation.Currency,
+        "totalMarketValue": portfolio.Round2(totalMarketValue),
+        "totalCost":        portfolio.Round2(totalCost),
+        "unpriced":         valuation.Unpriced,
+        "unconverted":      valuation.Unconverted + unconvertedCash,
+        "unclassified":     unclassifiedTickers, // Tickers missing from the security master or its field for by
+        "allocations":      weights,
+    })
+}
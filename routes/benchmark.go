@@ -0,0 +1,120 @@
+package routes
+
+// Path: routes/benchmark.go
+import (
+    "errors"
+    "log"
+    "net/http"
+    "os"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/jalong4/stock-service-go/config"
+    "github.com/jalong4/stock-service-go/portfolio"
+    "github.com/jalong4/stock-service-go/prices"
+)
+
+// BenchmarkRequest defines the structure of the request payload for choosing a benchmark
+type BenchmarkRequest struct {
+    Ticker string `json:"ticker"`
+}
+
+// defaultBenchmark is used for users who have not chosen a benchmark and when DEFAULT_BENCHMARK is not set
+const defaultBenchmark = "SPY"
+
+// GetBenchmarkHandler handles requests to compare the portfolio with a benchmark over one period
+// (?period=, default 1Y, or ?from=&to=), optionally against another ticker (?ticker=)
+func GetBenchmarkHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    ticker := strings.ToUpper(c.Query("ticker"))
+    if ticker == "" {
+        user, err := config.GetUserByID(userID.Hex())
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
+            return
+        }
+        ticker = userBenchmark(user.Benchmark)
+    }
+
+    customFrom, to, ok := dateRange(c, time.Now())
+    if !ok {
+        return
+    }
+
+    history, ok := loadPerformanceHistory(c, userID, to)
+    if !ok {
+        return
+    }
+    if history == nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "No transactions or cash flows recorded to compare"})
+        return
+    }
+
+    periods, ok := requestedPeriods(c, []string{"1Y"}, customFrom, history)
+    if !ok {
+        return
+    }
+    if len(periods) != 1 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Compare one period at a time"})
+        return
+    }
+    period := periods[0]
+
+    // Benchmark closes come from the prices collection, loaded with POST /prices/:ticker/import
+    closes, err := prices.MongoProvider{}.DailyCloses(c.Request.Context(), ticker, period.from.AddDate(0, 0, -10), to)
+    if err != nil && !errors.Is(err, prices.ErrNoPrice) {
+        log.Printf("Failed to retrieve daily closes for %s: %v", ticker, err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve benchmark prices"})
+        return
+    }
+    if len(closes) == 0 {
+        c.JSON(http.StatusNotFound, gin.H{"error": "No daily prices stored for benchmark " + ticker})
+        return
+    }
+
+    comparison := portfolio.CompareToBenchmark(history.overall, ticker, closes, period.from, to)
+
+    c.JSON(http.StatusOK, gin.H{
+        "period":     period.name,
+        "comparison": comparison,
+    })
+}
+
+// SetBenchmarkHandler handles requests to choose the ticker the user's performance is compared against
+func SetBenchmarkHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    var req BenchmarkRequest
+    if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Ticker) == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "A benchmark ticker is required"})
+        return
+    }
+    ticker := strings.ToUpper(strings.TrimSpace(req.Ticker))
+
+    if err := config.SetUserBenchmark(userID, ticker); err != nil {
+        log.Printf("Failed to set benchmark: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set benchmark"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Benchmark set to " + ticker, "benchmark": ticker})
+}
+
+// userBenchmark returns the user's chosen benchmark, or the configured default
+func userBenchmark(benchmark string) string {
+    if benchmark != "" {
+        return benchmark
+    }
+    if configured := os.Getenv("DEFAULT_BENCHMARK"); configured != "" {
+        return strings.ToUpper(configured)
+    }
+    return defaultBenchmark
+}
@@ -0,0 +1,374 @@
+package routes
+
+// Path: routes/corporateactions.go
+import (
+    "fmt"
+    "log"
+    "net/http"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/jalong4/stock-service-go/auth"
+    "github.com/jalong4/stock-service-go/config"
+    "github.com/jalong4/stock-service-go/models"
+    "github.com/jalong4/stock-service-go/portfolio"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+)
+
+// CorporateActionRequest defines the structure of the request payload for recording a corporate action
+type CorporateActionRequest struct {
+    Type           string  `json:"type"` // split, reverseSplit, rename, spinOff or cashMerger
+    Ticker         string  `json:"ticker"`
+    NewTicker      string  `json:"newTicker"`      // Renames and spin-offs
+    Date           string  `json:"date"`           // Effective date, YYYY-MM-DD
+    Ratio          float64 `json:"ratio"`          // New shares per old share, e.g. 2 for a 2-for-1 split or 0.1 for a 1-for-10 reverse split
+    CostAllocation float64 `json:"costAllocation"` // Spin-offs only, fraction of the cost basis moved to the new ticker
+    CashPerShare   float64 `json:"cashPerShare"`   // Cash mergers only
+    Notes          string  `json:"notes"`
+}
+
+// CorporateActionFailure is a position a corporate action could not be applied to
+type CorporateActionFailure struct {
+    UserID  primitive.ObjectID `json:"userId"`
+    Account string             `json:"account"`
+    Error   string             `json:"error"`
+}
+
+// AddCorporateActionHandler records a corporate action and applies it to every user's holdings of the ticker
+func AddCorporateActionHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    var req CorporateActionRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+        return
+    }
+
+    action, err := req.toCorporateAction(userID)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    id, err := config.AddCorporateAction(action)
+    if mongo.IsDuplicateKeyError(err) {
+        c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("A %s of %s effective %s has already been recorded",
+            action.Type, action.Ticker, action.Date.Format("2006-01-02"))})
+        return
+    }
+    if err != nil {
+        log.Printf("Failed to record corporate action: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record corporate action"})
+        return
+    }
+    action.ID, _ = primitive.ObjectIDFromHex(id)
+
+    adjustments, failures, err := applyCorporateAction(&action)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply corporate action"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, gin.H{
+        "message":         fmt.Sprintf("Recorded %s of %s with ID %s, adjusted %d positions", action.Type, action.Ticker, id, len(adjustments)),
+        "corporateAction": action,
+        "adjustments":     adjustments,
+        "failures":        failures,
+    })
+}
+
+// ApplyCorporateActionHandler applies a corporate action again to the positions it has not adjusted yet,
+// after the failures reported when it was recorded have been fixed
+func ApplyCorporateActionHandler(c *gin.Context) {
+    id := c.Param("_id")
+    action, err := config.GetCorporateActionByID(id)
+    if isNotFound(err) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "No corporate action found with ID: " + id})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve corporate action"})
+        return
+    }
+
+    adjustments, failures, err := applyCorporateAction(action)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply corporate action"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message":         fmt.Sprintf("Applied %s of %s, adjusted %d more positions", action.Type, action.Ticker, len(adjustments)),
+        "corporateAction": action,
+        "adjustments":     adjustments,
+        "failures":        failures,
+    })
+}
+
+// GetCorporateActionsHandler handles requests to list corporate actions, optionally for a ticker (?ticker=)
+func GetCorporateActionsHandler(c *gin.Context) {
+    actions, err := config.GetCorporateActions(strings.ToUpper(c.Query("ticker")))
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve corporate actions"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "count":            len(actions),
+        "corporateActions": actions,
+    })
+}
+
+// GetCorporateActionAdjustmentsHandler handles requests for what a corporate action changed.
+// Admins see every user's positions, everyone else only their own.
+func GetCorporateActionAdjustmentsHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    id := c.Param("_id")
+    action, err := config.GetCorporateActionByID(id)
+    if isNotFound(err) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "No corporate action found with ID: " + id})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve corporate action"})
+        return
+    }
+
+    if auth.IsAdmin(c) {
+        userID = primitive.NilObjectID
+    }
+    adjustments, err := config.GetCorporateActionAdjustments(action.ID, userID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve corporate action adjustments"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "corporateAction": action,
+        "count":           len(adjustments),
+        "adjustments":     adjustments,
+    })
+}
+
+// toCorporateAction validates the request and converts it to a corporate action
+func (req CorporateActionRequest) toCorporateAction(createdBy primitive.ObjectID) (models.CorporateAction, error) {
+    action := models.CorporateAction{
+        Type:           req.Type,
+        Ticker:         strings.ToUpper(strings.TrimSpace(req.Ticker)),
+        NewTicker:      strings.ToUpper(strings.TrimSpace(req.NewTicker)),
+        Ratio:          req.Ratio,
+        CostAllocation: req.CostAllocation,
+        CashPerShare:   req.CashPerShare,
+        Notes:          req.Notes,
+        CreatedBy:      createdBy,
+        CreatedAt:      time.Now(),
+    }
+
+    if !models.IsValidCorporateActionType(action.Type) {
+        return action, fmt.Errorf("Invalid corporate action type: %q", req.Type)
+    }
+    if action.Ticker == "" || req.Date == "" {
+        return action, fmt.Errorf("Ticker and date are required")
+    }
+
+    date, err := parseDate(req.Date)
+    if err != nil {
+        return action, err
+    }
+    action.Date = date
+
+    needsNewTicker := action.Type == models.CorporateActionRename || action.Type == models.CorporateActionSpinOff
+    if needsNewTicker != (action.NewTicker != "") || action.NewTicker == action.Ticker {
+        return action, fmt.Errorf("A different new ticker is required for, and only for, renames and spin-offs")
+    }
+
+    switch action.Type {
+    case models.CorporateActionSplit:
+        if action.Ratio <= 1 {
+            return action, fmt.Errorf("A split needs a ratio of new shares per share above 1")
+        }
+    case models.CorporateActionReverseSplit:
+        if action.Ratio <= 0 || action.Ratio >= 1 {
+            return action, fmt.Errorf("A reverse split needs a ratio of new shares per share between 0 and 1")
+        }
+    case models.CorporateActionSpinOff:
+        if action.Ratio <= 0 || action.CostAllocation <= 0 || action.CostAllocation >= 1 {
+            return action, fmt.Errorf("A spin-off needs a positive ratio and a cost allocation between 0 and 1")
+        }
+    case models.CorporateActionCashMerger:
+        if action.CashPerShare <= 0 {
+            return action, fmt.Errorf("A cash merger needs a positive cash per share")
+        }
+    }
+
+    return action, nil
+}
+
+// applyCorporateAction records the action's ledger entries in every position that held the ticker before
+// it took effect and rebuilds the affected holdings. Positions already adjusted are skipped, and positions
+// that cannot be adjusted are reported as failures and left unchanged. The action is marked applied once
+// no position is left to adjust.
+func applyCorporateAction(action *models.CorporateAction) ([]models.CorporateActionAdjustment, []CorporateActionFailure, error) {
+    positions, err := config.GetPositionsTradedBefore(action.Ticker, action.Date)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    // Holdings entered before the ledger existed have no transactions to find them by
+    holdings, err := config.GetHoldingsForTicker(action.Ticker)
+    if err != nil {
+        return nil, nil, err
+    }
+    for _, holding := range holdings {
+        positions = append(positions, config.LedgerPosition{UserID: holding.UserID, AccountID: holding.AccountID, Account: holding.Account})
+    }
+
+    seen := map[primitive.ObjectID]bool{}
+
+    adjustments := []models.CorporateActionAdjustment{}
+    failures := []CorporateActionFailure{}
+    for _, p := range positions {
+        if seen[p.AccountID] {
+            continue
+        }
+        seen[p.AccountID] = true
+
+        adjustment, err := applyCorporateActionToPosition(*action, p.UserID, p.AccountID, p.Account)
+        if err != nil {
+            log.Printf("Failed to apply corporate action %s to %s in %s: %v", action.ID.Hex(), action.Ticker, p.Account, err)
+            failures = append(failures, CorporateActionFailure{UserID: p.UserID, Account: p.Account, Error: err.Error()})
+            continue
+        }
+        if adjustment != nil {
+            adjustments = append(adjustments, *adjustment)
+        }
+    }
+
+    if len(failures) == 0 && action.AppliedAt == nil {
+        appliedAt := time.Now()
+        if err = config.SetCorporateActionApplied(action.ID, appliedAt); err != nil {
+            return nil, nil, err
+        }
+        action.AppliedAt = &appliedAt
+    }
+
+    return adjustments, failures, nil
+}
+
+// applyCorporateActionToPosition applies the action to one position. It returns nil if the position was
+// opened after the action took effect or has already been adjusted.
+func applyCorporateActionToPosition(action models.CorporateAction, userID primitive.ObjectID, accountID primitive.ObjectID, account string) (*models.CorporateActionAdjustment, error) {
+    transactions, err := positionTransactions(userID, accountID, action.Ticker)
+    if err != nil {
+        return nil, err
+    }
+    for _, t := range transactions {
+        if t.CorporateActionID == action.ID {
+            return nil, nil
+        }
+    }
+
+    before, err := portfolio.BuildPosition(transactions)
+    if err != nil {
+        return nil, err
+    }
+    lots, err := portfolio.LotsAt(transactions, action.Date)
+    if err != nil {
+        return nil, err
+    }
+    if len(lots) == 0 {
+        return nil, nil
+    }
+
+    entries, newEntries := portfolio.CorporateActionTransactions(action, userID, account, lots)
+    for i := range entries {
+        entries[i].AccountID = accountID
+    }
+    for i := range newEntries {
+        newEntries[i].AccountID = accountID
+    }
+
+    // Check both ledgers replay before recording anything
+    if _, err = portfolio.BuildPosition(append(transactions, entries...)); err != nil {
+        return nil, err
+    }
+    var newTransactions []models.Transaction
+    if len(newEntries) > 0 {
+        newTransactions, err = positionTransactions(userID, accountID, action.NewTicker)
+        if err != nil {
+            return nil, err
+        }
+        if _, err = portfolio.BuildPosition(append(newTransactions, newEntries...)); err != nil {
+            return nil, err
+        }
+    }
+
+    adjustment := models.CorporateActionAdjustment{
+        CorporateActionID: action.ID,
+        UserID:            userID,
+        Account:           account,
+        AccountID:         accountID,
+        Ticker:            action.Ticker,
+        QuantityBefore:    before.Quantity,
+        CostBefore:        portfolio.Round2(before.TotalCost),
+        NewTicker:         action.NewTicker,
+        TransactionIDs:    []primitive.ObjectID{},
+        CreatedAt:         time.Now(),
+    }
+
+    // Both ledgers' entries are written together, so a position is either adjusted or left for a retry
+    ids, err := config.AddTransactions(append(append([]models.Transaction{}, entries...), newEntries...))
+    if err != nil {
+        return nil, err
+    }
+    adjustment.TransactionIDs = ids
+    for i := range entries {
+        entries[i].ID = ids[i]
+    }
+    for i := range newEntries {
+        newEntries[i].ID = ids[len(entries)+i]
+    }
+
+    after, err := portfolio.BuildPosition(append(transactions, entries...))
+    if err != nil {
+        return nil, err
+    }
+    if _, err = savePosition(userID, accountID, account, action.Ticker, after); err != nil {
+        return nil, err
+    }
+    adjustment.QuantityAfter = after.Quantity
+    adjustment.CostAfter = portfolio.Round2(after.TotalCost)
+
+    if len(newEntries) > 0 {
+        newPosition, err := portfolio.BuildPosition(append(newTransactions, newEntries...))
+        if err != nil {
+            return nil, err
+        }
+        if _, err = savePosition(userID, accountID, account, action.NewTicker, newPosition); err != nil {
+            return nil, err
+        }
+        for _, entry := range newEntries {
+            adjustment.NewQuantity += entry.Quantity
+            adjustment.NewCost += entry.Quantity * entry.Price
+        }
+        adjustment.NewCost = portfolio.Round2(adjustment.NewCost)
+    }
+
+    if action.Type == models.CorporateActionCashMerger {
+        adjustment.CashProceeds = portfolio.Round2(entries[0].Quantity * entries[0].Price)
+    }
+
+    if err = config.AddCorporateActionAdjustment(adjustment); err != nil {
+        return nil, err
+    }
+    return &adjustment, nil
+}
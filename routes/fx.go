@@ -0,0 +1,153 @@
+package routes
+
+// Path: routes/fx.go
+import (
+    "errors"
+    "fmt"
+    "log"
+    "net/http"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/jalong4/stock-service-go/config"
+    "github.com/jalong4/stock-service-go/fx"
+    "github.com/jalong4/stock-service-go/models"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BaseCurrencyRequest defines the structure of the request payload for choosing the reporting currency
+type BaseCurrencyRequest struct {
+    Currency string `json:"currency"`
+}
+
+// GetFxRateHandler handles requests for the value of one unit of :base in :quote (?date=, defaults to today)
+func GetFxRateHandler(c *gin.Context) {
+    base := strings.ToUpper(c.Param("base"))
+    quote := strings.ToUpper(c.Param("quote"))
+
+    date := time.Now()
+    if value := c.Query("date"); value != "" {
+        parsed, err := parseDate(value)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        date = parsed
+    }
+
+    rate, err := fx.ConversionRate(c.Request.Context(), fx.GetProvider(), base, quote, date)
+    if errors.Is(err, fx.ErrNoRate) {
+        c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("No FX rate available for %s/%s", base, quote)})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve FX rate"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "base":  base,
+        "quote": quote,
+        "date":  date.Format("2006-01-02"),
+        "rate":  rate,
+    })
+}
+
+// ImportFxRatesHandler stores FX rates from a CSV request body of "date,base,quote,rate" rows
+func ImportFxRatesHandler(c *gin.Context) {
+    count, err := fx.ImportCSV(c.Request.Body)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to import FX rates: %v", err)})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Imported %d FX rates", count)})
+}
+
+// SetBaseCurrencyHandler handles requests to choose the currency the user's summaries are reported in
+func SetBaseCurrencyHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    var req BaseCurrencyRequest
+    if err := c.ShouldBindJSON(&req); err != nil || !isCurrencyCode(strings.TrimSpace(req.Currency)) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "A three letter currency code is required"})
+        return
+    }
+    currency := strings.ToUpper(strings.TrimSpace(req.Currency))
+
+    if err := config.SetUserBaseCurrency(userID, currency); err != nil {
+        log.Printf("Failed to set base currency: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set base currency"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Base currency set to " + currency, "baseCurrency": currency})
+}
+
+// reportingCurrency converts a user's amounts from their accounts' currencies into their base currency
+type reportingCurrency struct {
+    converter fx.Converter
+    byID      map[primitive.ObjectID]string // Currency of each account by ID, for records of an account
+    names     map[primitive.ObjectID]string // Current name of each account by ID, for labelling amounts by account
+}
+
+// loadReportingCurrency fetches the user's base currency and today's rates for their accounts' currencies.
+// It responds with an error and returns false if they could not be retrieved.
+func loadReportingCurrency(c *gin.Context, userID primitive.ObjectID) (reportingCurrency, bool) {
+    user, err := config.GetUserByID(userID.Hex())
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
+        return reportingCurrency{}, false
+    }
+
+    accounts, err := config.GetAccounts(userID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve accounts"})
+        return reportingCurrency{}, false
+    }
+
+    currency := reportingCurrency{byID: map[primitive.ObjectID]string{}, names: map[primitive.ObjectID]string{}}
+    var currencies []string
+    for _, account := range accounts {
+        currency.byID[account.ID] = account.Currency
+        currency.names[account.ID] = account.Name
+        currencies = append(currencies, account.Currency)
+    }
+    currency.converter = fx.NewConverter(c.Request.Context(), fx.GetProvider(), user.GetBaseCurrency(), currencies, time.Now())
+
+    return currency, true
+}
+
+// accountIDCurrency returns the currency of the account with the given ID
+func (r reportingCurrency) accountIDCurrency(accountID primitive.ObjectID) string {
+    if currency, ok := r.byID[accountID]; ok && currency != "" {
+        return currency
+    }
+    return models.DefaultCurrency
+}
+
+// accountName returns the current name of the account with the given ID, or the ID itself for records
+// whose account no longer exists
+func (r reportingCurrency) accountName(accountID primitive.ObjectID) string {
+    if name, ok := r.names[accountID]; ok {
+        return name
+    }
+    return accountID.Hex()
+}
+
+// isCurrencyCode reports whether code looks like a three letter ISO 4217 currency code
+func isCurrencyCode(code string) bool {
+    if len(code) != 3 {
+        return false
+    }
+    for _, r := range strings.ToUpper(code) {
+        if r < 'A' || r > 'Z' {
+            return false
+        }
+    }
+    return true
+}
@@ -7,10 +7,15 @@ import (
 	"log"
 	"math"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jalong4/stock-service-go/config"
 	"github.com/jalong4/stock-service-go/models"
+	"github.com/jalong4/stock-service-go/portfolio"
+	"github.com/jalong4/stock-service-go/prices"
+	"github.com/jalong4/stock-service-go/webhooks"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Response struct {
@@ -18,37 +23,58 @@ type Response struct {
 	Holdings interface{} `json:"holdings"`
 }
 
+// SellRequest defines the structure of the request payload for selling part or all of a holding
+type SellRequest struct {
+	Quantity        float64 `json:"quantity"`
+	Price           float64 `json:"price"`
+	Fees            float64 `json:"fees"`
+	Date            string  `json:"date"`            // YYYY-MM-DD or RFC3339, defaults to now
+	CostBasisMethod string  `json:"costBasisMethod"` // Defaults to the account's method
+	LotID           string  `json:"lotId"`           // Required with the specificLot method
+}
+
 // GetAllHoldingsHandler handles requests to get all holdings
 func GetAllHoldingsHandler(c *gin.Context) {
-    holdings, err := config.GetAllHoldings()
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    holdings, err := config.GetAllHoldings(userID)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve holdings"})
         return
     }
 
-    // Calculating the total cost and organizing the summary
-    totalCost := 0.0
-    for _, holding := range holdings {
-        totalCost += holding.TotalCost
+    currency, ok := loadReportingCurrency(c, userID)
+    if !ok {
+        return
     }
 
-	// Round totalCost to two decimal places
-	totalCost = math.Round(totalCost*100) / 100
+    // Price the holdings and total their cost and market value for the summary
+    valued, valuation := valueHoldings(c, userID, holdings, currency)
 
-    summary := map[string]interface{}{
-        "found": len(holdings),
-        "totalCost": totalCost,
+    balances, ok := cashBalances(c, userID)
+    if !ok {
+        return
     }
+    summary := valuation.Summary(len(holdings))
+    addCashToSummary(summary, valuation, balances, currency, func(primitive.ObjectID) bool { return true })
 
     response := Response {
         Summary: summary,
-        Holdings: holdings,
+        Holdings: valued,
     }
 
     c.JSON(http.StatusOK, response)
 }
 
 func AddHoldingsHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
     var input map[string]interface{}
 
     // Bind JSON to a map to check for unknown fields
@@ -91,21 +117,46 @@ func AddHoldingsHandler(c *gin.Context) {
         c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
         return
     }
+    if holding.Ticker == "" || holding.Account == "" || holding.Quantity <= 0 || holding.TotalCost < 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Ticker, account and a positive quantity are required"})
+        return
+    }
 
-    // Add the holding to the database
-    newId, err := config.AddHolding(holding)
-    if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add holding"})
+    // Catch typos before they become holdings
+    holding.Ticker, ok = validateTicker(c, holding.Ticker)
+    if !ok {
         return
     }
 
-	c.JSON(http.StatusCreated, gin.H{"message": fmt.Sprintf("Successfully added holdings for ticker %s with ID %s", holding.Ticker, newId)})
+    // Holdings are derived from the ledger, so a new holding is recorded as shares transferred in at their cost
+    transaction := models.Transaction{
+        UserID:    userID,
+        Account:   holding.Account,
+        Ticker:    holding.Ticker,
+        Type:      models.TransactionTransferIn,
+        Date:      time.Now(),
+        Quantity:  holding.Quantity,
+        Price:     holding.TotalCost / holding.Quantity,
+        CreatedAt: time.Now(),
+    }
+
+    _, _, newHolding, ok := recordTransaction(c, transaction)
+    if !ok {
+        return
+    }
+
+	c.JSON(http.StatusCreated, gin.H{"message": fmt.Sprintf("Successfully added holdings for ticker %s with ID %s", newHolding.Ticker, newHolding.ID.Hex())})
 }
 
 // GetHoldingsByTickerHandler handles requests to get holdings by ticker
 func GetHoldingsByTickerHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
     ticker := c.Param("ticker")
-    holdings, err := config.GetHoldingsByTicker(ticker)
+    holdings, err := config.GetHoldingsByTicker(userID, ticker)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve holdings"})
         return
@@ -116,44 +167,78 @@ func GetHoldingsByTickerHandler(c *gin.Context) {
         return
     }
 
-    c.JSON(http.StatusOK, holdings)
+    currency, ok := loadReportingCurrency(c, userID)
+    if !ok {
+        return
+    }
+
+    valued, _ := valueHoldings(c, userID, holdings, currency)
+
+    c.JSON(http.StatusOK, valued)
 }
 
-// GetHoldingsByAccountHandler handles requests to get holdings by account regex pattern
+// GetHoldingsByAccountHandler handles requests to get the holdings in one account, given by its ID or exact name
 func GetHoldingsByAccountHandler(c *gin.Context) {
-    accountPattern := c.Param("account")
-    holdings, err := config.GetHoldingsByAccount(accountPattern)
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    ref := c.Param("account")
+    account, err := config.GetAccountByID(userID, ref)
+    if isNotFound(err) {
+        account, err = config.GetAccountByName(userID, ref)
+    }
+    if isNotFound(err) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "No account found named " + ref})
+        return
+    }
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve holdings"})
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve account"})
         return
     }
 
-    if len(holdings) == 0 {
-        c.JSON(http.StatusNotFound, gin.H{"message": "No holdings found for the given account pattern"})
+    holdings, err := config.GetHoldingsByAccount(userID, account.ID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve holdings"})
         return
     }
 
-    totalCost := 0.0
-    for _, holding := range holdings {
-        totalCost += holding.TotalCost
+    currency, ok := loadReportingCurrency(c, userID)
+    if !ok {
+        return
     }
-    totalCost = math.Round(totalCost*100) / 100  // Round total cost to two decimal places
 
-    summary := map[string]interface{}{
-        "found": len(holdings),
-        "totalCost": totalCost,
+    valued, valuation := valueHoldings(c, userID, holdings, currency)
+
+    // The account's cash is part of its value even when it has no holdings
+    balances, ok := cashBalances(c, userID)
+    if !ok {
+        return
     }
+    summary := valuation.Summary(len(holdings))
+    addCashToSummary(summary, valuation, balances, currency, func(accountID primitive.ObjectID) bool { return accountID == account.ID })
+
     response := Response {
         Summary: summary,
-        Holdings: holdings,
+        Holdings: valued,
     }
 
     c.JSON(http.StatusOK, response)
 }
 
 func GetHoldingByIDHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
 	id := c.Param("_id")
-	holding, err := config.GetHoldingByID(id)
+	holding, err := config.GetHoldingByID(userID, id)
+	if isNotFound(err) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No holding found with ID: " + id})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve holding"})
 		return
@@ -163,18 +248,49 @@ func GetHoldingByIDHandler(c *gin.Context) {
 }
 
 func DeleteHoldingHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
 	id := c.Param("_id")
 
-	holding, err := config.GetHoldingByID(id)
+	holding, err := config.GetHoldingByID(userID, id)
+	if isNotFound(err) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No holding found with ID: " + id})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find holding with ID: " + id})
 		return
 	}
 
-	// Delete the holding from the database
-	_, err = config.DeleteHoldingByID(id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete holding"})
+	// Empty holdings from before the ledger existed have nothing to close
+	if holding.Quantity <= 0 {
+		if _, err = config.DeleteHoldingByID(userID, id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete holding"})
+			return
+		}
+		webhooks.Publish(userID, models.EventHoldingDeleted, holding)
+		c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Holdings for %s deleted successfully!", holding.Ticker)})
+		return
+	}
+
+	// The position is closed with a transfer out of the remaining shares rather than by deleting its ledger,
+	// so its sales, income and corporate actions stay in the account's cash, gains and performance history
+	transaction := models.Transaction{
+		UserID:    userID,
+		Account:   holding.Account,
+		Ticker:    holding.Ticker,
+		Type:      models.TransactionTransferOut,
+		Date:      time.Now(),
+		Quantity:  holding.Quantity,
+		Price:     holding.CostBasis() / holding.Quantity,
+		Notes:     fmt.Sprintf("Closed by deleting holding %s", holding.ID.Hex()),
+		CreatedAt: time.Now(),
+	}
+
+	if _, _, _, ok := recordTransaction(c, transaction); !ok {
 		return
 	}
 
@@ -182,11 +298,20 @@ func DeleteHoldingHandler(c *gin.Context) {
 }
 
 func UpdateHoldingHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
 	id := c.Param("_id")
 
 	log.Printf("Updating Holding ID: %s", id)
 
-	holding, err := config.GetHoldingByID(id)
+	holding, err := config.GetHoldingByID(userID, id)
+	if isNotFound(err) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No holding found with ID: " + id})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find holding with ID: " + id})
 		return
@@ -194,19 +319,142 @@ func UpdateHoldingHandler(c *gin.Context) {
 
 	log.Printf("Updating Holding: %s", holding.Ticker)
 
+	// Holdings with a ledger are derived from their transactions and cannot be edited by hand
+	transactions, err := config.GetTransactions(userID, holding.AccountID, holding.Ticker)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve holding transactions"})
+		return
+	}
+	if len(transactions) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Holding is derived from its transactions, record a transaction via POST /transactions/ instead"})
+		return
+	}
+
 	var updatedHolding models.Holding
 	if err = c.ShouldBindJSON(&updatedHolding); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
 		return
 	}
 
+	// Keep the holding linked to an existing account
+	if updatedHolding.Account == "" {
+		updatedHolding.Account = holding.Account
+	}
+	account, ok := resolveAccount(c, userID, updatedHolding.Account)
+	if !ok {
+		return
+	}
+	updatedHolding.Account = account.Name
+	updatedHolding.AccountID = account.ID
+
 	// Update the holding in the database
-	_, err = config.UpdateHoldingByID(id, updatedHolding)
+	_, err = config.UpdateHoldingByID(userID, id, updatedHolding)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update holding"})
 		return
 	}
+	updatedHolding.ID = holding.ID
+	updatedHolding.UserID = userID
+	webhooks.Publish(userID, models.EventHoldingUpdated, updatedHolding)
 
 	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Holdings for %s updated successfully!", holding.Ticker)})
 
-}
\ No newline at end of file
+}
+
+// SellHoldingHandler sells shares of a holding, consuming lots with the account's cost basis method
+func SellHoldingHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	id := c.Param("_id")
+	holding, err := config.GetHoldingByID(userID, id)
+	if isNotFound(err) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No holding found with ID: " + id})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find holding with ID: " + id})
+		return
+	}
+
+	var req SellRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+		return
+	}
+
+	transactionReq := TransactionRequest{
+		Account:         holding.Account,
+		Ticker:          holding.Ticker,
+		Type:            models.TransactionSell,
+		Date:            req.Date,
+		Quantity:        req.Quantity,
+		Price:           req.Price,
+		Fees:            req.Fees,
+		CostBasisMethod: req.CostBasisMethod,
+		LotID:           req.LotID,
+	}
+	transaction, err := transactionReq.toTransaction(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !resolveCostBasisMethod(c, &transaction) {
+		return
+	}
+
+	recorded, position, remaining, ok := recordTransaction(c, transaction)
+	if !ok {
+		return
+	}
+
+	// Report the lots consumed by this sale
+	var sold portfolio.Disposal
+	for _, disposal := range position.Disposals {
+		if disposal.Transaction.ID == recorded.ID {
+			sold = disposal
+		}
+	}
+
+	proceeds := recorded.Quantity*recorded.Price - recorded.Fees
+	basis := sold.Basis()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Sold %v shares of %s", recorded.Quantity, recorded.Ticker),
+		"sale": gin.H{
+			"transactionId":   recorded.ID.Hex(),
+			"costBasisMethod": recorded.CostBasisMethod,
+			"quantity":        recorded.Quantity,
+			"proceeds":        math.Round(proceeds*100) / 100,
+			"basis":           math.Round(basis*100) / 100,
+			"realizedGain":    math.Round((proceeds-basis)*100) / 100,
+			"lots":            sold.Lots,
+		},
+		"holding": remaining, // null once the position is closed
+	})
+}
+
+// valueHoldings prices the holdings, totals them in the user's base currency and adds their trailing
+// twelve month yield on cost
+func valueHoldings(c *gin.Context, userID primitive.ObjectID, holdings []models.Holding, currency reportingCurrency) ([]portfolio.ValuedHolding, portfolio.Valuation) {
+	// Holdings saved before they carried a currency are in their account's
+	for i := range holdings {
+		if holdings[i].Currency == "" {
+			holdings[i].Currency = currency.accountIDCurrency(holdings[i].AccountID)
+		}
+	}
+
+	valued, valuation := portfolio.ValueHoldings(c.Request.Context(), prices.GetProvider(), holdings, currency.converter)
+
+	now := time.Now()
+	income, err := config.GetIncomeTransactions(userID, primitive.NilObjectID, now.AddDate(-1, 0, 0), now)
+	if err != nil {
+		log.Printf("Failed to retrieve income for yield on cost: %v", err)
+		return valued, valuation
+	}
+	portfolio.AddYieldOnCost(valued, income)
+
+	return valued, valuation
+}
@@ -0,0 +1,115 @@
+package routes
+
+// Path: routes/income.go
+import (
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/jalong4/stock-service-go/config"
+    "github.com/jalong4/stock-service-go/models"
+    "github.com/jalong4/stock-service-go/portfolio"
+)
+
+// IncomeTotals sums dividends and interest
+type IncomeTotals struct {
+    Gross          float64 `json:"gross"`
+    WithholdingTax float64 `json:"withholdingTax"`
+    Net            float64 `json:"net"`
+    Reinvested     float64 `json:"reinvested"` // Net income used to buy shares
+    Dividends      float64 `json:"dividends"`  // Gross
+    Interest       float64 `json:"interest"`   // Gross
+}
+
+// add includes a dividend or interest payment in the totals
+func (t *IncomeTotals) add(transaction models.Transaction) {
+    t.Gross += transaction.Amount
+    t.WithholdingTax += transaction.WithholdingTax
+    t.Net += transaction.NetIncome()
+    if transaction.Reinvested {
+        t.Reinvested += transaction.NetIncome()
+    }
+    if transaction.Type == models.TransactionDividend {
+        t.Dividends += transaction.Amount
+    } else {
+        t.Interest += transaction.Amount
+    }
+}
+
+// rounded returns the totals rounded to two decimal places
+func (t IncomeTotals) rounded() IncomeTotals {
+    return IncomeTotals{
+        Gross:          portfolio.Round2(t.Gross),
+        WithholdingTax: portfolio.Round2(t.WithholdingTax),
+        Net:            portfolio.Round2(t.Net),
+        Reinvested:     portfolio.Round2(t.Reinvested),
+        Dividends:      portfolio.Round2(t.Dividends),
+        Interest:       portfolio.Round2(t.Interest),
+    }
+}
+
+// GetIncomeHandler handles requests for dividends and interest received totalled by month, ticker and account
+// (?from=&to=, defaults to the last twelve months, and ?account=)
+func GetIncomeHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    from, to, ok := dateRange(c, time.Now().AddDate(-1, 0, 1))
+    if !ok {
+        return
+    }
+    account := c.Query("account")
+    accountID, ok := queryAccountID(c, userID)
+    if !ok {
+        return
+    }
+
+    transactions, err := config.GetIncomeTransactions(userID, accountID, from, to.AddDate(0, 0, 1))
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve income"})
+        return
+    }
+
+    var totals IncomeTotals
+    byMonth := map[string]*IncomeTotals{}
+    byTicker := map[string]*IncomeTotals{}
+    byAccount := map[string]*IncomeTotals{}
+    addTo := func(group map[string]*IncomeTotals, key string, transaction models.Transaction) {
+        if group[key] == nil {
+            group[key] = &IncomeTotals{}
+        }
+        group[key].add(transaction)
+    }
+
+    for _, transaction := range transactions {
+        totals.add(transaction)
+        addTo(byMonth, transaction.Date.Format("2006-01"), transaction)
+        ticker := transaction.Ticker
+        if ticker == "" {
+            ticker = "cash"
+        }
+        addTo(byTicker, ticker, transaction)
+        addTo(byAccount, transaction.Account, transaction)
+    }
+
+    roundAll := func(group map[string]*IncomeTotals) map[string]IncomeTotals {
+        rounded := make(map[string]IncomeTotals, len(group))
+        for key, groupTotals := range group {
+            rounded[key] = groupTotals.rounded()
+        }
+        return rounded
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "from":      from.Format("2006-01-02"),
+        "to":        to.Format("2006-01-02"),
+        "account":   account,
+        "count":     len(transactions),
+        "totals":    totals.rounded(),
+        "byMonth":   roundAll(byMonth),
+        "byTicker":  roundAll(byTicker),
+        "byAccount": roundAll(byAccount),
+    })
+}
@@ -0,0 +1,361 @@
+package routes
+
+// Path: routes/performance.go
+import (
+    "errors"
+    "fmt"
+    "log"
+    "net/http"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/jalong4/stock-service-go/config"
+    "github.com/jalong4/stock-service-go/jobs"
+    "github.com/jalong4/stock-service-go/models"
+    "github.com/jalong4/stock-service-go/portfolio"
+    "github.com/jalong4/stock-service-go/prices"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CashFlowRequest defines the structure of the request payload for recording a deposit or withdrawal
+type CashFlowRequest struct {
+    Account string  `json:"account"`
+    Type    string  `json:"type"` // deposit, withdrawal or fee
+    Date    string  `json:"date"` // YYYY-MM-DD or RFC3339, defaults to now
+    Amount  float64 `json:"amount"`
+    Notes   string  `json:"notes"`
+}
+
+// PeriodPerformance is the performance of the portfolio and of each account over one period
+type PeriodPerformance struct {
+    Period   string                           `json:"period"`
+    Overall  portfolio.Performance            `json:"overall"`
+    Accounts map[string]portfolio.Performance `json:"accounts"`
+}
+
+// performancePeriods are reported when no period or dates are requested
+var performancePeriods = []string{"1M", "3M", "YTD", "1Y", "ITD"}
+
+// AddCashFlowHandler records a deposit into, a withdrawal from or a fee charged to an account
+func AddCashFlowHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    var req CashFlowRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+        return
+    }
+
+    if req.Account == "" || req.Amount <= 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Account and a positive amount are required"})
+        return
+    }
+    if !models.IsValidCashFlowType(req.Type) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid cash flow type: %s", req.Type)})
+        return
+    }
+
+    date := time.Now()
+    if req.Date != "" {
+        parsed, err := parseDate(req.Date)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        date = parsed
+    }
+
+    account, ok := resolveAccount(c, userID, req.Account)
+    if !ok {
+        return
+    }
+
+    cashFlow := models.CashFlow{
+        UserID:    userID,
+        Account:   account.Name,
+        AccountID: account.ID,
+        Type:      req.Type,
+        Date:      date,
+        Amount:    req.Amount,
+        Notes:     req.Notes,
+        CreatedAt: time.Now(),
+    }
+
+    id, err := config.AddCashFlow(cashFlow)
+    if err != nil {
+        log.Printf("Failed to record cash flow: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record cash flow"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, gin.H{"message": fmt.Sprintf("Successfully recorded %s to %s with ID %s", cashFlow.Type, cashFlow.Account, id)})
+}
+
+// GetCashFlowsHandler handles requests to list the user's deposits and withdrawals (?account=)
+func GetCashFlowsHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    accountID, ok := queryAccountID(c, userID)
+    if !ok {
+        return
+    }
+
+    cashFlows, err := config.GetCashFlows(userID, accountID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve cash flows"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "count":     len(cashFlows),
+        "cashFlows": cashFlows,
+    })
+}
+
+// DeleteCashFlowHandler handles requests to delete a deposit or withdrawal
+func DeleteCashFlowHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    id := c.Param("_id")
+    result, err := config.DeleteCashFlowByID(userID, id)
+    if isNotFound(err) || (err == nil && result.DeletedCount == 0) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "No cash flow found with ID: " + id})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete cash flow"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Cash flow deleted successfully!"})
+}
+
+// GetPerformanceHandler handles requests for time-weighted and money-weighted returns,
+// for named periods (?period=1M,3M,YTD,1Y,ITD) or a custom range (?from=&to=)
+func GetPerformanceHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    customFrom, to, ok := dateRange(c, time.Now())
+    if !ok {
+        return
+    }
+
+    history, ok := loadPerformanceHistory(c, userID, to)
+    if !ok {
+        return
+    }
+    if history == nil {
+        c.JSON(http.StatusOK, gin.H{"to": to.Format("2006-01-02"), "periods": []PeriodPerformance{}})
+        return
+    }
+
+    periods, ok := requestedPeriods(c, performancePeriods, customFrom, history)
+    if !ok {
+        return
+    }
+
+    results := make([]PeriodPerformance, 0, len(periods))
+    for _, p := range periods {
+        result := PeriodPerformance{
+            Period:   p.name,
+            Overall:  history.overall.Performance(p.from, history.to),
+            Accounts: map[string]portfolio.Performance{},
+        }
+        for account, series := range history.accounts {
+            result.Accounts[account] = series.Performance(p.from, history.to)
+        }
+        results = append(results, result)
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "inception":   history.inception.Format("2006-01-02"),
+        "to":          to.Format("2006-01-02"),
+        "currency":    history.currency,
+        "unconverted": history.unconverted,
+        "periods":     results,
+    })
+}
+
+// performanceHistory is the daily value of each of a user's accounts in its own currency and of the whole
+// portfolio in the base currency
+type performanceHistory struct {
+    inception   time.Time
+    to          time.Time
+    currency    string
+    overall     portfolio.Series
+    accounts    map[string]portfolio.Series
+    unconverted []string // Accounts left out of the overall series for lack of an FX rate
+}
+
+// namedPeriod is a reporting period starting on from and ending on the history's last day
+type namedPeriod struct {
+    name string
+    from time.Time
+}
+
+// loadPerformanceHistory values the user's accounts every day from inception through to. It returns
+// nil if the user has nothing recorded, and responds with an error and returns false if loading fails.
+func loadPerformanceHistory(c *gin.Context, userID primitive.ObjectID, to time.Time) (*performanceHistory, bool) {
+    transactions, err := config.GetTransactions(userID, primitive.NilObjectID, "")
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve transactions"})
+        return nil, false
+    }
+    cashFlows, err := config.GetCashFlows(userID, primitive.NilObjectID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve cash flows"})
+        return nil, false
+    }
+
+    inception, found := inceptionDate(transactions, cashFlows)
+    if !found || to.Before(inception) {
+        return nil, true
+    }
+
+    // The series starts the day before inception so every period has a baseline value
+    start := inception.AddDate(0, 0, -1)
+
+    closes, err := loadDailyCloses(c, transactions, start, to)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve daily prices"})
+        return nil, false
+    }
+
+    currency, ok := loadReportingCurrency(c, userID)
+    if !ok {
+        return nil, false
+    }
+
+    history := &performanceHistory{
+        inception:   inception,
+        to:          to,
+        currency:    currency.converter.Base,
+        accounts:    map[string]portfolio.Series{},
+        unconverted: []string{},
+    }
+
+    // Accounts are labelled with their current names and added up in the base currency at the latest rates
+    series := accountPerformanceSeries(transactions, cashFlows, closes, start, to)
+    accountIDs := make([]primitive.ObjectID, 0, len(series))
+    for accountID := range series {
+        accountIDs = append(accountIDs, accountID)
+    }
+    sort.Slice(accountIDs, func(i, j int) bool {
+        return currency.accountName(accountIDs[i]) < currency.accountName(accountIDs[j])
+    })
+    for _, accountID := range accountIDs {
+        name := currency.accountName(accountID)
+        history.accounts[name] = series[accountID]
+
+        rate, ok := currency.converter.Rate(currency.accountIDCurrency(accountID))
+        if !ok {
+            history.unconverted = append(history.unconverted, name)
+            continue
+        }
+        history.overall = history.overall.Add(series[accountID].Scale(rate))
+    }
+    return history, true
+}
+
+// requestedPeriods returns the custom period if ?from= was given, otherwise the named periods in ?period=
+// or the defaults. Periods starting before inception start at inception.
+func requestedPeriods(c *gin.Context, defaults []string, customFrom time.Time, history *performanceHistory) ([]namedPeriod, bool) {
+    var periods []namedPeriod
+    if c.Query("from") != "" {
+        periods = append(periods, namedPeriod{name: "custom", from: customFrom})
+    } else {
+        names := defaults
+        if value := c.Query("period"); value != "" {
+            names = strings.Split(value, ",")
+        }
+        for _, name := range names {
+            from, err := portfolio.PeriodStart(name, history.to, history.inception)
+            if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return nil, false
+            }
+            periods = append(periods, namedPeriod{name: strings.ToUpper(name), from: from})
+        }
+    }
+
+    for i := range periods {
+        if periods[i].from.Before(history.inception) {
+            periods[i].from = history.inception
+        }
+    }
+    return periods, true
+}
+
+// inceptionDate returns the day of the user's first transaction or cash flow
+func inceptionDate(transactions []models.Transaction, cashFlows []models.CashFlow) (time.Time, bool) {
+    var inception time.Time
+    for _, t := range transactions {
+        if inception.IsZero() || t.Date.Before(inception) {
+            inception = t.Date
+        }
+    }
+    for _, f := range cashFlows {
+        if inception.IsZero() || f.Date.Before(inception) {
+            inception = f.Date
+        }
+    }
+    return jobs.StartOfDay(inception), !inception.IsZero()
+}
+
+// loadDailyCloses fetches the daily closes of every ticker traded. Tickers without prices are left
+// out and get valued at their last trade price.
+func loadDailyCloses(c *gin.Context, transactions []models.Transaction, from time.Time, to time.Time) (map[string][]prices.DailyClose, error) {
+    provider := prices.GetProvider()
+    closes := map[string][]prices.DailyClose{}
+
+    for _, t := range transactions {
+        if _, exists := closes[t.Ticker]; exists || t.Ticker == "" {
+            continue
+        }
+
+        daily, err := provider.DailyCloses(c.Request.Context(), t.Ticker, from, to.AddDate(0, 0, 1))
+        if err != nil && !errors.Is(err, prices.ErrNoPrice) {
+            log.Printf("Failed to retrieve daily closes for %s: %v", t.Ticker, err)
+            return nil, err
+        }
+        closes[t.Ticker] = daily
+    }
+    return closes, nil
+}
+
+// accountPerformanceSeries builds the daily value series of each account, in the account's currency, by account ID
+func accountPerformanceSeries(transactions []models.Transaction, cashFlows []models.CashFlow, closes map[string][]prices.DailyClose, start time.Time, end time.Time) map[primitive.ObjectID]portfolio.Series {
+    accountTransactions := map[primitive.ObjectID][]models.Transaction{}
+    for _, t := range transactions {
+        accountTransactions[t.AccountID] = append(accountTransactions[t.AccountID], t)
+    }
+    accountCashFlows := map[primitive.ObjectID][]models.CashFlow{}
+    for _, f := range cashFlows {
+        accountCashFlows[f.AccountID] = append(accountCashFlows[f.AccountID], f)
+    }
+
+    series := map[primitive.ObjectID]portfolio.Series{}
+    for accountID := range accountTransactions {
+        series[accountID] = portfolio.BuildSeries(accountTransactions[accountID], accountCashFlows[accountID], closes, start, end)
+    }
+    for accountID := range accountCashFlows {
+        if _, ok := series[accountID]; !ok {
+            series[accountID] = portfolio.BuildSeries(nil, accountCashFlows[accountID], closes, start, end)
+        }
+    }
+    return series
+}
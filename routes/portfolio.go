@@ -0,0 +1,141 @@
+package routes
+
+// Path: routes/portfolio.go
+import (
+    "fmt"
+    "net/http"
+    "sort"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/jalong4/stock-service-go/config"
+    "github.com/jalong4/stock-service-go/jobs"
+    "github.com/jalong4/stock-service-go/portfolio"
+)
+
+// HistoryPoint is the value of the portfolio, or of one account, on a day
+type HistoryPoint struct {
+    Date        time.Time `json:"date"`
+    MarketValue float64   `json:"marketValue"` // The holdings' market value
+    Cash        float64   `json:"cash"`
+    TotalValue  float64   `json:"totalValue"` // Market value plus cash, as totalAccountValue in the holdings summary
+    CostBasis   float64   `json:"costBasis"`
+}
+
+// GetPortfolioHistoryHandler handles requests for the daily value of the portfolio (?from=&to=&account=)
+func GetPortfolioHistoryHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    from, to, ok := dateRange(c, time.Now().AddDate(-1, 0, 0))
+    if !ok {
+        return
+    }
+    account := c.Query("account")
+    accountID, ok := queryAccountID(c, userID)
+    if !ok {
+        return
+    }
+
+    snapshots, err := config.GetSnapshots(userID, accountID, from, to)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve portfolio history"})
+        return
+    }
+    currency, ok := loadReportingCurrency(c, userID)
+    if !ok {
+        return
+    }
+
+    // Add up the accounts for each day in the base currency. Snapshots recorded before they kept
+    // their currency are in their account's.
+    points := map[time.Time]*HistoryPoint{}
+    unconverted := 0
+    for _, snapshot := range snapshots {
+        snapshotCurrency := snapshot.Currency
+        if snapshotCurrency == "" {
+            snapshotCurrency = currency.accountIDCurrency(snapshot.AccountID)
+        }
+        rate, ok := currency.converter.Rate(snapshotCurrency)
+        if !ok {
+            unconverted++
+            continue
+        }
+
+        point, exists := points[snapshot.Date]
+        if !exists {
+            point = &HistoryPoint{Date: snapshot.Date}
+            points[snapshot.Date] = point
+        }
+        point.MarketValue += snapshot.MarketValue * rate
+        point.Cash += snapshot.Cash * rate
+        point.CostBasis += snapshot.CostBasis * rate
+    }
+
+    history := make([]HistoryPoint, 0, len(points))
+    for _, point := range points {
+        point.TotalValue = portfolio.Round2(point.MarketValue + point.Cash)
+        point.MarketValue = portfolio.Round2(point.MarketValue)
+        point.Cash = portfolio.Round2(point.Cash)
+        point.CostBasis = portfolio.Round2(point.CostBasis)
+        history = append(history, *point)
+    }
+    sort.Slice(history, func(i, j int) bool { return history[i].Date.Before(history[j].Date) })
+
+    c.JSON(http.StatusOK, gin.H{
+        "from":        from.Format("2006-01-02"),
+        "to":          to.Format("2006-01-02"),
+        "account":     account,
+        "currency":    currency.converter.Base,
+        "unconverted": unconverted,
+        "history":     history,
+    })
+}
+
+// RecordSnapshotsHandler handles requests to record today's snapshots now instead of waiting for the daily job
+func RecordSnapshotsHandler(c *gin.Context) {
+    count, failed, err := jobs.RecordSnapshots(c.Request.Context(), time.Now())
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to record snapshots: %v", err)})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message":  fmt.Sprintf("Recorded %d account snapshots, %d failed", count, failed),
+        "recorded": count,
+        "failed":   failed,
+    })
+}
+
+// dateRange parses the ?from= and ?to= query parameters as whole days, defaulting to defaultFrom through today.
+// It responds with an error and returns false if either is invalid.
+func dateRange(c *gin.Context, defaultFrom time.Time) (time.Time, time.Time, bool) {
+    from := jobs.StartOfDay(defaultFrom)
+    to := jobs.StartOfDay(time.Now())
+
+    if value := c.Query("from"); value != "" {
+        parsed, err := parseDate(value)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return from, to, false
+        }
+        from = jobs.StartOfDay(parsed)
+    }
+
+    if value := c.Query("to"); value != "" {
+        parsed, err := parseDate(value)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return from, to, false
+        }
+        to = jobs.StartOfDay(parsed)
+    }
+
+    if to.Before(from) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "The from date must not be after the to date"})
+        return from, to, false
+    }
+    return from, to, true
+}
@@ -0,0 +1,73 @@
+package routes
+
+// Path: routes/quotes.go
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/jalong4/stock-service-go/prices"
+)
+
+// GetQuoteHandler handles requests for the latest quote of a ticker
+func GetQuoteHandler(c *gin.Context) {
+    ticker := c.Param("ticker")
+    quote, err := prices.GetProvider().LatestQuote(c.Request.Context(), ticker)
+    if errors.Is(err, prices.ErrNoPrice) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "No quote available for " + ticker})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve quote"})
+        return
+    }
+
+    c.JSON(http.StatusOK, quote)
+}
+
+// GetQuoteCacheStatsHandler handles requests for the quote cache hit/miss counters
+func GetQuoteCacheStatsHandler(c *gin.Context) {
+    stats := prices.GetCacheStats()
+    if stats == nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Quote cache is disabled"})
+        return
+    }
+
+    c.JSON(http.StatusOK, stats)
+}
+
+// GetDailyPricesHandler handles requests for a ticker's daily closes (?from=&to=, defaults to the last year)
+func GetDailyPricesHandler(c *gin.Context) {
+    from, to, ok := dateRange(c, time.Now().AddDate(-1, 0, 0))
+    if !ok {
+        return
+    }
+
+    ticker := c.Param("ticker")
+    closes, err := prices.GetProvider().DailyCloses(c.Request.Context(), ticker, from, to)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve prices"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "ticker": ticker,
+        "count":  len(closes),
+        "prices": closes,
+    })
+}
+
+// ImportDailyPricesHandler stores the ticker's daily closes from a CSV request body of "date,close" rows
+func ImportDailyPricesHandler(c *gin.Context) {
+    ticker := strings.ToUpper(c.Param("ticker"))
+    count, err := prices.ImportCSV(ticker, c.Request.Body)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to import prices: %v", err)})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Imported %d daily prices for %s", count, ticker)})
+}
@@ -0,0 +1,293 @@
+package routes
+
+// Path: routes/rebalance.go
+import (
+    "fmt"
+    "log"
+    "math"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/jalong4/stock-service-go/config"
+    "github.com/jalong4/stock-service-go/models"
+    "github.com/jalong4/stock-service-go/portfolio"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// defaultTolerance is the drift band, in percentage points, of targets that don't set one
+const defaultTolerance = 5.0
+
+// TargetAllocationRequest defines the structure of the request payload for setting target weights
+type TargetAllocationRequest struct {
+    By             string                `json:"by"`        // ticker or assetClass
+    Targets        []models.TargetWeight `json:"targets"`   // Percentages adding up to 100, "cash" for cash
+    Tolerance      *float64              `json:"tolerance"` // Defaults to 5 percentage points
+    MinTradeAmount float64               `json:"minTradeAmount"`
+}
+
+// GetTargetAllocationHandler handles requests for the user's target weights
+func GetTargetAllocationHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    target, err := config.GetTargetAllocation(userID)
+    if isNotFound(err) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "No target allocation set, set one with PUT /portfolio/targets"})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve target allocation"})
+        return
+    }
+
+    c.JSON(http.StatusOK, target)
+}
+
+// SetTargetAllocationHandler handles requests to set the user's target weights by ticker or asset class
+func SetTargetAllocationHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    var req TargetAllocationRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+        return
+    }
+
+    target, err := req.toTargetAllocation(userID)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    if err := config.SaveTargetAllocation(target); err != nil {
+        log.Printf("Failed to save target allocation: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save target allocation"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Target allocation saved", "target": target})
+}
+
+// DeleteTargetAllocationHandler handles requests to remove the user's target weights
+func DeleteTargetAllocationHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    result, err := config.DeleteTargetAllocation(userID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete target allocation"})
+        return
+    }
+    if result.DeletedCount == 0 {
+        c.JSON(http.StatusNotFound, gin.H{"error": "No target allocation set"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Target allocation deleted"})
+}
+
+// GetRebalanceHandler handles requests for the drift from the target weights and the trades that correct it.
+// ?tolerance= and ?minTradeAmount= override the saved values, and ?avoidTaxableSells=true only sells in
+// tax-advantaged accounts.
+func GetRebalanceHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    target, err := config.GetTargetAllocation(userID)
+    if isNotFound(err) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "No target allocation set, set one with PUT /portfolio/targets"})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve target allocation"})
+        return
+    }
+
+    options := portfolio.RebalanceOptions{
+        Tolerance:      target.Tolerance,
+        MinTradeAmount: target.MinTradeAmount,
+        ByTicker:       target.By == models.TargetByTicker,
+    }
+    if !queryFloat(c, "tolerance", &options.Tolerance) || !queryFloat(c, "minTradeAmount", &options.MinTradeAmount) {
+        return
+    }
+    if value := c.Query("avoidTaxableSells"); value != "" {
+        avoid, err := strconv.ParseBool(value)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "avoidTaxableSells must be true or false"})
+            return
+        }
+        options.AvoidTaxableSells = avoid
+    }
+
+    holdings, err := config.GetAllHoldings(userID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve holdings"})
+        return
+    }
+    accounts, err := config.GetAccounts(userID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve accounts"})
+        return
+    }
+    taxable := map[primitive.ObjectID]bool{}
+    for _, account := range accounts {
+        taxable[account.ID] = account.IsTaxable()
+    }
+
+    currency, ok := loadReportingCurrency(c, userID)
+    if !ok {
+        return
+    }
+    valued, valuation := valueHoldings(c, userID, holdings, currency)
+
+    var securities map[string]models.Security
+    if target.By == models.TargetByAssetClass {
+        var tickers []string
+        for _, holding := range holdings {
+            tickers = append(tickers, holding.Ticker)
+        }
+        securities, err = config.GetSecuritiesBySymbol(tickers)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve securities"})
+            return
+        }
+    }
+
+    // Holdings without a price or FX rate can't be weighed, so they are left out
+    var positions []portfolio.RebalancePosition
+    for _, holding := range valued {
+        if holding.BaseMarketValue == nil || holding.Quantity == 0 {
+            continue
+        }
+
+        group := holding.Ticker
+        if target.By == models.TargetByAssetClass {
+            group = securities[holding.Ticker].AssetClass
+            if group == "" {
+                group = unclassifiedGroup
+            }
+        }
+
+        positions = append(positions, portfolio.RebalancePosition{
+            Ticker:      holding.Ticker,
+            Account:     holding.Account,
+            Group:       group,
+            Taxable:     taxable[holding.AccountID],
+            MarketValue: *holding.BaseMarketValue,
+            Price:       *holding.BaseMarketValue / holding.Quantity,
+        })
+    }
+
+    balances, ok := cashBalances(c, userID)
+    if !ok {
+        return
+    }
+    cash := 0.0
+    for accountID, balance := range balances {
+        if converted, ok := currency.converter.Convert(balance, currency.accountIDCurrency(accountID)); ok {
+            cash += converted
+        }
+    }
+
+    targets := map[string]float64{}
+    for _, weight := range target.Targets {
+        targets[weight.Group] = weight.Weight
+    }
+
+    plan := portfolio.Rebalance(positions, cash, targets, options)
+
+    c.JSON(http.StatusOK, gin.H{
+        "by":                target.By,
+        "currency":          valuation.Currency,
+        "tolerance":         options.Tolerance,
+        "minTradeAmount":    options.MinTradeAmount,
+        "avoidTaxableSells": options.AvoidTaxableSells,
+        "unpriced":          valuation.Unpriced,
+        "unconverted":       valuation.Unconverted,
+        "totalValue":        plan.TotalValue,
+        "groups":            plan.Groups,
+        "trades":            plan.Trades,
+        "notes":             plan.Notes,
+    })
+}
+
+// toTargetAllocation validates the request and converts it into the user's target allocation
+func (req TargetAllocationRequest) toTargetAllocation(userID primitive.ObjectID) (models.TargetAllocation, error) {
+    target := models.TargetAllocation{
+        UserID:         userID,
+        By:             req.By,
+        Tolerance:      defaultTolerance,
+        MinTradeAmount: req.MinTradeAmount,
+        UpdatedAt:      time.Now(),
+    }
+
+    if target.By != models.TargetByTicker && target.By != models.TargetByAssetClass {
+        return target, fmt.Errorf("By must be %s or %s", models.TargetByTicker, models.TargetByAssetClass)
+    }
+    if req.Tolerance != nil {
+        target.Tolerance = *req.Tolerance
+    }
+    if target.Tolerance < 0 || target.MinTradeAmount < 0 {
+        return target, fmt.Errorf("Tolerance and minimum trade amount must not be negative")
+    }
+    if len(req.Targets) == 0 {
+        return target, fmt.Errorf("At least one target weight is required")
+    }
+
+    seen := map[string]bool{}
+    total := 0.0
+    for _, weight := range req.Targets {
+        weight.Group = strings.TrimSpace(weight.Group)
+        if target.By == models.TargetByTicker && !strings.EqualFold(weight.Group, portfolio.CashGroup) {
+            weight.Group = strings.ToUpper(weight.Group)
+        }
+        if strings.EqualFold(weight.Group, portfolio.CashGroup) {
+            weight.Group = portfolio.CashGroup
+        }
+
+        if weight.Group == "" || weight.Weight < 0 {
+            return target, fmt.Errorf("Each target needs a group and a weight that is not negative")
+        }
+        if seen[weight.Group] {
+            return target, fmt.Errorf("Duplicate target for %s", weight.Group)
+        }
+        seen[weight.Group] = true
+        total += weight.Weight
+        target.Targets = append(target.Targets, weight)
+    }
+    if math.Abs(total-100) > 0.01 {
+        return target, fmt.Errorf("Target weights add up to %v, they must add up to 100", total)
+    }
+
+    return target, nil
+}
+
+// queryFloat parses the named query parameter into value when it is present.
+// It responds with 400 and returns false if it is not a number that is not negative.
+func queryFloat(c *gin.Context, name string, value *float64) bool {
+    raw := c.Query(name)
+    if raw == "" {
+        return true
+    }
+
+    parsed, err := strconv.ParseFloat(raw, 64)
+    if err != nil || parsed < 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s must be a number that is not negative", name)})
+        return false
+    }
+    *value = parsed
+    return true
+}
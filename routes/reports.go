@@ -0,0 +1,225 @@
+package routes
+
+// Path: routes/reports.go
+import (
+    "math"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/jalong4/stock-service-go/config"
+    "github.com/jalong4/stock-service-go/models"
+    "github.com/jalong4/stock-service-go/portfolio"
+    "github.com/jalong4/stock-service-go/prices"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GainTotals sums realized gains, split by holding period
+type GainTotals struct {
+    Proceeds      float64 `json:"proceeds"`
+    Basis         float64 `json:"basis"`
+    Gain          float64 `json:"gain"`
+    ShortTermGain float64 `json:"shortTermGain"`
+    LongTermGain  float64 `json:"longTermGain"`
+}
+
+// add includes a realized lot in the totals, its amounts multiplied by rate to convert them
+func (t *GainTotals) add(lot models.RealizedLot, rate float64) {
+    t.Proceeds += lot.Proceeds * rate
+    t.Basis += lot.Basis * rate
+    t.Gain += lot.Gain * rate
+    if lot.Term == models.LongTerm {
+        t.LongTermGain += lot.Gain * rate
+    } else {
+        t.ShortTermGain += lot.Gain * rate
+    }
+}
+
+// rounded returns the totals rounded to two decimal places
+func (t GainTotals) rounded() GainTotals {
+    round := func(value float64) float64 { return math.Round(value*100) / 100 }
+    return GainTotals{
+        Proceeds:      round(t.Proceeds),
+        Basis:         round(t.Basis),
+        Gain:          round(t.Gain),
+        ShortTermGain: round(t.ShortTermGain),
+        LongTermGain:  round(t.LongTermGain),
+    }
+}
+
+// GetRealizedGainsHandler handles requests for the realized gains of a tax year (?year=, defaults to the current year)
+func GetRealizedGainsHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    year := time.Now().Year()
+    if value := c.Query("year"); value != "" {
+        parsed, err := strconv.Atoi(value)
+        if err != nil || parsed < 1900 || parsed > 9999 {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid year: " + value})
+            return
+        }
+        year = parsed
+    }
+
+    from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+    lots, err := config.GetRealizedLots(userID, from, from.AddDate(1, 0, 0))
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve realized gains"})
+        return
+    }
+
+    currency, ok := loadReportingCurrency(c, userID)
+    if !ok {
+        return
+    }
+
+    // The summary is in the base currency, each account's totals in the account's currency
+    var totals GainTotals
+    unconverted := 0
+    accountTotals := map[primitive.ObjectID]*GainTotals{}
+    for _, lot := range lots {
+        if accountTotals[lot.AccountID] == nil {
+            accountTotals[lot.AccountID] = &GainTotals{}
+        }
+        accountTotals[lot.AccountID].add(lot, 1)
+
+        rate, ok := currency.converter.Rate(currency.accountIDCurrency(lot.AccountID))
+        if !ok {
+            unconverted++
+            continue
+        }
+        totals.add(lot, rate)
+    }
+
+    accounts := map[string]GainTotals{}
+    currencies := map[string]string{}
+    for accountID, accountTotal := range accountTotals {
+        name := currency.accountName(accountID)
+        accounts[name] = accountTotal.rounded()
+        currencies[name] = currency.accountIDCurrency(accountID)
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "year":        year,
+        "currency":    currency.converter.Base,
+        "unconverted": unconverted,
+        "summary":     totals.rounded(),
+        "accounts":    accounts,
+        "currencies":  currencies,
+        "lots":        lots,
+    })
+}
+
+// GetTaxLossHarvestingHandler handles requests for the lots in taxable accounts that could be sold today to
+// realize a loss (?minLoss=&minLossPct=), flagging those a recent purchase in any account would make a wash sale
+func GetTaxLossHarvestingHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    criteria := portfolio.HarvestCriteria{SaleDate: time.Now()}
+    if !queryFloat(c, "minLoss", &criteria.MinLoss) || !queryFloat(c, "minLossPct", &criteria.MinLossPct) {
+        return
+    }
+
+    holdings, err := config.GetAllHoldings(userID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve holdings"})
+        return
+    }
+    accounts, err := config.GetAccounts(userID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve accounts"})
+        return
+    }
+    // Purchases in every account count towards wash sales, IRAs included
+    transactions, err := config.GetTransactions(userID, primitive.NilObjectID, "")
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve transactions"})
+        return
+    }
+
+    currency, ok := loadReportingCurrency(c, userID)
+    if !ok {
+        return
+    }
+
+    // Losses can only be harvested in taxable accounts
+    taxable := map[primitive.ObjectID]bool{}
+    for _, account := range accounts {
+        taxable[account.ID] = account.IsTaxable()
+    }
+    var taxableHoldings []models.Holding
+    var tickers []string
+    undated := 0
+    for _, holding := range holdings {
+        if !taxable[holding.AccountID] {
+            continue
+        }
+        if len(holding.Lots) == 0 {
+            undated++ // Entered before lots were tracked
+            continue
+        }
+        if holding.Currency == "" {
+            holding.Currency = currency.accountIDCurrency(holding.AccountID)
+        }
+        taxableHoldings = append(taxableHoldings, holding)
+        tickers = append(tickers, holding.Ticker)
+    }
+
+    quotes := portfolio.FetchQuotes(c.Request.Context(), prices.GetProvider(), tickers)
+    unpriced := 0
+    for _, quote := range quotes {
+        if quote == nil {
+            unpriced++
+        }
+    }
+
+    candidates := portfolio.HarvestCandidates(taxableHoldings, quotes, transactions, criteria)
+
+    // Candidates are in their holding's currency, the totals in the base currency
+    totalLoss, shortTermLoss, longTermLoss := 0.0, 0.0, 0.0
+    washSales, unconverted := 0, 0
+    for _, candidate := range candidates {
+        if candidate.WashSale {
+            washSales++
+        }
+
+        loss, ok := currency.converter.Convert(candidate.UnrealizedLoss, candidate.Currency)
+        if !ok {
+            unconverted++
+            continue
+        }
+        totalLoss += loss
+        if candidate.Term == models.LongTerm {
+            longTermLoss += loss
+        } else {
+            shortTermLoss += loss
+        }
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "saleDate": criteria.SaleDate.Format("2006-01-02"),
+        "washSaleWindow": gin.H{
+            "from": criteria.SaleDate.AddDate(0, 0, -portfolio.WashSaleWindowDays).Format("2006-01-02"),
+            "to":   criteria.SaleDate.AddDate(0, 0, portfolio.WashSaleWindowDays).Format("2006-01-02"),
+        },
+        "summary": gin.H{
+            "candidates":      len(candidates),
+            "currency":        currency.converter.Base,
+            "totalLoss":       portfolio.Round2(totalLoss),
+            "shortTermLoss":   portfolio.Round2(shortTermLoss),
+            "longTermLoss":    portfolio.Round2(longTermLoss),
+            "washSales":       washSales,
+            "unpricedTickers": unpriced,
+            "undatedHoldings": undated,
+            "unconverted":     unconverted,
+        },
+        "candidates": candidates,
+    })
+}
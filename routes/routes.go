@@ -1,11 +1,16 @@
 package routes
 
 import (
+    "encoding/hex"
+    "errors"
     "net/http"
 
     "github.com/gin-gonic/gin"
     "github.com/jalong4/stock-service-go/auth"
     "github.com/jalong4/stock-service-go/config"
+    "github.com/jalong4/stock-service-go/models"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
 )
 
 type RouteMetadata struct {
@@ -14,22 +19,83 @@ type RouteMetadata struct {
     Description  string
     Handler      gin.HandlerFunc
     RequiresAuth bool
+    RequiredRole string // Role needed in addition to authentication, empty for any authenticated user
 }
 
 var routeDefinitions = []RouteMetadata{
     {Method: "POST", Path: "/users/login", Description: "Authenticate user and provide tokens", Handler: LoginHandler, RequiresAuth: false},
+    {Method: "POST", Path: "/users/token/refresh", Description: "Exchange a refresh token for new tokens", Handler: RefreshTokenHandler, RequiresAuth: false},
+    {Method: "POST", Path: "/users/logout", Description: "Revoke the current access token and optional refresh token", Handler: LogoutHandler, RequiresAuth: true},
+    {Method: "POST", Path: "/users/logout-all", Description: "Revoke all tokens issued to the current user", Handler: LogoutAllHandler, RequiresAuth: true},
     {Method: "POST", Path: "/users/register", Description: "Register a new user", Handler: RegisterUserHandler, RequiresAuth: false},
-    {Method: "GET", Path: "/users/", Description: "Retrieve all users", Handler: GetAllUsers, RequiresAuth: true},
-    {Method: "GET", Path: "/users/id/:_id", Description: "Retrieve a user by their ID", Handler: GetUserByID, RequiresAuth: true},
-    {Method: "DELETE", Path: "/users/id/:_id", Description: "Delete a user by their ID", Handler: DeleteUserHandler, RequiresAuth: true},
-    {Method: "PUT", Path: "/users/id/:_id", Description: "Update a user by their ID", Handler: UpdateUserHandler, RequiresAuth: true},
+    {Method: "POST", Path: "/users/password/forgot", Description: "Email a password reset token to a registered address", Handler: ForgotPasswordHandler, RequiresAuth: false},
+    {Method: "POST", Path: "/users/password/reset", Description: "Set a new password with a password reset token (token, password, password2)", Handler: ResetPasswordHandler, RequiresAuth: false},
+    {Method: "GET", Path: "/users/", Description: "Retrieve all users", Handler: GetAllUsers, RequiresAuth: true, RequiredRole: models.RoleAdmin},
+    {Method: "GET", Path: "/users/id/:_id", Description: "Retrieve a user by their ID", Handler: GetUserByID, RequiresAuth: true, RequiredRole: models.RoleUser},
+    {Method: "DELETE", Path: "/users/id/:_id", Description: "Delete a user by their ID", Handler: DeleteUserHandler, RequiresAuth: true, RequiredRole: models.RoleUser},
+    {Method: "PUT", Path: "/users/id/:_id", Description: "Update a user by their ID", Handler: UpdateUserHandler, RequiresAuth: true, RequiredRole: models.RoleUser},
     {Method: "GET", Path: "/holdings/", Description: "Retrieve all holdings", Handler: GetAllHoldingsHandler, RequiresAuth: true},
     {Method: "POST", Path: "/holdings/", Description: "Add a new holding", Handler: AddHoldingsHandler, RequiresAuth: true},
     {Method: "GET", Path: "/holdings/id/:_id", Description: "Retrieve a holding by its ID", Handler: GetHoldingByIDHandler, RequiresAuth: true},
-    {Method: "DELETE", Path: "/holdings/id/:_id", Description: "Delete a holding by its ID", Handler: DeleteHoldingHandler, RequiresAuth: true},
-    {Method: "PUT", Path: "/holdings/id/:_id", Description: "Update a holding by its ID", Handler: UpdateHoldingHandler, RequiresAuth: true},
+    {Method: "DELETE", Path: "/holdings/id/:_id", Description: "Close a holding by its ID with a transfer out, keeping its transaction history", Handler: DeleteHoldingHandler, RequiresAuth: true},
+    {Method: "PUT", Path: "/holdings/id/:_id", Description: "Update a holding by its ID (only holdings without transactions)", Handler: UpdateHoldingHandler, RequiresAuth: true},
+    {Method: "POST", Path: "/holdings/id/:_id/sell", Description: "Sell shares of a holding using the account's cost basis method", Handler: SellHoldingHandler, RequiresAuth: true},
     {Method: "GET", Path: "/holdings/ticker/:ticker", Description: "Retrieve holdings by ticker", Handler: GetHoldingsByTickerHandler, RequiresAuth: true},
     {Method: "GET", Path: "/holdings/account/:account", Description: "Retrieve holdings by account", Handler: GetHoldingsByAccountHandler, RequiresAuth: true},
+    {Method: "GET", Path: "/accounts/cash", Description: "Retrieve the cash balance of each account", Handler: GetCashBalancesHandler, RequiresAuth: true},
+    {Method: "GET", Path: "/accounts/", Description: "Retrieve your accounts", Handler: GetAccountsHandler, RequiresAuth: true},
+    {Method: "POST", Path: "/accounts/", Description: "Add an account (name, institution, type, currency, costBasisMethod)", Handler: AddAccountHandler, RequiresAuth: true},
+    {Method: "GET", Path: "/accounts/id/:_id", Description: "Retrieve an account by its ID", Handler: GetAccountByIDHandler, RequiresAuth: true},
+    {Method: "PUT", Path: "/accounts/id/:_id", Description: "Update an account, renaming it everywhere it is used", Handler: UpdateAccountHandler, RequiresAuth: true},
+    {Method: "DELETE", Path: "/accounts/id/:_id", Description: "Delete an account with no holdings, transactions or cash flows", Handler: DeleteAccountHandler, RequiresAuth: true},
+    {Method: "GET", Path: "/transactions/", Description: "Retrieve transactions, optionally filtered by ?account= and ?ticker=", Handler: GetTransactionsHandler, RequiresAuth: true},
+    {Method: "POST", Path: "/transactions/", Description: "Record a buy, sell, transferIn, transferOut, dividend or interest transaction", Handler: AddTransactionHandler, RequiresAuth: true},
+    {Method: "GET", Path: "/transactions/id/:_id", Description: "Retrieve a transaction by its ID", Handler: GetTransactionByIDHandler, RequiresAuth: true},
+    {Method: "DELETE", Path: "/transactions/id/:_id", Description: "Delete a transaction by its ID", Handler: DeleteTransactionHandler, RequiresAuth: true},
+    {Method: "GET", Path: "/securities/", Description: "Search the security master by symbol prefix, name, CUSIP or ISIN (?q=&limit=)", Handler: SearchSecuritiesHandler, RequiresAuth: true},
+    {Method: "GET", Path: "/securities/symbol/:symbol", Description: "Look up a security by its symbol", Handler: GetSecurityHandler, RequiresAuth: true},
+    {Method: "POST", Path: "/securities/import", Description: "Import securities from a CSV body with a header row naming its columns", Handler: ImportSecuritiesHandler, RequiresAuth: true, RequiredRole: models.RoleAdmin},
+    {Method: "GET", Path: "/quotes/:ticker", Description: "Retrieve the latest quote for a ticker", Handler: GetQuoteHandler, RequiresAuth: true},
+    {Method: "GET", Path: "/prices/:ticker", Description: "Retrieve daily closes for a ticker (?from=&to=)", Handler: GetDailyPricesHandler, RequiresAuth: true},
+    {Method: "POST", Path: "/prices/:ticker/import", Description: "Import daily closes for a ticker from a CSV body of date,close rows", Handler: ImportDailyPricesHandler, RequiresAuth: true, RequiredRole: models.RoleAdmin},
+    {Method: "GET", Path: "/fx/rates/:base/:quote", Description: "Retrieve the value of one unit of a currency in another (?date=)", Handler: GetFxRateHandler, RequiresAuth: true},
+    {Method: "POST", Path: "/fx/rates/import", Description: "Import FX rates from a CSV body of date,base,quote,rate rows", Handler: ImportFxRatesHandler, RequiresAuth: true, RequiredRole: models.RoleAdmin},
+    {Method: "GET", Path: "/portfolio/history", Description: "Retrieve daily portfolio value (?from=&to=&account=)", Handler: GetPortfolioHistoryHandler, RequiresAuth: true},
+    {Method: "POST", Path: "/portfolio/snapshots", Description: "Record today's portfolio snapshots now", Handler: RecordSnapshotsHandler, RequiresAuth: true, RequiredRole: models.RoleAdmin},
+    {Method: "GET", Path: "/portfolio/performance", Description: "Retrieve time-weighted and money-weighted returns per account and overall (?period=1M,3M,YTD,1Y,ITD or ?from=&to=)", Handler: GetPerformanceHandler, RequiresAuth: true},
+    {Method: "GET", Path: "/portfolio/allocation", Description: "Retrieve portfolio weights by market value and cost (?by=assetClass|sector|country|account|ticker)", Handler: GetAllocationHandler, RequiresAuth: true},
+    {Method: "GET", Path: "/portfolio/targets", Description: "Retrieve your target weights", Handler: GetTargetAllocationHandler, RequiresAuth: true},
+    {Method: "PUT", Path: "/portfolio/targets", Description: "Set target weights by ticker or asset class, with a drift tolerance and minimum trade amount", Handler: SetTargetAllocationHandler, RequiresAuth: true},
+    {Method: "DELETE", Path: "/portfolio/targets", Description: "Delete your target weights", Handler: DeleteTargetAllocationHandler, RequiresAuth: true},
+    {Method: "GET", Path: "/portfolio/rebalance", Description: "Retrieve drift from the target weights and the trades that correct it (?tolerance=&minTradeAmount=&avoidTaxableSells=)", Handler: GetRebalanceHandler, RequiresAuth: true},
+    {Method: "GET", Path: "/portfolio/benchmark", Description: "Compare portfolio returns and growth of $10k with a benchmark (?period= or ?from=&to=, ?ticker=)", Handler: GetBenchmarkHandler, RequiresAuth: true},
+    {Method: "PUT", Path: "/portfolio/benchmark", Description: "Set the benchmark ticker performance is compared against", Handler: SetBenchmarkHandler, RequiresAuth: true},
+    {Method: "PUT", Path: "/portfolio/currency", Description: "Set the base currency holdings summaries are reported in", Handler: SetBaseCurrencyHandler, RequiresAuth: true},
+    {Method: "GET", Path: "/cashflows/", Description: "Retrieve deposits, withdrawals and fees (?account=)", Handler: GetCashFlowsHandler, RequiresAuth: true},
+    {Method: "POST", Path: "/cashflows/", Description: "Record a deposit, withdrawal or fee for an account", Handler: AddCashFlowHandler, RequiresAuth: true},
+    {Method: "DELETE", Path: "/cashflows/id/:_id", Description: "Delete a deposit, withdrawal or fee", Handler: DeleteCashFlowHandler, RequiresAuth: true},
+    {Method: "GET", Path: "/diagnostics/quote-cache", Description: "Retrieve quote cache hit and miss counters", Handler: GetQuoteCacheStatsHandler, RequiresAuth: true, RequiredRole: models.RoleAdmin},
+    {Method: "GET", Path: "/corporate-actions/", Description: "Retrieve corporate actions, optionally for a ticker (?ticker=)", Handler: GetCorporateActionsHandler, RequiresAuth: true},
+    {Method: "POST", Path: "/corporate-actions/", Description: "Record a split, reverse split, rename, spin-off or cash merger and adjust every user's holdings", Handler: AddCorporateActionHandler, RequiresAuth: true, RequiredRole: models.RoleAdmin},
+    {Method: "POST", Path: "/corporate-actions/id/:_id/apply", Description: "Apply a corporate action again to the positions it could not adjust when recorded", Handler: ApplyCorporateActionHandler, RequiresAuth: true, RequiredRole: models.RoleAdmin},
+    {Method: "GET", Path: "/corporate-actions/id/:_id/adjustments", Description: "Retrieve the positions a corporate action adjusted", Handler: GetCorporateActionAdjustmentsHandler, RequiresAuth: true},
+    {Method: "GET", Path: "/income", Description: "Retrieve dividends and interest totalled by month, ticker and account (?from=&to=&account=)", Handler: GetIncomeHandler, RequiresAuth: true},
+    {Method: "GET", Path: "/alerts/", Description: "Retrieve your alerts", Handler: GetAlertsHandler, RequiresAuth: true},
+    {Method: "POST", Path: "/alerts/", Description: "Add a price, portfolio day change or position weight alert (type, ticker, operator, threshold)", Handler: AddAlertHandler, RequiresAuth: true},
+    {Method: "GET", Path: "/alerts/id/:_id", Description: "Retrieve an alert by its ID", Handler: GetAlertByIDHandler, RequiresAuth: true},
+    {Method: "PUT", Path: "/alerts/id/:_id", Description: "Update an alert, re-arming it if its condition changes", Handler: UpdateAlertHandler, RequiresAuth: true},
+    {Method: "DELETE", Path: "/alerts/id/:_id", Description: "Delete an alert and its trigger history", Handler: DeleteAlertHandler, RequiresAuth: true},
+    {Method: "GET", Path: "/alerts/triggers", Description: "Retrieve alert trigger history, newest first (?alertId=&limit=)", Handler: GetAlertTriggersHandler, RequiresAuth: true},
+    {Method: "POST", Path: "/alerts/evaluate", Description: "Evaluate every active alert now", Handler: EvaluateAlertsHandler, RequiresAuth: true, RequiredRole: models.RoleAdmin},
+    {Method: "GET", Path: "/webhooks/", Description: "Retrieve your webhooks", Handler: GetWebhooksHandler, RequiresAuth: true},
+    {Method: "POST", Path: "/webhooks/", Description: "Subscribe a URL to holding.created, holding.updated, holding.deleted or alert.triggered events (url, secret, events)", Handler: AddWebhookHandler, RequiresAuth: true},
+    {Method: "GET", Path: "/webhooks/id/:_id", Description: "Retrieve a webhook by its ID", Handler: GetWebhookByIDHandler, RequiresAuth: true},
+    {Method: "PUT", Path: "/webhooks/id/:_id", Description: "Update a webhook's URL, secret, events or active flag", Handler: UpdateWebhookHandler, RequiresAuth: true},
+    {Method: "DELETE", Path: "/webhooks/id/:_id", Description: "Delete a webhook and its delivery log", Handler: DeleteWebhookHandler, RequiresAuth: true},
+    {Method: "GET", Path: "/webhooks/id/:_id/deliveries", Description: "Retrieve a webhook's delivery log, newest first (?limit=)", Handler: GetWebhookDeliveriesHandler, RequiresAuth: true},
+    {Method: "POST", Path: "/webhooks/id/:_id/ping", Description: "Send a ping event to a webhook", Handler: PingWebhookHandler, RequiresAuth: true},
+    {Method: "GET", Path: "/reports/realized-gains", Description: "Retrieve realized gains for a tax year (?year=) with short and long term totals per account", Handler: GetRealizedGainsHandler, RequiresAuth: true},
+    {Method: "GET", Path: "/reports/tax-loss-harvesting", Description: "Retrieve lots in taxable accounts with unrealized losses (?minLoss=&minLossPct=), flagging wash sales", Handler: GetTaxLossHarvestingHandler, RequiresAuth: true},
 }
 
 func GetRoutes() []RouteMetadata {
@@ -48,7 +114,9 @@ func Setup(router *gin.Engine) {
 
     // Define routes dynamically based on routeDefinitions
     for _, route := range routeDefinitions {
-        if route.RequiresAuth {
+        if route.RequiresAuth && route.RequiredRole != "" {
+            router.Handle(route.Method, route.Path, auth.AuthMiddleware(), auth.RequireRole(route.RequiredRole), route.Handler)
+        } else if route.RequiresAuth {
             router.Handle(route.Method, route.Path, auth.AuthMiddleware(), route.Handler)
         } else {
             router.Handle(route.Method, route.Path, route.Handler)
@@ -62,3 +130,34 @@ func Setup(router *gin.Engine) {
         })
     })
 }
+
+// currentUserID returns the authenticated user's ID, responding with 401 if it is missing
+func currentUserID(c *gin.Context) (primitive.ObjectID, bool) {
+    userID, err := auth.GetUserID(c)
+    if err != nil {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user in token"})
+        return primitive.NilObjectID, false
+    }
+    return userID, true
+}
+
+// isNotFound reports whether a lookup error means the record does not exist
+func isNotFound(err error) bool {
+    var invalidByte hex.InvalidByteError
+    return errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, primitive.ErrInvalidHex) || errors.As(err, &invalidByte)
+}
+
+// canAccessUser reports whether the authenticated user may read or modify the given user,
+// responding with 403 if not. Admins may access any user, everyone else only themselves.
+func canAccessUser(c *gin.Context, id string) bool {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return false
+    }
+
+    if userID.Hex() != id && !auth.IsAdmin(c) {
+        c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to access this user"})
+        return false
+    }
+    return true
+}
@@ -0,0 +1,95 @@
+package routes
+
+// Path: routes/securities.go
+import (
+    "fmt"
+    "log"
+    "net/http"
+    "strconv"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+    "github.com/jalong4/stock-service-go/config"
+    "github.com/jalong4/stock-service-go/securities"
+)
+
+// maxSecuritySearchResults caps the ?limit= of a security search
+const maxSecuritySearchResults = 100
+
+// GetSecurityHandler handles requests to look up a security by its symbol
+func GetSecurityHandler(c *gin.Context) {
+    symbol := c.Param("symbol")
+    security, err := config.GetSecurityBySymbol(symbol)
+    if isNotFound(err) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "No security found with symbol " + strings.ToUpper(symbol)})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve security"})
+        return
+    }
+
+    c.JSON(http.StatusOK, security)
+}
+
+// SearchSecuritiesHandler handles requests to find securities by symbol prefix, or by name, CUSIP or ISIN (?q=&limit=)
+func SearchSecuritiesHandler(c *gin.Context) {
+    limit := int64(25)
+    if value := c.Query("limit"); value != "" {
+        parsed, err := strconv.ParseInt(value, 10, 64)
+        if err != nil || parsed <= 0 || parsed > maxSecuritySearchResults {
+            c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Limit must be between 1 and %d", maxSecuritySearchResults)})
+            return
+        }
+        limit = parsed
+    }
+
+    found, err := config.SearchSecurities(c.Query("q"), limit)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search securities"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "count":      len(found),
+        "securities": found,
+    })
+}
+
+// ImportSecuritiesHandler stores securities from a CSV request body with a header row naming its columns
+func ImportSecuritiesHandler(c *gin.Context) {
+    count, err := securities.ImportCSV(c.Request.Body)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to import securities: %v", err)})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Imported %d securities", count)})
+}
+
+// validateTicker checks the ticker against the security master and returns its symbol as listed there.
+// It responds with an error and returns false for unknown tickers. Tickers are accepted as they are
+// while the security master is empty.
+func validateTicker(c *gin.Context, ticker string) (string, bool) {
+    security, err := config.GetSecurityBySymbol(ticker)
+    if err == nil {
+        return security.Symbol, true
+    }
+    if !isNotFound(err) {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve security"})
+        return "", false
+    }
+
+    loaded, err := config.HasSecurities()
+    if err != nil {
+        log.Printf("Failed to check the security master: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve security"})
+        return "", false
+    }
+    if !loaded {
+        return ticker, true
+    }
+
+    c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown ticker %s, search the security master with GET /securities/?q=", ticker)})
+    return "", false
+}
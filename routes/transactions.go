@@ -0,0 +1,478 @@
+package routes
+
+// Path: routes/transactions.go
+import (
+    "fmt"
+    "log"
+    "net/http"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/jalong4/stock-service-go/config"
+    "github.com/jalong4/stock-service-go/models"
+    "github.com/jalong4/stock-service-go/portfolio"
+    "github.com/jalong4/stock-service-go/webhooks"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TransactionRequest defines the structure of the request payload for recording a transaction
+type TransactionRequest struct {
+    Account  string  `json:"account"`
+    Ticker   string  `json:"ticker"`
+    Type     string  `json:"type"`
+    Date     string  `json:"date"` // YYYY-MM-DD or RFC3339, defaults to now
+    Quantity float64 `json:"quantity"`
+    Price    float64 `json:"price"`
+    Fees     float64 `json:"fees"`
+    Notes    string  `json:"notes"`
+
+    // Sells and transfers out only, CostBasisMethod defaults to the account's method
+    CostBasisMethod string `json:"costBasisMethod"`
+    LotID           string `json:"lotId"`
+
+    // Transfers in only, the date the shares were originally bought
+    AcquiredDate string `json:"acquiredDate"`
+
+    // Dividends and interest only. Reinvested payments buy Quantity shares at Price, which defaults
+    // to the net amount divided by the quantity. Interest on cash has no ticker.
+    Amount         float64 `json:"amount"`
+    WithholdingTax float64 `json:"withholdingTax"`
+    Reinvested     bool    `json:"reinvested"`
+}
+
+// AddTransactionHandler records a transaction in the ledger and updates the affected holding
+func AddTransactionHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    var req TransactionRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+        return
+    }
+
+    transaction, err := req.toTransaction(userID)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    if transaction.Type == models.TransactionSell || transaction.Type == models.TransactionTransferOut {
+        if !resolveCostBasisMethod(c, &transaction) {
+            return
+        }
+    }
+
+    // Positions are opened by buys and transfers in, so only those need a known ticker
+    if transaction.Type == models.TransactionBuy || transaction.Type == models.TransactionTransferIn {
+        transaction.Ticker, ok = validateTicker(c, transaction.Ticker)
+        if !ok {
+            return
+        }
+    }
+
+    recorded, _, holding, ok := recordTransaction(c, transaction)
+    if !ok {
+        return
+    }
+
+    c.JSON(http.StatusCreated, gin.H{
+        "message": fmt.Sprintf("Successfully recorded %s of %s with ID %s", recorded.Type, recorded.Ticker, recorded.ID.Hex()),
+        "holding": holding,
+    })
+}
+
+// GetTransactionsHandler handles requests to list the user's transactions, optionally filtered by account and ticker
+func GetTransactionsHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    accountID, ok := queryAccountID(c, userID)
+    if !ok {
+        return
+    }
+
+    transactions, err := config.GetTransactions(userID, accountID, c.Query("ticker"))
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve transactions"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "count":        len(transactions),
+        "transactions": transactions,
+    })
+}
+
+// GetTransactionByIDHandler handles requests to get a transaction by its ID
+func GetTransactionByIDHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    id := c.Param("_id")
+    transaction, err := config.GetTransactionByID(userID, id)
+    if isNotFound(err) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "No transaction found with ID: " + id})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve transaction"})
+        return
+    }
+
+    c.JSON(http.StatusOK, transaction)
+}
+
+// DeleteTransactionHandler removes a transaction from the ledger and updates the affected holding
+func DeleteTransactionHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    id := c.Param("_id")
+    transaction, err := config.GetTransactionByID(userID, id)
+    if isNotFound(err) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "No transaction found with ID: " + id})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve transaction"})
+        return
+    }
+
+    if !transaction.CorporateActionID.IsZero() {
+        c.JSON(http.StatusConflict, gin.H{"error": "Transaction was recorded by corporate action " + transaction.CorporateActionID.Hex() + " and cannot be deleted"})
+        return
+    }
+
+    if transaction.Ticker == "" {
+        if _, err = config.DeleteTransactionByID(userID, id); err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete transaction"})
+            return
+        }
+        c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Transaction %s deleted successfully!", id)})
+        return
+    }
+
+    transactions, err := config.GetTransactions(userID, transaction.AccountID, transaction.Ticker)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve transactions"})
+        return
+    }
+
+    // Make sure the remaining ledger still makes sense, e.g. no sells of shares that were never bought
+    var remaining []models.Transaction
+    for _, t := range transactions {
+        if t.ID != transaction.ID {
+            remaining = append(remaining, t)
+        }
+    }
+    position, err := portfolio.BuildPosition(remaining)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Cannot delete transaction: %v", err)})
+        return
+    }
+
+    if _, err = config.DeleteTransactionByID(userID, id); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete transaction"})
+        return
+    }
+
+    if _, err = savePosition(userID, transaction.AccountID, transaction.Account, transaction.Ticker, position); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update holding"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Transaction %s deleted successfully!", id)})
+}
+
+// toTransaction validates the request and converts it to a ledger transaction
+func (req TransactionRequest) toTransaction(userID primitive.ObjectID) (models.Transaction, error) {
+    req.Account = strings.TrimSpace(req.Account)
+    req.Ticker = strings.TrimSpace(req.Ticker)
+
+    if !models.IsValidTransactionType(req.Type) {
+        return models.Transaction{}, fmt.Errorf("Invalid transaction type: %q", req.Type)
+    }
+    income := req.Type == models.TransactionDividend || req.Type == models.TransactionInterest
+    if req.Account == "" || (req.Ticker == "" && (req.Type != models.TransactionInterest || req.Reinvested)) {
+        return models.Transaction{}, fmt.Errorf("Account and ticker are required")
+    }
+    if req.Price < 0 || req.Fees < 0 {
+        return models.Transaction{}, fmt.Errorf("Price and fees cannot be negative")
+    }
+
+    if income {
+        if req.Amount <= 0 || req.WithholdingTax < 0 || req.WithholdingTax > req.Amount {
+            return models.Transaction{}, fmt.Errorf("Income needs a positive amount and withholding tax between zero and the amount")
+        }
+        if !req.Reinvested {
+            req.Quantity, req.Price = 0, 0
+        } else if req.Quantity > 0 && req.Price == 0 {
+            req.Price = (req.Amount - req.WithholdingTax - req.Fees) / req.Quantity
+        }
+    } else if req.Amount != 0 || req.WithholdingTax != 0 || req.Reinvested {
+        return models.Transaction{}, fmt.Errorf("Amount, withholding tax and reinvested only apply to dividends and interest")
+    }
+
+    if req.Quantity <= 0 && (!income || req.Reinvested) {
+        return models.Transaction{}, fmt.Errorf("Quantity must be greater than zero")
+    }
+
+    date := time.Now()
+    if req.Date != "" {
+        var err error
+        date, err = parseDate(req.Date)
+        if err != nil {
+            return models.Transaction{}, err
+        }
+    }
+
+    transaction := models.Transaction{
+        UserID:          userID,
+        Account:         req.Account,
+        Ticker:          req.Ticker,
+        Type:            req.Type,
+        Date:            date,
+        Quantity:        req.Quantity,
+        Price:           req.Price,
+        Fees:            req.Fees,
+        Notes:           req.Notes,
+        CostBasisMethod: req.CostBasisMethod,
+        Amount:          req.Amount,
+        WithholdingTax:  req.WithholdingTax,
+        Reinvested:      req.Reinvested,
+        CreatedAt:       time.Now(),
+    }
+
+    if req.AcquiredDate != "" {
+        if req.Type != models.TransactionTransferIn {
+            return models.Transaction{}, fmt.Errorf("Acquired date only applies to transfers in")
+        }
+        acquired, err := parseDate(req.AcquiredDate)
+        if err != nil {
+            return models.Transaction{}, err
+        }
+        transaction.AcquiredDate = &acquired
+    }
+
+    if req.LotID != "" {
+        lotID, err := primitive.ObjectIDFromHex(req.LotID)
+        if err != nil {
+            return models.Transaction{}, fmt.Errorf("Invalid lot ID: %s", req.LotID)
+        }
+        transaction.LotID = lotID
+    }
+
+    return transaction, nil
+}
+
+// resolveCostBasisMethod fills in the account's cost basis method when the sell did not choose one.
+// It responds with an error and returns false if the method is invalid.
+func resolveCostBasisMethod(c *gin.Context, transaction *models.Transaction) bool {
+    if transaction.CostBasisMethod == "" {
+        account, ok := resolveAccount(c, transaction.UserID, transaction.Account)
+        if !ok {
+            return false
+        }
+
+        transaction.CostBasisMethod = models.CostBasisFIFO
+        if account.CostBasisMethod != "" {
+            transaction.CostBasisMethod = account.CostBasisMethod
+        }
+    }
+
+    if !models.IsValidCostBasisMethod(transaction.CostBasisMethod) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid cost basis method: %s", transaction.CostBasisMethod)})
+        return false
+    }
+
+    // The lot has to be named when selling a specific lot, and only then
+    if (transaction.CostBasisMethod == models.CostBasisSpecificLot) != (transaction.LotID != primitive.NilObjectID) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "A lot ID is required with, and only with, the specificLot method"})
+        return false
+    }
+    return true
+}
+
+// parseDate accepts either a plain date (YYYY-MM-DD) or an RFC3339 timestamp
+func parseDate(value string) (time.Time, error) {
+    if date, err := time.Parse("2006-01-02", value); err == nil {
+        return date, nil
+    }
+    date, err := time.Parse(time.RFC3339, value)
+    if err != nil {
+        return time.Time{}, fmt.Errorf("Invalid date %q, expected YYYY-MM-DD", value)
+    }
+    return date, nil
+}
+
+// recordTransaction validates a transaction against the position's ledger, stores it and rebuilds the holding.
+// It responds with an error and returns false if the transaction could not be recorded.
+func recordTransaction(c *gin.Context, transaction models.Transaction) (models.Transaction, portfolio.Position, *models.Holding, bool) {
+    // Transactions reference their account by ID and carry its current name
+    account, ok := resolveAccount(c, transaction.UserID, transaction.Account)
+    if !ok {
+        return transaction, portfolio.Position{}, nil, false
+    }
+    transaction.Account = account.Name
+    transaction.AccountID = account.ID
+
+    // Interest on cash does not belong to any position
+    if transaction.Ticker == "" {
+        id, err := config.AddTransaction(transaction)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record transaction"})
+            return transaction, portfolio.Position{}, nil, false
+        }
+        transaction.ID, _ = primitive.ObjectIDFromHex(id)
+        return transaction, portfolio.Position{}, nil, true
+    }
+
+    transactions, err := positionTransactions(transaction.UserID, transaction.AccountID, transaction.Ticker)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve transactions"})
+        return transaction, portfolio.Position{}, nil, false
+    }
+
+    if _, err = portfolio.BuildPosition(append(transactions, transaction)); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return transaction, portfolio.Position{}, nil, false
+    }
+
+    id, err := config.AddTransaction(transaction)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record transaction"})
+        return transaction, portfolio.Position{}, nil, false
+    }
+    transaction.ID, _ = primitive.ObjectIDFromHex(id)
+
+    // Replay again now that the transaction has an ID for its lot
+    position, err := portfolio.BuildPosition(append(transactions, transaction))
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return transaction, portfolio.Position{}, nil, false
+    }
+
+    holding, err := savePosition(transaction.UserID, transaction.AccountID, transaction.Account, transaction.Ticker, position)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update holding"})
+        return transaction, portfolio.Position{}, nil, false
+    }
+
+    return transaction, position, holding, true
+}
+
+// positionTransactions returns the ledger for a position. Holdings entered before the ledger existed
+// are converted into opening transfer-in transactions the first time their position is touched.
+func positionTransactions(userID primitive.ObjectID, accountID primitive.ObjectID, ticker string) ([]models.Transaction, error) {
+    transactions, err := config.GetTransactions(userID, accountID, ticker)
+    if err != nil || len(transactions) > 0 {
+        return transactions, err
+    }
+
+    holdings, err := config.GetPositionHoldings(userID, accountID, ticker)
+    if err != nil {
+        return nil, err
+    }
+
+    for _, holding := range holdings {
+        if holding.Quantity <= 0 {
+            continue
+        }
+
+        opening := models.Transaction{
+            UserID:    userID,
+            Account:   holding.Account,
+            AccountID: accountID,
+            Ticker:    ticker,
+            Type:      models.TransactionTransferIn,
+            Date:      holding.ID.Timestamp(),
+            Quantity:  holding.Quantity,
+            Price:     holding.TotalCost / holding.Quantity,
+            Notes:     fmt.Sprintf("Opening balance from holding %s", holding.ID.Hex()),
+            CreatedAt: time.Now(),
+        }
+        id, err := config.AddTransaction(opening)
+        if err != nil {
+            return nil, err
+        }
+        opening.ID, _ = primitive.ObjectIDFromHex(id)
+        log.Printf("Converted holding %s into an opening transaction", holding.ID.Hex())
+        transactions = append(transactions, opening)
+    }
+
+    return transactions, nil
+}
+
+// savePosition writes the derived position back to the holdings collection, keeping a single holding per position,
+// and records the lots its sells realized. The account's name is only kept on the holding for display.
+// It returns nil once the position has been closed.
+func savePosition(userID primitive.ObjectID, accountID primitive.ObjectID, account string, ticker string, position portfolio.Position) (*models.Holding, error) {
+    if err := config.ReplaceRealizedLots(userID, accountID, ticker, portfolio.RealizedLots(position)); err != nil {
+        return nil, err
+    }
+
+    existing, err := config.GetPositionHoldings(userID, accountID, ticker)
+    if err != nil {
+        return nil, err
+    }
+
+    // Collapse duplicate holdings left over from before the ledger existed
+    stale := existing
+    if position.Quantity > 0 && len(existing) > 0 {
+        stale = existing[1:]
+    }
+    for _, holding := range stale {
+        if _, err := config.DeleteHoldingByID(userID, holding.ID.Hex()); err != nil {
+            return nil, err
+        }
+        webhooks.Publish(userID, models.EventHoldingDeleted, holding)
+    }
+
+    if position.Quantity == 0 {
+        return nil, nil
+    }
+
+    // The holding's cost and prices are in its account's currency
+    accountRecord, err := config.GetAccountByID(userID, accountID.Hex())
+    if err != nil {
+        return nil, err
+    }
+
+    holding := models.Holding{
+        UserID:    userID,
+        Ticker:    ticker,
+        Quantity:  position.Quantity,
+        TotalCost: position.TotalCost,
+        Account:   account,
+        AccountID: accountID,
+        Currency:  accountRecord.Currency,
+        Lots:      position.Lots,
+    }
+
+    if len(existing) > 0 {
+        holding.ID = existing[0].ID
+        if _, err = config.UpdateHoldingByID(userID, holding.ID.Hex(), holding); err != nil {
+            return nil, err
+        }
+        webhooks.Publish(userID, models.EventHoldingUpdated, holding)
+        return &holding, nil
+    }
+
+    newID, err := config.AddHolding(holding)
+    if err != nil {
+        return nil, err
+    }
+    holding.ID, _ = primitive.ObjectIDFromHex(newID)
+    webhooks.Publish(userID, models.EventHoldingCreated, holding)
+    return &holding, nil
+}
@@ -2,17 +2,23 @@ package routes
 
 // Path: routes/users.go
 import (
+    "errors"
     "fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jalong4/stock-service-go/auth"
 	"github.com/jalong4/stock-service-go/config"
+	"github.com/jalong4/stock-service-go/email"
 	"github.com/jalong4/stock-service-go/models"
 
     "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -23,6 +29,18 @@ type LoginRequest struct {
     Password string `json:"password"`
 }
 
+// ForgotPasswordRequest defines the structure of the request payload for asking for a password reset email
+type ForgotPasswordRequest struct {
+    Email string `json:"email"`
+}
+
+// ResetPasswordRequest defines the structure of the request payload for choosing a new password
+type ResetPasswordRequest struct {
+    Token     string `json:"token"`
+    Password  string `json:"password"`
+    Password2 string `json:"password2"`
+}
+
 
 // TokenDetails holds the token information
 type TokenDetails struct {
@@ -32,6 +50,11 @@ type TokenDetails struct {
     ExpiresAt    int64  `json:"exp"`
 }
 
+// RefreshTokenRequest defines the structure of the token refresh request payload
+type RefreshTokenRequest struct {
+    RefreshToken string `json:"refreshToken"`
+}
+
 // AuthResponse defines the structure of the authentication response
 type AuthResponse struct {
     Success bool          `json:"success"`
@@ -60,7 +83,67 @@ func LoginHandler(c *gin.Context) {
         return
     }
 
-    accessToken, refreshToken, err := auth.CreateTokens(user)
+    accessToken, refreshToken, accessClaims, err := auth.GenerateTokens(user)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tokens"})
+        return
+    }
+
+    resp := AuthResponse{
+        Success: true,
+        User: *user,
+        Auth: TokenDetails{
+            AccessToken:  accessToken,
+            RefreshToken: refreshToken,
+            IssuedAt:     accessClaims.IssuedAt,
+            ExpiresAt:    accessClaims.ExpiresAt, // 30 days
+        },
+    }
+    c.JSON(http.StatusOK, gin.H{"response": resp})
+}
+
+// RefreshTokenHandler exchanges a refresh token for a new access/refresh token pair.
+// Refresh tokens are single use; presenting one twice revokes all of the user's refresh tokens.
+func RefreshTokenHandler(c *gin.Context) {
+    var req RefreshTokenRequest
+    if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+        return
+    }
+
+    claims, err := auth.ParseRefreshToken(req.RefreshToken)
+    if err != nil {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+        return
+    }
+
+    userID, err := primitive.ObjectIDFromHex(claims.ID)
+    if err != nil {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+        return
+    }
+
+    if _, err = config.ConsumeRefreshToken(claims.Id, userID); err != nil {
+        if errors.Is(err, mongo.ErrNoDocuments) {
+            // A valid but already used token means it may have been stolen
+            log.Printf("Refresh token %s reused for user %s, revoking all refresh tokens", claims.Id, claims.ID)
+            if err := config.RevokeRefreshTokens(userID); err != nil {
+                log.Printf("Failed to revoke refresh tokens: %v", err)
+            }
+            c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token has expired or was already used"})
+            return
+        }
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate refresh token"})
+        return
+    }
+
+    user, err := config.GetUserByID(claims.ID)
+    if err != nil {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication failed"})
+        return
+    }
+
+    accessToken, refreshToken, accessClaims, err := auth.GenerateTokens(user)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tokens"})
         return
@@ -72,13 +155,83 @@ func LoginHandler(c *gin.Context) {
         Auth: TokenDetails{
             AccessToken:  accessToken,
             RefreshToken: refreshToken,
-            IssuedAt:     time.Now().Unix(),
-            ExpiresAt:    time.Now().Add(time.Hour * 24 * 30).Unix(), // 30 days
+            IssuedAt:     accessClaims.IssuedAt,
+            ExpiresAt:    accessClaims.ExpiresAt,
         },
     }
     c.JSON(http.StatusOK, gin.H{"response": resp})
 }
 
+// LogoutHandler revokes the access token used for the request and, if provided, its refresh token
+func LogoutHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    claims, err := auth.GetAccessClaims(c)
+    if err != nil {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+        return
+    }
+
+    // Tokens issued before token IDs were added can only be revoked with logout-all
+    if claims.Id == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "This token cannot be revoked individually, use /users/logout-all"})
+        return
+    }
+
+    var req RefreshTokenRequest
+    if c.Request.ContentLength > 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+            return
+        }
+    }
+
+    err = config.RevokeToken(models.RevokedToken{
+        TokenID:   claims.Id,
+        UserID:    userID,
+        ExpiresAt: time.Unix(claims.ExpiresAt, 0),
+    })
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+        return
+    }
+
+    if req.RefreshToken != "" {
+        refreshClaims, err := auth.ParseRefreshToken(req.RefreshToken)
+        if err == nil && refreshClaims.ID == userID.Hex() {
+            if _, err := config.ConsumeRefreshToken(refreshClaims.Id, userID); err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+                c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke refresh token"})
+                return
+            }
+        }
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// LogoutAllHandler revokes every access and refresh token issued to the user so far
+func LogoutAllHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    if err := config.SetTokensNotBefore(userID, time.Now()); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke tokens"})
+        return
+    }
+
+    if err := config.RevokeRefreshTokens(userID); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke refresh tokens"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Logged out of all sessions successfully"})
+}
+
 func RegisterUserHandler(c *gin.Context) {
     log.Println("RegisterUserHandler")
     var req models.RegistrationRequest
@@ -98,6 +251,11 @@ func RegisterUserHandler(c *gin.Context) {
         c.JSON(http.StatusBadRequest, gin.H{"error": "Passwords do not match"})
         return
     }
+    baseCurrency := strings.ToUpper(strings.TrimSpace(req.BaseCurrency))
+    if baseCurrency != "" && !isCurrencyCode(baseCurrency) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Base currency must be a three letter code"})
+        return
+    }
 
     // Check if the user already exists
     existingUser, err := config.GetUserByEmail(req.Email)
@@ -125,8 +283,10 @@ func RegisterUserHandler(c *gin.Context) {
         Email:           req.Email,
         Password:        string(hashedPassword),
         Timezone:        req.Timezone,
+        BaseCurrency:    baseCurrency,
         Date:            time.Now(),
         ProfileImageURL: req.ProfileImageURL,
+        Role:            models.RoleUser, // Admins are promoted at startup through ADMIN_EMAILS or by another admin
     }
 
     // Insert the new user into the database
@@ -140,6 +300,8 @@ func RegisterUserHandler(c *gin.Context) {
     newID := result.InsertedID.(primitive.ObjectID)
     newUser.ID = newID
 
+    email.Notify(newUser.Email, "welcome", gin.H{"FirstName": newUser.FirstName, "Email": newUser.Email})
+
     // Generate JWT tokens
     accessToken, refreshToken, accessClaims, err := auth.GenerateTokens(&newUser)
     if err != nil {
@@ -157,6 +319,8 @@ func RegisterUserHandler(c *gin.Context) {
                 "email":           newUser.Email,
                 "password":        newUser.Password,
                 "timezone":        newUser.Timezone,
+                "baseCurrency":    newUser.GetBaseCurrency(),
+                "role":            newUser.Role,
                 "date":            newUser.Date.Format(time.RFC3339), // Ensure Date is set to the current time or appropriately
             },
             "auth": gin.H{
@@ -165,6 +329,7 @@ func RegisterUserHandler(c *gin.Context) {
                 "accessTokenProperties": gin.H{
                     "_id":   newID.Hex(),
                     "email": newUser.Email,
+                    "role":  accessClaims.Role,
                     "iat":   accessClaims.IssuedAt,
                     "exp":   accessClaims.ExpiresAt,
                 },
@@ -175,8 +340,156 @@ func RegisterUserHandler(c *gin.Context) {
     c.JSON(http.StatusOK, response)
 }
 
+// defaultPasswordResetTTL is how long a password reset token lasts when PASSWORD_RESET_TTL is not set
+const defaultPasswordResetTTL = time.Hour
+
+// ForgotPasswordHandler emails a single use password reset token to a registered address.
+// It responds the same way whether or not the address is registered.
+func ForgotPasswordHandler(c *gin.Context) {
+    var req ForgotPasswordRequest
+    if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Email) == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Email is required"})
+        return
+    }
+
+    response := gin.H{"message": "If the email is registered, a password reset email has been sent"}
+
+    user, err := config.GetUserByEmail(strings.TrimSpace(req.Email))
+    if err != nil || user == nil {
+        if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+            log.Printf("Failed to look up user for password reset: %v", err)
+        }
+        c.JSON(http.StatusOK, response)
+        return
+    }
+
+    token, tokenHash, err := auth.NewPasswordResetToken()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reset token"})
+        return
+    }
+
+    ttl := defaultPasswordResetTTL
+    if value := os.Getenv("PASSWORD_RESET_TTL"); value != "" {
+        parsed, err := time.ParseDuration(value)
+        if err != nil || parsed <= 0 {
+            log.Printf("Invalid PASSWORD_RESET_TTL %q, using %s", value, defaultPasswordResetTTL)
+        } else {
+            ttl = parsed
+        }
+    }
+
+    now := time.Now()
+    reset := models.PasswordReset{TokenHash: tokenHash, UserID: user.ID, CreatedAt: now, ExpiresAt: now.Add(ttl)}
+    if err := config.InsertPasswordReset(reset); err != nil {
+        log.Printf("Failed to record password reset: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reset token"})
+        return
+    }
+
+    // PASSWORD_RESET_URL is the app page that reads the token from its ?token= parameter
+    resetURL := ""
+    if value := os.Getenv("PASSWORD_RESET_URL"); value != "" {
+        parsed, err := url.Parse(value)
+        if err != nil {
+            log.Printf("Invalid PASSWORD_RESET_URL %q: %v", value, err)
+        } else {
+            query := parsed.Query()
+            query.Set("token", token)
+            parsed.RawQuery = query.Encode()
+            resetURL = parsed.String()
+        }
+    }
+
+    email.Notify(user.Email, "password_reset", gin.H{
+        "FirstName": user.FirstName,
+        "Email":     user.Email,
+        "Token":     token,
+        "ResetURL":  resetURL,
+        "ExpiresIn": humanDuration(ttl),
+    })
+
+    c.JSON(http.StatusOK, response)
+}
+
+// humanDuration formats d for an email in whole hours and minutes, e.g. "1 hour 30 minutes"
+func humanDuration(d time.Duration) string {
+    minutes := int(d / time.Minute)
+    if minutes < 1 {
+        return "less than a minute"
+    }
+
+    plural := func(n int, unit string) string {
+        if n == 1 {
+            return fmt.Sprintf("1 %s", unit)
+        }
+        return fmt.Sprintf("%d %ss", n, unit)
+    }
+
+    hours, minutes := minutes/60, minutes%60
+    switch {
+    case hours == 0:
+        return plural(minutes, "minute")
+    case minutes == 0:
+        return plural(hours, "hour")
+    default:
+        return plural(hours, "hour") + " " + plural(minutes, "minute")
+    }
+}
+
+// ResetPasswordHandler sets a new password with a token from a password reset email and signs the user out everywhere
+func ResetPasswordHandler(c *gin.Context) {
+    var req ResetPasswordRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+        return
+    }
+    if req.Token == "" || req.Password == "" || req.Password2 == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Please fill in all fields"})
+        return
+    }
+    if req.Password != req.Password2 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Passwords do not match"})
+        return
+    }
+
+    reset, err := config.ConsumePasswordReset(auth.HashPasswordResetToken(req.Token))
+    if errors.Is(err, mongo.ErrNoDocuments) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check reset token"})
+        return
+    }
+
+    hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encrypt password"})
+        return
+    }
+
+    if err := config.SetUserPassword(reset.UserID, string(hashedPassword)); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+        return
+    }
+
+    // Sessions opened with the old password may have been someone else's
+    if err := config.SetTokensNotBefore(reset.UserID, time.Now()); err != nil {
+        log.Printf("Failed to revoke tokens after password reset: %v", err)
+    }
+    if err := config.RevokeRefreshTokens(reset.UserID); err != nil {
+        log.Printf("Failed to revoke refresh tokens after password reset: %v", err)
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully, please log in with your new password"})
+}
+
 func DeleteUserHandler(c *gin.Context) {
     id := c.Param("_id")
+    if !canAccessUser(c, id) {
+        return
+    }
 
     // Delete the user from the database
     user, err := config.DeleteUserByID(id)
@@ -191,12 +504,47 @@ func DeleteUserHandler(c *gin.Context) {
 
 func UpdateUserHandler(c *gin.Context) {
     id := c.Param("_id")
+    if !canAccessUser(c, id) {
+        return
+    }
+
+    existingUser, err := config.GetUserByID(id)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+        return
+    }
+
     var updatedUser models.User
     if err := c.ShouldBindJSON(&updatedUser); err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
         return
     }
 
+    // Only admins may change roles
+    if updatedUser.Role == "" || !auth.IsAdmin(c) {
+        updatedUser.Role = existingUser.Role
+    }
+    if updatedUser.Role != "" && !models.IsValidRole(updatedUser.Role) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid role: %s", updatedUser.Role)})
+        return
+    }
+
+    // The benchmark is set through PUT /portfolio/benchmark, keep it unless a new one is given
+    if updatedUser.Benchmark == "" {
+        updatedUser.Benchmark = existingUser.Benchmark
+    }
+    updatedUser.Benchmark = strings.ToUpper(updatedUser.Benchmark)
+
+    // Likewise the base currency, which is set through PUT /portfolio/currency
+    if updatedUser.BaseCurrency == "" {
+        updatedUser.BaseCurrency = existingUser.BaseCurrency
+    }
+    updatedUser.BaseCurrency = strings.ToUpper(updatedUser.BaseCurrency)
+    if updatedUser.BaseCurrency != "" && !isCurrencyCode(updatedUser.BaseCurrency) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Base currency must be a three letter code"})
+        return
+    }
+
     // Hash password
     hashedPassword, err := bcrypt.GenerateFromPassword([]byte(updatedUser.Password), bcrypt.DefaultCost)
     if err != nil {
@@ -212,6 +560,13 @@ func UpdateUserHandler(c *gin.Context) {
         return
     }
 
+    // Tokens carry the role, so a role change invalidates the user's existing tokens
+    if updatedUser.GetRole() != existingUser.GetRole() {
+        if err := config.SetTokensNotBefore(existingUser.ID, time.Now()); err != nil {
+            log.Printf("Failed to revoke tokens after role change: %v", err)
+        }
+    }
+
     c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("User %s updated successfully!\n%v", id, result)})
 }
 
@@ -237,6 +592,9 @@ func GetAllUsers(c *gin.Context) {
 func GetUserByID(c *gin.Context) {
     idParam := c.Param("_id")
     log.Println("ID Param:", idParam)
+    if !canAccessUser(c, idParam) {
+        return
+    }
     user, err := config.GetUserByID(idParam)
 
     if err != nil {
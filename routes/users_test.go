@@ -0,0 +1,28 @@
+package routes
+
+import (
+    "testing"
+    "time"
+)
+
+func TestHumanDuration(t *testing.T) {
+    tests := []struct {
+        duration time.Duration
+        want     string
+    }{
+        {30 * time.Second, "less than a minute"},
+        {time.Minute, "1 minute"},
+        {10 * time.Minute, "10 minutes"},
+        {time.Hour, "1 hour"},
+        {90 * time.Minute, "1 hour 30 minutes"},
+        {2*time.Hour + time.Minute, "2 hours 1 minute"},
+        {48 * time.Hour, "48 hours"},
+        {time.Hour + 59*time.Second, "1 hour"},
+    }
+
+    for _, tt := range tests {
+        if got := humanDuration(tt.duration); got != tt.want {
+            t.Errorf("humanDuration(%s) = %q, want %q", tt.duration, got, tt.want)
+        }
+    }
+}
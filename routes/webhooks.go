@@ -0,0 +1,272 @@
+package routes
+
+// Path: routes/webhooks.go
+import (
+    "fmt"
+    "log"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/jalong4/stock-service-go/config"
+    "github.com/jalong4/stock-service-go/models"
+    "github.com/jalong4/stock-service-go/webhooks"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// defaultDeliveryLimit is how many webhook deliveries are returned when ?limit= is not given
+const defaultDeliveryLimit = 100
+
+// WebhookRequest defines the structure of the request payload for creating or updating a webhook
+type WebhookRequest struct {
+    URL    string   `json:"url"`
+    Secret string   `json:"secret"` // Generated for new webhooks when empty, kept on update when empty
+    Events []string `json:"events"` // holding.created, holding.updated, holding.deleted and alert.triggered
+    Active *bool    `json:"active"` // Defaults to true
+}
+
+// GetWebhooksHandler handles requests to list the user's webhooks
+func GetWebhooksHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    subscriptions, err := config.GetWebhooks(userID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve webhooks"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "count":    len(subscriptions),
+        "webhooks": subscriptions,
+    })
+}
+
+// AddWebhookHandler handles requests to create a webhook. The signing secret is only returned here.
+func AddWebhookHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    var req WebhookRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+        return
+    }
+
+    now := time.Now()
+    webhook := models.Webhook{UserID: userID, CreatedAt: now, UpdatedAt: now}
+    if err := req.apply(&webhook); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if webhook.Secret == "" {
+        secret, err := webhooks.NewSecret()
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate webhook secret"})
+            return
+        }
+        webhook.Secret = secret
+    }
+
+    id, err := config.AddWebhook(webhook)
+    if err != nil {
+        log.Printf("Failed to add webhook: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add webhook"})
+        return
+    }
+    webhook.ID, _ = primitive.ObjectIDFromHex(id)
+
+    c.JSON(http.StatusCreated, gin.H{
+        "message": fmt.Sprintf("Successfully added webhook for %s with ID %s", webhook.URL, id),
+        "webhook": webhook,
+        "secret":  webhook.Secret,
+    })
+}
+
+// GetWebhookByIDHandler handles requests to get a webhook by its ID
+func GetWebhookByIDHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    webhook, ok := findWebhook(c, userID)
+    if !ok {
+        return
+    }
+
+    c.JSON(http.StatusOK, webhook)
+}
+
+// UpdateWebhookHandler handles requests to update a webhook's URL, secret, events or active flag
+func UpdateWebhookHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    webhook, ok := findWebhook(c, userID)
+    if !ok {
+        return
+    }
+
+    var req WebhookRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+        return
+    }
+
+    secret := webhook.Secret
+    if err := req.apply(webhook); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if webhook.Secret == "" {
+        webhook.Secret = secret
+    }
+    webhook.UpdatedAt = time.Now()
+
+    if err := config.UpdateWebhook(userID, *webhook); err != nil {
+        log.Printf("Failed to update webhook: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update webhook"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": fmt.Sprintf("Webhook for %s updated successfully!", webhook.URL),
+        "webhook": webhook,
+    })
+}
+
+// DeleteWebhookHandler handles requests to delete a webhook and its delivery log
+func DeleteWebhookHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    webhook, ok := findWebhook(c, userID)
+    if !ok {
+        return
+    }
+
+    if _, err := config.DeleteWebhookByID(userID, webhook.ID); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Webhook for %s deleted successfully!", webhook.URL)})
+}
+
+// GetWebhookDeliveriesHandler handles requests for a webhook's delivery log, newest first
+func GetWebhookDeliveriesHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    webhook, ok := findWebhook(c, userID)
+    if !ok {
+        return
+    }
+
+    limit := int64(defaultDeliveryLimit)
+    if value := c.Query("limit"); value != "" {
+        parsed, err := strconv.ParseInt(value, 10, 64)
+        if err != nil || parsed <= 0 {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+            return
+        }
+        limit = parsed
+    }
+
+    deliveries, err := config.GetWebhookDeliveries(userID, webhook.ID, limit)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve webhook deliveries"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "count":      len(deliveries),
+        "deliveries": deliveries,
+    })
+}
+
+// PingWebhookHandler handles requests to send a ping event to a webhook
+func PingWebhookHandler(c *gin.Context) {
+    userID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    webhook, ok := findWebhook(c, userID)
+    if !ok {
+        return
+    }
+
+    if !webhook.Active {
+        c.JSON(http.StatusConflict, gin.H{"error": "Webhook is inactive"})
+        return
+    }
+
+    delivery, err := webhooks.Send(*webhook, models.EventPing, gin.H{"webhookId": webhook.ID.Hex()})
+    if err != nil {
+        log.Printf("Failed to queue ping: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send ping"})
+        return
+    }
+
+    c.JSON(http.StatusAccepted, gin.H{
+        "message":  fmt.Sprintf("Ping queued for %s, check its delivery log for the outcome", webhook.URL),
+        "delivery": delivery,
+    })
+}
+
+// findWebhook retrieves the user's webhook named by the :_id parameter.
+// It responds with an error and returns false if it could not be retrieved.
+func findWebhook(c *gin.Context, userID primitive.ObjectID) (*models.Webhook, bool) {
+    id := c.Param("_id")
+    webhook, err := config.GetWebhookByID(userID, id)
+    if isNotFound(err) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "No webhook found with ID: " + id})
+        return nil, false
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve webhook"})
+        return nil, false
+    }
+    return webhook, true
+}
+
+// apply validates the request and copies it onto the webhook
+func (req WebhookRequest) apply(webhook *models.Webhook) error {
+    webhook.URL = strings.TrimSpace(req.URL)
+    webhook.Secret = req.Secret
+    webhook.Active = req.Active == nil || *req.Active
+
+    if err := webhooks.ValidateURL(webhook.URL); err != nil {
+        return err
+    }
+
+    if len(req.Events) == 0 {
+        return fmt.Errorf("At least one event is required")
+    }
+    webhook.Events = nil
+    seen := map[string]bool{}
+    for _, event := range req.Events {
+        if !models.IsValidWebhookEvent(event) {
+            return fmt.Errorf("Invalid webhook event: %s", event)
+        }
+        if !seen[event] {
+            seen[event] = true
+            webhook.Events = append(webhook.Events, event)
+        }
+    }
+    return nil
+}
@@ -0,0 +1,101 @@
+package securities
+// Path: securities/import.go
+
+import (
+    "encoding/csv"
+    "fmt"
+    "io"
+    "os"
+    "strings"
+    "time"
+
+    "github.com/jalong4/stock-service-go/config"
+    "github.com/jalong4/stock-service-go/models"
+)
+
+// columns maps the CSV header names to the security fields they set
+var columns = map[string]func(*models.Security, string){
+    "symbol":     func(s *models.Security, value string) { s.Symbol = strings.ToUpper(value) },
+    "name":       func(s *models.Security, value string) { s.Name = value },
+    "exchange":   func(s *models.Security, value string) { s.Exchange = strings.ToUpper(value) },
+    "type":       func(s *models.Security, value string) { s.Type = strings.ToLower(value) },
+    "currency":   func(s *models.Security, value string) { s.Currency = strings.ToUpper(value) },
+    "assetclass": func(s *models.Security, value string) { s.AssetClass = value },
+    "sector":     func(s *models.Security, value string) { s.Sector = value },
+    "country":    func(s *models.Security, value string) { s.Country = strings.ToUpper(value) },
+    "cusip":      func(s *models.Security, value string) { s.CUSIP = strings.ToUpper(value) },
+    "isin":       func(s *models.Security, value string) { s.ISIN = strings.ToUpper(value) },
+}
+
+// ParseCSV reads securities from CSV rows. The header row names the columns, which may come in any order:
+// symbol (required), name, exchange, type, currency, assetClass, sector, country, cusip and isin.
+// Other columns are ignored.
+func ParseCSV(r io.Reader) ([]models.Security, error) {
+    reader := csv.NewReader(r)
+    reader.FieldsPerRecord = -1
+    reader.TrimLeadingSpace = true
+
+    header, err := reader.Read()
+    if err == io.EOF {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    setters := make([]func(*models.Security, string), len(header))
+    hasSymbol := false
+    for i, name := range header {
+        name = strings.ToLower(strings.TrimSpace(name))
+        setters[i] = columns[name]
+        hasSymbol = hasSymbol || name == "symbol"
+    }
+    if !hasSymbol {
+        return nil, fmt.Errorf("line 1: expected a header row with a symbol column")
+    }
+
+    var securities []models.Security
+    now := time.Now()
+    for line := 2; ; line++ {
+        record, err := reader.Read()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return nil, err
+        }
+
+        security := models.Security{UpdatedAt: now}
+        for i, value := range record {
+            if i < len(setters) && setters[i] != nil {
+                setters[i](&security, strings.TrimSpace(value))
+            }
+        }
+        if security.Symbol == "" {
+            return nil, fmt.Errorf("line %d: symbol is required", line)
+        }
+        securities = append(securities, security)
+    }
+
+    return securities, nil
+}
+
+// ImportCSV stores the securities read from r in the security master
+func ImportCSV(r io.Reader) (int64, error) {
+    securities, err := ParseCSV(r)
+    if err != nil {
+        return 0, err
+    }
+    return config.UpsertSecurities(securities)
+}
+
+// ImportCSVFile stores the securities in the CSV file at path in the security master
+func ImportCSVFile(path string) (int64, error) {
+    file, err := os.Open(path)
+    if err != nil {
+        return 0, err
+    }
+    defer file.Close()
+
+    return ImportCSV(file)
+}
@@ -0,0 +1,127 @@
+package webhooks
+// Path: webhooks/address.go
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net"
+    "net/http"
+    "net/url"
+    "strings"
+    "syscall"
+    "time"
+)
+
+// ErrBlockedAddress is returned for webhook hosts on loopback, link-local, private or otherwise internal addresses
+var ErrBlockedAddress = errors.New("webhook address is not a public address")
+
+// allowPrivate lets webhooks reach internal addresses, for local development only (WEBHOOK_ALLOW_PRIVATE)
+var allowPrivate = false
+
+// blockedNetworks are internal ranges the net.IP methods don't cover
+var blockedNetworks = []*net.IPNet{
+    mustParseCIDR("0.0.0.0/8"),     // "This" network
+    mustParseCIDR("100.64.0.0/10"), // Carrier-grade NAT
+    mustParseCIDR("192.0.0.0/24"),  // IETF protocol assignments
+    mustParseCIDR("198.18.0.0/15"), // Benchmarking
+    mustParseCIDR("64:ff9b::/96"),  // NAT64, which can reach IPv4 internal addresses
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+    _, network, err := net.ParseCIDR(cidr)
+    if err != nil {
+        panic(err)
+    }
+    return network
+}
+
+// newClient returns the HTTP client deliveries are posted with. It connects only to public addresses, checked
+// after DNS resolution so a hostname can't be pointed at an internal service, never uses a proxy and never
+// follows redirects.
+func newClient() *http.Client {
+    dialer := &net.Dialer{Timeout: 5 * time.Second, Control: checkDialAddress}
+    return &http.Client{
+        Timeout: 10 * time.Second,
+        Transport: &http.Transport{
+            Proxy:               nil,
+            DialContext:         dialer.DialContext,
+            TLSHandshakeTimeout: 5 * time.Second,
+            MaxIdleConns:        10,
+            IdleConnTimeout:     90 * time.Second,
+        },
+        CheckRedirect: func(req *http.Request, via []*http.Request) error {
+            return http.ErrUseLastResponse
+        },
+    }
+}
+
+// checkDialAddress is the dialer's Control hook, run with the resolved address of every connection
+func checkDialAddress(network string, address string, conn syscall.RawConn) error {
+    host, _, err := net.SplitHostPort(address)
+    if err != nil {
+        return err
+    }
+    ip := net.ParseIP(host)
+    if ip == nil {
+        return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+    }
+    if isBlockedIP(ip) && !allowPrivate {
+        return fmt.Errorf("%w: %s", ErrBlockedAddress, ip)
+    }
+    return nil
+}
+
+// isBlockedIP reports whether ip is a loopback, link-local, private, unspecified, multicast or other internal address
+func isBlockedIP(ip net.IP) bool {
+    if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+        ip.IsMulticast() || ip.IsPrivate() || ip.IsUnspecified() {
+        return true
+    }
+    for _, network := range blockedNetworks {
+        if network.Contains(ip) {
+            return true
+        }
+    }
+    return false
+}
+
+// ValidateURL checks that a webhook URL is an absolute http or https URL whose host resolves only to public
+// addresses. Deliveries check the address again when they connect.
+func ValidateURL(raw string) error {
+    parsed, err := url.Parse(raw)
+    if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+        return fmt.Errorf("URL must be an absolute http or https URL")
+    }
+    if allowPrivate {
+        return nil
+    }
+
+    host := strings.ToLower(parsed.Hostname())
+    if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+        return fmt.Errorf("URL must not point to a loopback, private or link-local address")
+    }
+
+    var ips []net.IP
+    if ip := net.ParseIP(host); ip != nil {
+        ips = []net.IP{ip}
+    } else {
+        ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+        defer cancel()
+
+        addresses, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+        if err != nil {
+            return fmt.Errorf("URL host %s could not be resolved", host)
+        }
+        for _, address := range addresses {
+            ips = append(ips, address.IP)
+        }
+    }
+
+    for _, ip := range ips {
+        if isBlockedIP(ip) {
+            return fmt.Errorf("URL must not point to a loopback, private or link-local address")
+        }
+    }
+    return nil
+}
@@ -0,0 +1,250 @@
+package webhooks
+// Path: webhooks/webhooks.go
+
+import (
+    "bytes"
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "log"
+    "net/http"
+    "os"
+    "strconv"
+    "time"
+
+    "github.com/jalong4/stock-service-go/config"
+    "github.com/jalong4/stock-service-go/models"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+)
+
+// Headers sent with every delivery
+const (
+    SignatureHeader = "X-Webhook-Signature" // "sha256=" and the hex HMAC-SHA256 of the body keyed with the webhook's secret
+    EventHeader     = "X-Webhook-Event"
+    DeliveryHeader  = "X-Webhook-Delivery" // The delivery ID, the same on every attempt
+)
+
+// Payload is the JSON body posted to a webhook
+type Payload struct {
+    ID        string      `json:"id"` // The delivery ID
+    Event     string      `json:"event"`
+    CreatedAt time.Time   `json:"createdAt"`
+    Data      interface{} `json:"data"`
+}
+
+// maxRetryDelay caps the wait between attempts however many have failed
+const maxRetryDelay = time.Hour
+
+var (
+    maxAttempts = 5
+    retryDelay  = 10 * time.Second
+    client      = newClient()
+)
+
+// Setup reads the retry settings from WEBHOOK_MAX_ATTEMPTS and WEBHOOK_RETRY_DELAY and resumes the deliveries
+// a restart left pending. WEBHOOK_ALLOW_PRIVATE=true lets webhooks reach private and loopback addresses, for
+// local development.
+func Setup() {
+    allowPrivate = os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "true"
+    if allowPrivate {
+        log.Printf("Webhooks may reach private and loopback addresses")
+    }
+
+    if value := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); value != "" {
+        parsed, err := strconv.Atoi(value)
+        if err != nil || parsed < 1 {
+            log.Printf("Invalid WEBHOOK_MAX_ATTEMPTS %q, using %d", value, maxAttempts)
+        } else {
+            maxAttempts = parsed
+        }
+    }
+    if value := os.Getenv("WEBHOOK_RETRY_DELAY"); value != "" {
+        parsed, err := time.ParseDuration(value)
+        if err != nil || parsed <= 0 {
+            log.Printf("Invalid WEBHOOK_RETRY_DELAY %q, using %s", value, retryDelay)
+        } else {
+            retryDelay = parsed
+        }
+    }
+    log.Printf("Webhook deliveries make up to %d attempts, first retrying after %s", maxAttempts, retryDelay)
+
+    pending, err := config.GetPendingWebhookDeliveries()
+    if err != nil {
+        log.Printf("Failed to retrieve pending webhook deliveries: %v", err)
+        return
+    }
+    for _, delivery := range pending {
+        go deliver(delivery)
+    }
+    if len(pending) > 0 {
+        log.Printf("Resumed %d pending webhook deliveries", len(pending))
+    }
+}
+
+// Publish sends the event to each of the user's active webhooks subscribed to it
+func Publish(userID primitive.ObjectID, event string, data interface{}) {
+    webhooks, err := config.GetWebhooksForEvent(userID, event)
+    if err != nil {
+        log.Printf("Failed to retrieve webhooks for %s: %v", event, err)
+        return
+    }
+
+    for _, webhook := range webhooks {
+        if _, err := Send(webhook, event, data); err != nil {
+            log.Printf("Failed to queue %s delivery to webhook %s: %v", event, webhook.ID.Hex(), err)
+        }
+    }
+}
+
+// Send logs a delivery of the event to the webhook and makes its attempts in the background
+func Send(webhook models.Webhook, event string, data interface{}) (*models.WebhookDelivery, error) {
+    now := time.Now()
+    delivery := models.WebhookDelivery{
+        ID:            primitive.NewObjectID(),
+        WebhookID:     webhook.ID,
+        UserID:        webhook.UserID,
+        Event:         event,
+        URL:           webhook.URL,
+        Status:        models.DeliveryPending,
+        CreatedAt:     now,
+        NextAttemptAt: &now,
+    }
+
+    body, err := json.Marshal(Payload{ID: delivery.ID.Hex(), Event: event, CreatedAt: now, Data: data})
+    if err != nil {
+        return nil, err
+    }
+    delivery.Payload = string(body)
+
+    if _, err := config.AddWebhookDelivery(delivery); err != nil {
+        return nil, err
+    }
+
+    go deliver(delivery)
+    return &delivery, nil
+}
+
+// Sign returns the signature header value of body for the secret
+func Sign(secret string, body []byte) string {
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write(body)
+    return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret returns a random signing secret
+func NewSecret() (string, error) {
+    secret := make([]byte, 32)
+    if _, err := rand.Read(secret); err != nil {
+        return "", err
+    }
+    return hex.EncodeToString(secret), nil
+}
+
+// Backoff returns how long to wait before retrying a delivery that has failed attempts times,
+// at most maxRetryDelay
+func Backoff(attempts int) time.Duration {
+    if attempts < 1 {
+        return 0
+    }
+    delay := retryDelay
+    for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+        delay *= 2
+    }
+    if delay > maxRetryDelay {
+        return maxRetryDelay
+    }
+    return delay
+}
+
+// deliver makes the delivery's remaining attempts, waiting twice as long after each failure, and logs
+// the outcome of each one. The webhook is loaded again before each attempt so its current URL and secret are
+// used, and the delivery is dropped once the webhook is deleted or deactivated.
+func deliver(delivery models.WebhookDelivery) {
+    for delivery.Status == models.DeliveryPending {
+        if delivery.NextAttemptAt != nil {
+            time.Sleep(time.Until(*delivery.NextAttemptAt))
+        }
+
+        webhook, err := config.GetWebhookByID(delivery.UserID, delivery.WebhookID.Hex())
+        if errors.Is(err, mongo.ErrNoDocuments) || (err == nil && !webhook.Active) {
+            delivery.Status = models.DeliveryFailed
+            delivery.NextAttemptAt = nil
+            delivery.Error = "webhook was deleted or deactivated"
+            if err := config.UpdateWebhookDelivery(delivery); err != nil {
+                log.Printf("Failed to log webhook delivery %s: %v", delivery.ID.Hex(), err)
+            }
+            return
+        }
+        status := 0
+        if err != nil {
+            // Counted as a failed attempt so a lasting database error ends the delivery
+            log.Printf("Failed to retrieve webhook %s for delivery %s: %v", delivery.WebhookID.Hex(), delivery.ID.Hex(), err)
+            err = fmt.Errorf("retrieving webhook: %w", err)
+        } else {
+            delivery.URL = webhook.URL
+            status, err = post(delivery, webhook.Secret)
+        }
+        now := time.Now()
+        delivery.Attempts++
+        delivery.LastAttemptAt = &now
+        delivery.ResponseStatus = status
+        delivery.NextAttemptAt = nil
+        delivery.Error = ""
+
+        switch {
+        case err == nil:
+            delivery.Status = models.DeliverySucceeded
+            delivery.DeliveredAt = &now
+        case errors.Is(err, ErrBlockedAddress) || !isRetryable(status) || delivery.Attempts >= maxAttempts:
+            delivery.Status = models.DeliveryFailed
+            delivery.Error = err.Error()
+        default:
+            next := now.Add(Backoff(delivery.Attempts))
+            delivery.NextAttemptAt = &next
+            delivery.Error = err.Error()
+        }
+
+        if err := config.UpdateWebhookDelivery(delivery); err != nil {
+            log.Printf("Failed to log webhook delivery %s: %v", delivery.ID.Hex(), err)
+        }
+    }
+}
+
+// post sends the delivery's payload once. It returns the response status, zero if there was no response,
+// and an error unless the webhook accepted it with a 2xx status. Redirects are not followed, so a 3xx
+// response fails the attempt.
+func post(delivery models.WebhookDelivery, secret string) (int, error) {
+    body := []byte(delivery.Payload)
+    req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(body))
+    if err != nil {
+        return 0, err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("User-Agent", "stock-service-webhooks")
+    req.Header.Set(EventHeader, delivery.Event)
+    req.Header.Set(DeliveryHeader, delivery.ID.Hex())
+    req.Header.Set(SignatureHeader, Sign(secret, body))
+
+    resp, err := client.Do(req)
+    if err != nil {
+        return 0, err
+    }
+    defer resp.Body.Close()
+    io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+    }
+    return resp.StatusCode, nil
+}
+
+// isRetryable reports whether a failed attempt with the response status may succeed later
+func isRetryable(status int) bool {
+    return status == 0 || status == http.StatusRequestTimeout || status == http.StatusTooManyRequests || status >= 500
+}
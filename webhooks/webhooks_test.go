@@ -0,0 +1,183 @@
+package webhooks
+
+import (
+    "errors"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+
+    "github.com/jalong4/stock-service-go/models"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSign(t *testing.T) {
+    tests := []struct {
+        secret string
+        body   string
+        want   string
+    }{
+        // RFC 4231 test case 2
+        {"Jefe", "what do ya want for nothing?", "sha256=5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"},
+        {"", "", "sha256=b613679a0814d9ec772f95d778c35fc5ff1697c493715653c6c712144292c5ad"},
+    }
+
+    for _, tt := range tests {
+        if got := Sign(tt.secret, []byte(tt.body)); got != tt.want {
+            t.Errorf("Sign(%q, %q) = %s, want %s", tt.secret, tt.body, got, tt.want)
+        }
+    }
+}
+
+func TestBackoff(t *testing.T) {
+    tests := []struct {
+        attempts int
+        want     time.Duration
+    }{
+        {0, 0},
+        {1, retryDelay},
+        {2, 2 * retryDelay},
+        {4, 8 * retryDelay},
+        {10, maxRetryDelay},
+        {64, maxRetryDelay},
+        {1 << 30, maxRetryDelay},
+    }
+
+    for _, tt := range tests {
+        if got := Backoff(tt.attempts); got != tt.want {
+            t.Errorf("Backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+        }
+    }
+}
+
+func TestIsRetryable(t *testing.T) {
+    tests := []struct {
+        status int
+        want   bool
+    }{
+        {0, true},
+        {http.StatusRequestTimeout, true},
+        {http.StatusTooManyRequests, true},
+        {http.StatusBadGateway, true},
+        {http.StatusFound, false},
+        {http.StatusBadRequest, false},
+        {http.StatusGone, false},
+    }
+
+    for _, tt := range tests {
+        if got := isRetryable(tt.status); got != tt.want {
+            t.Errorf("isRetryable(%d) = %v, want %v", tt.status, got, tt.want)
+        }
+    }
+}
+
+func TestIsBlockedIP(t *testing.T) {
+    tests := []struct {
+        ip   string
+        want bool
+    }{
+        {"127.0.0.1", true},
+        {"::1", true},
+        {"10.1.2.3", true},
+        {"172.16.0.1", true},
+        {"192.168.1.1", true},
+        {"169.254.169.254", true},
+        {"fe80::1", true},
+        {"fd00::1", true},
+        {"0.0.0.0", true},
+        {"::", true},
+        {"100.64.0.1", true},
+        {"224.0.0.1", true},
+        {"::ffff:127.0.0.1", true},
+        {"93.184.216.34", false},
+        {"2606:4700:4700::1111", false},
+    }
+
+    for _, tt := range tests {
+        if got := isBlockedIP(net.ParseIP(tt.ip)); got != tt.want {
+            t.Errorf("isBlockedIP(%s) = %v, want %v", tt.ip, got, tt.want)
+        }
+    }
+}
+
+func TestValidateURL(t *testing.T) {
+    tests := []struct {
+        url     string
+        wantErr bool
+    }{
+        {"https://93.184.216.34/hook", false},
+        {"http://[2606:4700:4700::1111]:8080/hook", false},
+        {"ftp://93.184.216.34/hook", true},
+        {"/hook", true},
+        {"https:///hook", true},
+        {"http://localhost:8080/hook", true},
+        {"http://api.localhost/hook", true},
+        {"http://127.0.0.1/hook", true},
+        {"http://169.254.169.254/latest/meta-data", true},
+        {"http://10.0.0.5/hook", true},
+        {"http://[::1]/hook", true},
+    }
+
+    for _, tt := range tests {
+        if err := ValidateURL(tt.url); (err != nil) != tt.wantErr {
+            t.Errorf("ValidateURL(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+        }
+    }
+}
+
+func TestPost(t *testing.T) {
+    var signature string
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        switch r.URL.Path {
+        case "/redirect":
+            http.Redirect(w, r, "/ok", http.StatusFound)
+        case "/ok":
+            signature = r.Header.Get(SignatureHeader)
+            w.WriteHeader(http.StatusNoContent)
+        default:
+            w.WriteHeader(http.StatusServiceUnavailable)
+        }
+    }))
+    defer server.Close()
+
+    delivery := func(path string) models.WebhookDelivery {
+        return models.WebhookDelivery{ID: primitive.NewObjectID(), Event: models.EventPing, URL: server.URL + path, Payload: `{"event":"ping"}`}
+    }
+
+    tests := []struct {
+        name         string
+        path         string
+        allowPrivate bool
+        wantStatus   int
+        wantErr      bool
+        wantBlocked  bool
+    }{
+        {"loopback server blocked", "/ok", false, 0, true, true},
+        {"accepted", "/ok", true, http.StatusNoContent, false, false},
+        {"redirect not followed", "/redirect", true, http.StatusFound, true, false},
+        {"server error", "/down", true, http.StatusServiceUnavailable, true, false},
+    }
+
+    defer func(previous bool) { allowPrivate = previous }(allowPrivate)
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            allowPrivate = tt.allowPrivate
+            signature = ""
+
+            status, err := post(delivery(tt.path), "secret")
+            if status != tt.wantStatus || (err != nil) != tt.wantErr {
+                t.Fatalf("post() = %d, %v, want %d, error %v", status, err, tt.wantStatus, tt.wantErr)
+            }
+            if errors.Is(err, ErrBlockedAddress) != tt.wantBlocked {
+                t.Errorf("post() error = %v, want ErrBlockedAddress %v", err, tt.wantBlocked)
+            }
+            if tt.path == "/redirect" && signature != "" {
+                t.Errorf("post() followed the redirect")
+            }
+            if tt.path == "/ok" && !tt.wantErr && signature != Sign("secret", []byte(`{"event":"ping"}`)) {
+                t.Errorf("post() sent signature %q", signature)
+            }
+        })
+    }
+}